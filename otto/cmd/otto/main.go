@@ -35,7 +35,9 @@ func main() {
 	}
 
 	// Register modules explicitly
-	app.RegisterModule(&modules.OnCallModule{})
+	for _, m := range modules.All() {
+		app.RegisterModule(m)
+	}
 
 	// Start the application
 	if err := app.Start(ctx); err != nil {
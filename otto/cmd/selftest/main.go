@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package main implements selftest, a smoke test for CI and container health
+// checks that needs no live GitHub. It boots the event dispatcher and the
+// oncall module against an in-memory database, no-op telemetry and a fake
+// GitHub API, replays embedded webhook payloads through the dispatcher and
+// checks their database and comment side effects. It exits non-zero if any
+// check fails.
+//
+// Usage:
+//
+//	selftest [-check] [-timeout 30s]
+package main
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"github.com/open-telemetry/sig-project-infra/otto/modules"
+)
+
+var (
+	//go:embed payloads/ack.json
+	ackPayload []byte
+	//go:embed payloads/help.json
+	helpPayload []byte
+)
+
+// The fixtures the embedded payloads refer to.
+const (
+	selftestRepo  = "otto/selftest"
+	selftestIssue = 1
+	selftestUser  = "selftest-oncall"
+)
+
+func main() {
+	check := flag.Bool("check", false, "only report failures, without logs or progress")
+	timeout := flag.Duration("timeout", 30*time.Second, "maximum time the self-test may take")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if *check {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	slog.SetDefault(logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := run(ctx, logger, !*check); err != nil {
+		fmt.Fprintf(os.Stderr, "selftest: FAIL: %v\n", err)
+		os.Exit(1)
+	}
+	if !*check {
+		fmt.Println("selftest: ok")
+	}
+}
+
+// fakeGitHub records the issue comments posted through the GitHub API.
+type fakeGitHub struct {
+	mu       sync.Mutex
+	comments []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/comments") {
+		http.NotFound(w, r)
+		return
+	}
+	var comment github.IssueComment
+	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.comments = append(f.comments, comment.GetBody())
+	comment.ID = github.Ptr(int64(len(f.comments)))
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(comment)
+}
+
+// Comments returns the bodies of the comments posted so far.
+func (f *fakeGitHub) Comments() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.comments...)
+}
+
+// step replays one webhook payload and verifies its side effects.
+type step struct {
+	name      string
+	eventType string
+	payload   []byte
+	verify    func(db *sql.DB, comments []string) error
+}
+
+var steps = []step{
+	{
+		name:      "on-call user acknowledges with /ack",
+		eventType: "issue_comment",
+		payload:   ackPayload,
+		verify: func(db *sql.DB, comments []string) error {
+			task, err := modules.GetTaskByIssueNumber(db, selftestRepo, selftestIssue)
+			if err != nil {
+				return err
+			}
+			if task == nil || task.Status != modules.TaskStatusAck || task.AckedBy != selftestUser {
+				return fmt.Errorf("want task acknowledged by %s, got %+v", selftestUser, task)
+			}
+			if len(comments) != 0 {
+				return fmt.Errorf("want no comments, got %q", comments)
+			}
+			return nil
+		},
+	},
+	{
+		name:      "/oncall help replies with a comment",
+		eventType: "issue_comment",
+		payload:   helpPayload,
+		verify: func(_ *sql.DB, comments []string) error {
+			if len(comments) != 1 || !strings.Contains(comments[0], "/ack") {
+				return fmt.Errorf("want one help comment, got %q", comments)
+			}
+			return nil
+		},
+	},
+}
+
+// run boots the app with fakes, replays every step and returns the first
+// failure. If verbose, each passing step is printed.
+func run(ctx context.Context, logger *slog.Logger, verbose bool) error {
+	database, err := internal.NewDatabase("file:selftest?mode=memory&cache=shared")
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	gh := &fakeGitHub{}
+	srv := httptest.NewServer(gh)
+	defer srv.Close()
+	client := github.NewClient(srv.Client())
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	telemetry := internal.NewNoopTelemetryManager()
+	telemetry.Logger = logger
+	app := &internal.App{
+		Config:         &config.AppConfig{Modules: map[string]any{}},
+		Database:       database,
+		Telemetry:      telemetry,
+		Logger:         logger,
+		GitHubClient:   client,
+		ModuleRegistry: internal.NewModuleRegistryWithTelemetry(telemetry),
+	}
+	module := &modules.OnCallModule{}
+	app.RegisterModule(module)
+	if err := module.Initialize(ctx, app); err != nil {
+		return fmt.Errorf("initialize oncall module: %w", err)
+	}
+	defer func() { _ = module.Shutdown(context.Background()) }()
+
+	if err := seed(database.DB(), module); err != nil {
+		return fmt.Errorf("seed database: %w", err)
+	}
+
+	for _, s := range steps {
+		event, err := github.ParseWebHook(s.eventType, s.payload)
+		if err != nil {
+			return fmt.Errorf("%s: parse payload: %w", s.name, err)
+		}
+
+		before := len(gh.Comments())
+		done := make(chan bool, 1)
+		go func() { done <- app.DispatchEventAndWait(s.eventType, event, s.payload) }()
+		select {
+		case handled := <-done:
+			if !handled {
+				return fmt.Errorf("%s: event was not handled", s.name)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", s.name, ctx.Err())
+		}
+
+		if err := s.verify(database.DB(), gh.Comments()[before:]); err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+		if verbose {
+			fmt.Printf("ok: %s\n", s.name)
+		}
+	}
+	return nil
+}
+
+// seed creates the schedule, on-call user and task the payloads act on.
+func seed(db *sql.DB, module *modules.OnCallModule) error {
+	schedule, err := modules.AddSchedule(db, modules.DefaultScheduleTemplate, string(modules.RoundRobinPolicy))
+	if err != nil {
+		return err
+	}
+	user, err := modules.AddUser(db, selftestUser, "Self-test On-call")
+	if err != nil {
+		return err
+	}
+	if err := modules.AssignUserToSchedule(db, schedule.ID, user.ID, 0); err != nil {
+		return err
+	}
+	_, err = module.AddTask(schedule.ID, selftestRepo, selftestIssue, "Self-test issue", "", user.ID)
+	return err
+}
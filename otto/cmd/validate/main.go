@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package main implements validate, which checks a deployment's configuration
+// without starting otto. It loads the config and secrets as otto would, runs
+// the configuration checks of every module and checks that migrations could
+// write to the database, without changing it. It never calls GitHub. Every
+// problem found is printed, and it exits non-zero if there are any.
+//
+// Usage:
+//
+//	validate [-config config.yaml] [-secrets secrets.yaml]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/secrets"
+	"github.com/open-telemetry/sig-project-infra/otto/modules"
+)
+
+func main() {
+	configPath := flag.String("config", config.GetEnvOrDefault("OTTO_CONFIG", "config.yaml"),
+		"config file, or comma-separated files to merge")
+	secretsPath := flag.String("secrets", config.GetEnvOrDefault("OTTO_SECRETS", "secrets.yaml"), "secrets file")
+	flag.Parse()
+
+	// Loading logs a config summary; only warnings are of interest here
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	if err := run(context.Background(), *configPath, *secretsPath, os.Stdout); err != nil {
+		os.Exit(1)
+	}
+}
+
+// run checks the configuration at configPath and the secrets at secretsPath,
+// printing each problem found to w, and returns them joined.
+func run(ctx context.Context, configPath, secretsPath string, w io.Writer) error {
+	err := validate(ctx, configPath, secretsPath)
+	if err == nil {
+		fmt.Fprintln(w, "configuration is valid")
+		return nil
+	}
+
+	// Joined errors put each problem on its own line
+	for _, problem := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintf(w, "invalid: %s\n", problem)
+	}
+	return err
+}
+
+// validate loads the config and secrets and runs the offline startup checks.
+// The remaining checks are skipped when either fails to load.
+func validate(ctx context.Context, configPath, secretsPath string) error {
+	var errs []error
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("config: %w", err))
+	}
+	manager, err := secrets.LoadSecrets(secretsPath)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("secrets: %w", err))
+	}
+	if cfg == nil || manager == nil {
+		return errors.Join(errs...)
+	}
+
+	app := &internal.App{
+		Config:         cfg,
+		Secrets:        manager,
+		ModuleRegistry: internal.NewModuleRegistry(),
+	}
+	for _, m := range modules.All() {
+		app.RegisterModule(m)
+	}
+	app.Database, err = internal.NewDatabaseFromConfig(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("database: %w", err)
+	}
+	defer app.Database.Close()
+	return app.ValidateOffline(ctx)
+}
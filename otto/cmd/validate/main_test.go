@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	const validSecrets = "webhook_secret: secret\n"
+	tests := []struct {
+		name     string
+		config   string
+		secrets  string
+		wantErrs []string
+	}{
+		{
+			name:    "valid",
+			config:  "port: \"8080\"\n",
+			secrets: validSecrets,
+		},
+		{
+			name:     "invalid config",
+			config:   "max_event_age: -1m\n",
+			secrets:  validSecrets,
+			wantErrs: []string{"config: max_event_age must not be negative"},
+		},
+		{
+			name:     "missing webhook secret",
+			config:   "port: \"8080\"\n",
+			secrets:  "github_app_id: 0\n",
+			wantErrs: []string{"secrets: webhook_secret must be set"},
+		},
+		{
+			name:     "invalid config and secrets",
+			config:   "webhook_path: webhook\n",
+			secrets:  "github_app_id: 0\n",
+			wantErrs: []string{"config: webhook_path", "secrets: webhook_secret must be set"},
+		},
+		{
+			name:     "invalid module config",
+			config:   "modules:\n  oncall:\n    resolve_labels: [\"\"]\n",
+			secrets:  validSecrets,
+			wantErrs: []string{"module oncall: resolve_labels must not contain empty labels"},
+		},
+		{
+			name:     "unsupported database driver",
+			config:   "database:\n  driver: postgres\n",
+			secrets:  validSecrets,
+			wantErrs: []string{"config: unsupported database driver"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Secrets from the environment would stand in for missing ones
+			t.Setenv("OTTO_1PASSWORD_CONFIG", "")
+			t.Setenv("OTTO_WEBHOOK_SECRET", "")
+			dir := t.TempDir()
+			configPath := writeFile(t, dir, "config.yaml",
+				tt.config+"db_path: "+filepath.Join(dir, "otto.db")+"\n")
+			secretsPath := writeFile(t, dir, "secrets.yaml", tt.secrets)
+
+			var out strings.Builder
+			err := run(t.Context(), configPath, secretsPath, &out)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				if !strings.Contains(out.String(), "configuration is valid") {
+					t.Errorf("output %q does not report a valid configuration", out.String())
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %q, got nil", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(out.String(), "invalid: "+want) {
+					t.Errorf("output %q does not report %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package main implements verify, a tool for debugging webhook deliveries.
+// It computes the signature GitHub would send for a saved payload, checks a
+// received signature against it, and parses the payload as Otto would.
+//
+// Usage:
+//
+//	verify -payload delivery.json -secret-file secret.txt [-signature sha256=...] [-event issues]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run verifies the delivery described by args, printing the results to stdout
+// and problems to stderr, and returns the exit code: 0 if every check passed,
+// 1 if one failed and 2 for invalid arguments.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	payloadPath := flags.String("payload", "", "file containing the raw webhook request body")
+	secret := flags.String("secret", "", "webhook secret (prefer -secret-file or OTTO_WEBHOOK_SECRET)")
+	secretPath := flags.String("secret-file", "", "file containing the webhook secret")
+	signature := flags.String("signature", "", "received X-Hub-Signature-256 header to check")
+	eventType := flags.String("event", "", "X-GitHub-Event type to parse the payload as")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *payloadPath == "" {
+		fmt.Fprintln(stderr, "verify: -payload is required")
+		flags.Usage()
+		return 2
+	}
+	payload, err := os.ReadFile(*payloadPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "verify: failed to read payload: %v\n", err)
+		return 1
+	}
+
+	key, err := loadSecret(*secret, *secretPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "verify: failed to load secret: %v\n", err)
+		return 1
+	}
+
+	ok := true
+	if key != nil {
+		fmt.Fprintf(stdout, "expected signature: %s\n", internal.SignWebhookPayload(key, payload))
+		if *signature != "" {
+			if internal.VerifyWebhookSignature(payload, *signature, key) {
+				fmt.Fprintln(stdout, "signature: valid")
+			} else {
+				fmt.Fprintln(stdout, "signature: INVALID")
+				ok = false
+			}
+		}
+	}
+
+	if *eventType != "" {
+		if err := describeEvent(stdout, *eventType, payload); err != nil {
+			fmt.Fprintf(stdout, "parse: %v\n", err)
+			ok = false
+		}
+	}
+
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+// loadSecret returns the webhook secret from the flag, the secret file or the
+// OTTO_WEBHOOK_SECRET environment variable, in that order, or nil if none is set.
+func loadSecret(secret, path string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.TrimRight(string(data), "\r\n")), nil
+	}
+	if env := os.Getenv("OTTO_WEBHOOK_SECRET"); env != "" {
+		return []byte(env), nil
+	}
+	return nil, nil
+}
+
+// describeEvent parses payload as Otto's webhook handler does and prints the
+// fields modules act on to w.
+func describeEvent(w io.Writer, eventType string, payload []byte) error {
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "event: %s (%T)\n", eventType, event)
+
+	if e, ok := event.(interface{ GetAction() string }); ok && e.GetAction() != "" {
+		fmt.Fprintf(w, "action: %s\n", e.GetAction())
+	}
+	if e, ok := event.(interface{ GetRepo() *github.Repository }); ok && e.GetRepo() != nil {
+		fmt.Fprintf(w, "repository: %s\n", e.GetRepo().GetFullName())
+	}
+	if e, ok := event.(interface{ GetIssue() *github.Issue }); ok && e.GetIssue() != nil {
+		fmt.Fprintf(w, "issue: #%d\n", e.GetIssue().GetNumber())
+	}
+	if e, ok := event.(interface{ GetSender() *github.User }); ok && e.GetSender() != nil {
+		fmt.Fprintf(w, "sender: %s\n", e.GetSender().GetLogin())
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+)
+
+func TestRun(t *testing.T) {
+	const payload = `{"action":"opened","issue":{"number":7},"repository":{"full_name":"org/repo"}}`
+	valid := internal.SignWebhookPayload([]byte("secret"), []byte(payload))
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput []string
+	}{
+		{
+			name:       "valid signature",
+			args:       []string{"-secret", "secret", "-signature", valid},
+			wantOutput: []string{"expected signature: " + valid, "signature: valid"},
+		},
+		{
+			name:       "invalid signature",
+			args:       []string{"-secret", "other", "-signature", valid},
+			wantCode:   1,
+			wantOutput: []string{"signature: INVALID"},
+		},
+		{
+			name:       "parsed event",
+			args:       []string{"-event", "issues"},
+			wantOutput: []string{"action: opened", "repository: org/repo", "issue: #7"},
+		},
+		{
+			name:       "unknown event type",
+			args:       []string{"-event", "nonsense"},
+			wantCode:   1,
+			wantOutput: []string{"parse:"},
+		},
+		{
+			name:     "missing payload file",
+			args:     []string{"-payload", "missing.json"},
+			wantCode: 1,
+		},
+		{
+			name:     "missing payload flag",
+			args:     []string{"-payload", ""},
+			wantCode: 2,
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"-bogus"},
+			wantCode: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A secret from the environment would stand in for a missing one
+			t.Setenv("OTTO_WEBHOOK_SECRET", "")
+			dir := t.TempDir()
+			payloadPath := filepath.Join(dir, "delivery.json")
+			if err := os.WriteFile(payloadPath, []byte(payload), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Chdir(dir)
+
+			var stdout, stderr strings.Builder
+			args := append([]string{"-payload", payloadPath}, tt.args...)
+			if code := run(args, &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("exit code: want %d, got %d (stderr %q)", tt.wantCode, code, stderr.String())
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("output %q does not contain %q", stdout.String(), want)
+				}
+			}
+		})
+	}
+}
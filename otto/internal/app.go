@@ -5,9 +5,14 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/go-github/v71/github"
 	"github.com/jferrl/go-githubauth"
@@ -28,6 +33,12 @@ type App struct {
 	ModuleRegistry *ModuleRegistry
 	server         *Server
 	shutdownSignal chan struct{}
+	// dispatches tracks in-flight event handlers so Shutdown can drain them.
+	dispatches sync.WaitGroup
+	// dispatchMu guards dispatchStopped, which Shutdown sets before draining
+	// dispatches so that no handler is added while it waits.
+	dispatchMu      sync.Mutex
+	dispatchStopped bool
 }
 
 // NewApp creates and initializes a new application instance.
@@ -49,12 +60,11 @@ func NewApp(ctx context.Context, configPath, secretsPath string) (*App, error) {
 		return nil, err
 	}
 
-	// Initialize app with config and empty module registry
+	// Initialize app with config; the module registry needs telemetry
 	app := &App{
 		Config:         appConfig,
 		Secrets:        secretsManager,
 		Addr:           appConfig.Port,
-		ModuleRegistry: NewModuleRegistry(),
 		shutdownSignal: make(chan struct{}),
 	}
 
@@ -64,20 +74,27 @@ func NewApp(ctx context.Context, configPath, secretsPath string) (*App, error) {
 	}
 
 	// Initialize telemetry
-	app.Telemetry, err = NewTelemetryManager(ctx)
+	app.Telemetry, err = NewTelemetryManager(ctx, appConfig.LogFormat(), appConfig.Metrics)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize telemetry: %w", err)
 	}
 
 	// Get logger from telemetry
 	app.Logger = app.Telemetry.Logger
+	app.ModuleRegistry = NewModuleRegistryWithTelemetry(app.Telemetry)
 
 	// Initialize database
-	app.Database, err = NewDatabase(app.Config.DBPath)
+	app.Database, err = NewDatabaseFromConfig(app.Config.Database)
 	if err != nil {
 		return nil, err
 	}
 
+	// Fail fast on misconfiguration instead of at the first request
+	if err := app.Validate(ctx); err != nil {
+		app.Database.Close()
+		return nil, fmt.Errorf("startup validation failed: %w", err)
+	}
+
 	// Create HTTP server with app reference
 	app.server = NewServerWithApp(app.Addr, app.Secrets, app)
 
@@ -86,10 +103,31 @@ func NewApp(ctx context.Context, configPath, secretsPath string) (*App, error) {
 
 // Start begins all application services.
 func (a *App) Start(ctx context.Context) error {
+	// Refuse to start with invalid module configuration
+	if err := a.validateModules(); err != nil {
+		return err
+	}
+
+	// Reconnect to the database if it becomes unreachable
+	if a.Database != nil && a.Config != nil {
+		go a.Database.KeepAlive(ctx, a.Config.Database.PingInterval)
+	}
+
 	// Initialize and start all modules
 	if err := a.initializeModules(ctx); err != nil {
 		return err
 	}
+	a.server.registerModuleRoutes(a.ModuleRegistry.GetModules())
+	if a.Telemetry != nil {
+		if err := a.Telemetry.ObserveModules(a.ModuleRegistry.ModuleInfo); err != nil {
+			return err
+		}
+		if a.Database != nil {
+			if err := a.Telemetry.ObserveDatabase(a.Database.Stats); err != nil {
+				return err
+			}
+		}
+	}
 
 	// Start HTTP server (non-blocking)
 	go func() {
@@ -105,8 +143,15 @@ func (a *App) Start(ctx context.Context) error {
 // Shutdown gracefully stops all application services.
 func (a *App) Shutdown(ctx context.Context) error {
 	// Shutdown server
-	if err := a.server.Shutdown(ctx); err != nil {
-		a.Logger.Error("Error during server shutdown", "err", err)
+	if a.server != nil {
+		if err := a.server.Shutdown(ctx); err != nil {
+			a.Logger.Error("Error during server shutdown", "err", err)
+		}
+	}
+
+	// Let in-flight event handlers finish before their dependencies go away
+	if err := a.waitForDispatches(ctx); err != nil {
+		a.Logger.Warn("Event handlers still running at shutdown", "err", err)
 	}
 
 	// Shutdown modules
@@ -128,6 +173,13 @@ func (a *App) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	// Release secrets managers that hold clients, such as 1Password
+	if closer, ok := a.Secrets.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			a.Logger.Error("Error closing secrets manager", "err", err)
+		}
+	}
+
 	return nil
 }
 
@@ -151,6 +203,27 @@ func (a *App) GetModules() map[string]Module {
 	return a.ModuleRegistry.GetModules()
 }
 
+// validateModules checks the configuration of every registered module that
+// implements ModuleValidator, reporting all failures together.
+func (a *App) validateModules() error {
+	modules := a.ModuleRegistry.GetModules()
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if validator, ok := modules[name].(ModuleValidator); ok {
+			if err := validator.ValidateConfig(a.Config); err != nil {
+				errs = append(errs, fmt.Errorf("module %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // initializeModules initializes all registered modules.
 func (a *App) initializeModules(ctx context.Context) error {
 	// Get all registered modules
@@ -163,6 +236,7 @@ func (a *App) initializeModules(ctx context.Context) error {
 				return err
 			}
 		}
+		a.ModuleRegistry.SetEnabled(name, true)
 	}
 	return nil
 }
@@ -201,18 +275,103 @@ func (a *App) shutdownModules(ctx context.Context) error {
 
 // Command handling has been removed since commands are processed through events
 
-// DispatchEvent hands an event to all modules.
+// DispatchEvent hands an event to all modules without waiting for them.
 func (a *App) DispatchEvent(eventType string, event any, raw []byte) {
+	a.DispatchEnvelope(newEnvelope(eventType, event, raw))
+}
+
+// DispatchEventAndWait hands an event to all modules, waits for them to
+// finish and reports whether any of them acted on it.
+func (a *App) DispatchEventAndWait(eventType string, event any, raw []byte) bool {
+	return a.DispatchEnvelopeAndWait(newEnvelope(eventType, event, raw))
+}
+
+// DispatchEnvelope hands an event and its delivery metadata to all modules
+// without waiting for them.
+func (a *App) DispatchEnvelope(env *EventEnvelope) {
+	wait := a.dispatch(env)
+	// Record events that no module acted on without blocking the caller
+	go wait()
+}
+
+// DispatchEnvelopeAndWait hands an event and its delivery metadata to all
+// modules, waits for them to finish and reports whether any of them acted on
+// it.
+func (a *App) DispatchEnvelopeAndWait(env *EventEnvelope) bool {
+	return a.dispatch(env)()
+}
+
+// newEnvelope wraps an event without delivery metadata, received now.
+func newEnvelope(eventType string, event any, raw []byte) *EventEnvelope {
+	return &EventEnvelope{Type: eventType, Event: event, Raw: raw, ReceivedAt: time.Now()}
+}
+
+// dispatch starts the modules' handlers for an event. The returned function
+// waits for them, records the event if none acted on it and reports whether
+// one did. Events dispatched once Shutdown has begun are dropped.
+func (a *App) dispatch(env *EventEnvelope) func() bool {
+	eventType := env.Type
 	// Get all registered modules
 	modules := a.ModuleRegistry.GetModules()
 
+	var wg sync.WaitGroup
+	var handled atomic.Bool
+
+	a.dispatchMu.Lock()
+	defer a.dispatchMu.Unlock()
+	if a.dispatchStopped {
+		a.Logger.Warn("Dropping event received during shutdown", "event", eventType)
+		return func() bool { return false }
+	}
+
 	for name, mod := range modules {
+		if !wantsEvent(mod, eventType) {
+			continue
+		}
+		wg.Add(1)
+		a.dispatches.Add(1)
 		go func(n string, m Module) {
-			if err := m.HandleEvent(eventType, event, raw); err != nil {
+			defer a.dispatches.Done()
+			defer wg.Done()
+			ok, err := handleEnvelope(m, env)
+			if err != nil {
 				a.Logger.Error("Event handling error", "module", n, "event", eventType, "err", err)
+				return
+			}
+			if ok {
+				handled.Store(true)
 			}
 		}(name, mod)
 	}
+
+	return func() bool {
+		wg.Wait()
+		if !handled.Load() && a.Telemetry != nil {
+			a.Telemetry.IncUnhandledEvent(context.Background(), eventType)
+		}
+		return handled.Load()
+	}
+}
+
+// waitForDispatches stops dispatching events and blocks until all dispatched
+// event handlers have returned or ctx is done.
+func (a *App) waitForDispatches(ctx context.Context) error {
+	a.dispatchMu.Lock()
+	a.dispatchStopped = true
+	a.dispatchMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		a.dispatches.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // initializeGitHubClient sets up the GitHub API client with proper authentication.
@@ -228,6 +387,10 @@ func (a *App) initializeGitHubClient(ctx context.Context) error {
 			return fmt.Errorf("incomplete GitHub App credentials provided: appID=%d, installID=%d, privateKeyLength=%d",
 				appID, installID, len(privateKey))
 		}
+		// Fail at boot with a clear reason instead of at the first API call
+		if err := secrets.ValidatePrivateKey(privateKey); err != nil {
+			return err
+		}
 		// Use GitHub App authentication
 		appTokenSource, err := githubauth.NewApplicationTokenSource(appID, privateKey)
 		if err != nil {
@@ -237,7 +400,10 @@ func (a *App) initializeGitHubClient(ctx context.Context) error {
 		installationTokenSource := githubauth.NewInstallationTokenSource(installID, appTokenSource)
 
 		// Create an HTTP client that uses the installation token
-		httpClient := oauth2.NewClient(ctx, installationTokenSource)
+		httpClient := withGitHubTimeout(
+			oauth2.NewClient(ctx, installationTokenSource),
+			a.Config.GitHubTimeout,
+		)
 
 		// Create a new GitHub client with the custom HTTP client
 		a.GitHubClient = github.NewClient(httpClient)
@@ -246,7 +412,7 @@ func (a *App) initializeGitHubClient(ctx context.Context) error {
 			"installation_id", installID)
 	} else {
 		// If no authentication configured, use unauthenticated client
-		a.GitHubClient = github.NewClient(nil)
+		a.GitHubClient = github.NewClient(withGitHubTimeout(nil, a.Config.GitHubTimeout))
 		slog.Info("GitHub client initialized (no auth)")
 	}
 
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cache provides an in-memory cache whose entries expire, for values
+// that are costly to look up and may go stale, such as resolved secrets.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Clock tells the current time. internal.Clock is an alias for it.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is a Clock backed by the system clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// TTL caches values by key until they expire. Once it holds its maximum
+// number of entries, setting another evicts the least recently used one. Its
+// methods are safe for concurrent use.
+type TTL[K comparable, V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	clock   Clock
+	entries map[K]*list.Element
+	// order holds the entries, most recently used first.
+	order *list.List
+}
+
+// entry is a cached value and when it expires.
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// New returns a cache whose entries expire ttl after they are set and that
+// holds at most maxSize entries. A ttl that isn't positive keeps entries until
+// they are evicted or invalidated, and a maxSize that isn't positive doesn't
+// limit the size. A nil clock means the system clock.
+func New[K comparable, V any](ttl time.Duration, maxSize int, clock Clock) *TTL[K, V] {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &TTL[K, V]{
+		ttl:     ttl,
+		maxSize: maxSize,
+		clock:   clock,
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the value cached for key and whether an unexpired one was.
+func (c *TTL[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	e := elem.Value.(*entry[K, V])
+	if c.ttl > 0 && !c.clock.Now().Before(e.expires) {
+		c.remove(elem)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return e.value, true
+}
+
+// Set caches value for key, replacing any cached value, and evicts the least
+// recently used entry if the cache is over its maximum size.
+func (c *TTL[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.clock.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*entry[K, V])
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+	if c.maxSize > 0 && c.order.Len() > c.maxSize {
+		c.remove(c.order.Back())
+	}
+}
+
+// Invalidate forgets the value cached for key.
+func (c *TTL[K, V]) Invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Clear forgets every cached value.
+func (c *TTL[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+}
+
+// Len returns the number of cached entries, including expired ones that
+// haven't been looked up since.
+func (c *TTL[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove drops an entry. The caller must hold mu.
+func (c *TTL[K, V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when advanced.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestTTLExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		advance time.Duration
+		want    bool
+	}{
+		{"before expiry", time.Minute, 59 * time.Second, true},
+		{"at expiry", time.Minute, time.Minute, false},
+		{"after expiry", time.Minute, time.Hour, false},
+		{"no ttl", 0, 24 * time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+			c := New[string, int](tt.ttl, 0, clock)
+			c.Set("a", 1)
+
+			clock.now = clock.now.Add(tt.advance)
+			got, ok := c.Get("a")
+			if ok != tt.want {
+				t.Fatalf("Get found a value: got %v, want %v", ok, tt.want)
+			}
+			if ok && got != 1 {
+				t.Errorf("Get = %d, want 1", got)
+			}
+			if !ok && c.Len() != 0 {
+				t.Errorf("expired entry still cached, Len = %d", c.Len())
+			}
+		})
+	}
+}
+
+func TestTTLSetRenewsExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	c := New[string, int](time.Minute, 0, clock)
+	c.Set("a", 1)
+	clock.now = clock.now.Add(45 * time.Second)
+	c.Set("a", 2)
+	clock.now = clock.now.Add(45 * time.Second)
+
+	if got, ok := c.Get("a"); !ok || got != 2 {
+		t.Errorf("Get = %d, %v, want 2, true", got, ok)
+	}
+}
+
+func TestTTLEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](time.Minute, 2, &fakeClock{})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// Using a makes b the least recently used
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a was not cached")
+	}
+	c.Set("c", 3)
+
+	for _, tt := range []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	} {
+		if _, ok := c.Get(tt.key); ok != tt.want {
+			t.Errorf("%s cached: got %v, want %v", tt.key, ok, tt.want)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, want 2", c.Len())
+	}
+}
+
+func TestTTLInvalidateAndClear(t *testing.T) {
+	c := New[string, int](0, 0, nil)
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	c.Invalidate("a")
+	c.Invalidate("missing")
+	if _, ok := c.Get("a"); ok {
+		t.Error("a is still cached after Invalidate")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("Invalidate forgot b")
+	}
+
+	c.Clear()
+	if c.Len() != 0 {
+		t.Errorf("Len after Clear = %d, want 0", c.Len())
+	}
+}
@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// clock.go abstracts the current time so time-based logic can be tested.
+
+package internal
+
+import (
+	"time"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal/cache"
+)
+
+// Clock tells the current time. It is defined in the cache package, which
+// can't import this one, so that caches can share the app's clock.
+type Clock = cache.Clock
+
+// RealClock is a Clock backed by the system clock.
+type RealClock struct{}
+
+// Now returns the current system time.
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
@@ -4,23 +4,154 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultGitHubTimeout bounds a single GitHub API call when no timeout is configured.
+const DefaultGitHubTimeout = 15 * time.Second
+
+// MaxGitHubPerPage is the most items GitHub returns per page of a list, and
+// the page size used when none is configured.
+const MaxGitHubPerPage = 100
+
+// Default HTTP paths for the webhook and health check endpoints.
+const (
+	DefaultWebhookPath   = "/webhook"
+	DefaultLivenessPath  = "/check/liveness"
+	DefaultReadinessPath = "/check/readiness"
+)
+
+// DefaultMaxWebhookBodyBytes matches the largest payload GitHub delivers.
+const DefaultMaxWebhookBodyBytes = 25 << 20
+
+// DefaultRecentEvents is how many received webhooks /debug/events lists when
+// no number is configured.
+const DefaultRecentEvents = 100
+
+// DriverSQLite is the database driver used when none is configured.
+const DriverSQLite = "sqlite"
+
+// SupportedDrivers lists the database drivers otto can connect with.
+var SupportedDrivers = []string{DriverSQLite}
+
 // AppConfig contains non-secret application configuration.
 type AppConfig struct {
-	Port    string         `yaml:"port"`
-	DBPath  string         `yaml:"db_path"`
-	Log     map[string]any `yaml:"log"`
-	Modules map[string]any `yaml:"modules"`
+	Port string `yaml:"port"`
+	// WebhookPath is where GitHub webhooks are received, such as a
+	// non-guessable path behind a reverse proxy.
+	WebhookPath   string `yaml:"webhook_path"`
+	LivenessPath  string `yaml:"liveness_path"`
+	ReadinessPath string `yaml:"readiness_path"`
+	// MaxWebhookBodyBytes rejects larger webhook bodies with 413.
+	MaxWebhookBodyBytes int64 `yaml:"max_webhook_body_bytes"`
+	// SyncDispatch makes the webhook wait for modules to handle an event and
+	// answer 204 No Content when none acted on it.
+	SyncDispatch bool `yaml:"sync_dispatch"`
+	// Repositories lists the "owner/name" repositories whose webhooks are
+	// dispatched to modules. Either part may be a glob pattern, such as
+	// "open-telemetry/*". Events of other repositories are answered with 200
+	// and dropped. An empty list dispatches every repository's events.
+	Repositories []string `yaml:"repositories"`
+	// MaxEventAge rejects webhooks whose event happened longer ago, such as
+	// deliveries replayed after an outage that would act on stale state. The
+	// event time is when its comment, review, issue or pull request was last
+	// updated; events without one are accepted. Zero disables the check.
+	MaxEventAge time.Duration `yaml:"max_event_age"`
+	// RecentEvents is how many received webhooks are kept in memory, with
+	// what became of them, for GET /debug/events. Defaults to
+	// DefaultRecentEvents; a negative number keeps none.
+	RecentEvents int `yaml:"recent_events"`
+	// DBPath is shorthand for a SQLite database file when Database.DSN is unset.
+	DBPath        string         `yaml:"db_path"`
+	Database      DatabaseConfig `yaml:"database"`
+	GitHubTimeout time.Duration  `yaml:"github_timeout"`
+	// GitHubPerPage is how many items each GitHub API list request asks for.
+	// Defaults to, and is capped at, MaxGitHubPerPage.
+	GitHubPerPage int            `yaml:"github_per_page"`
+	Log           map[string]any `yaml:"log"`
+	Metrics       MetricsConfig  `yaml:"metrics"`
+	Modules       map[string]any `yaml:"modules"`
+}
+
+// DefaultRequestLatencyBuckets are the request latency histogram bucket
+// boundaries, in milliseconds, used when none are configured.
+var DefaultRequestLatencyBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// DefaultAckLatencyBuckets are the ack latency histogram bucket boundaries,
+// in milliseconds, used when none are configured. They range from a minute to
+// a day, as acknowledgments take minutes to hours.
+var DefaultAckLatencyBuckets = []float64{
+	60_000, 300_000, 900_000, 1_800_000, 3_600_000, 7_200_000, 14_400_000, 28_800_000, 86_400_000,
+}
+
+// MetricsConfig tunes the metrics otto records.
+type MetricsConfig struct {
+	// RequestLatencyBuckets are the bucket boundaries, in milliseconds, of
+	// the request latency histogram. Defaults to DefaultRequestLatencyBuckets.
+	RequestLatencyBuckets []float64 `yaml:"request_latency_buckets_ms"`
+	// AckLatencyBuckets are the bucket boundaries, in milliseconds, of the
+	// issue to ack latency histogram. Defaults to DefaultAckLatencyBuckets.
+	AckLatencyBuckets []float64 `yaml:"ack_latency_buckets_ms"`
+}
+
+// RequestBuckets returns the effective request latency bucket boundaries.
+func (c MetricsConfig) RequestBuckets() []float64 {
+	if len(c.RequestLatencyBuckets) == 0 {
+		return DefaultRequestLatencyBuckets
+	}
+	return c.RequestLatencyBuckets
+}
+
+// AckBuckets returns the effective ack latency bucket boundaries.
+func (c MetricsConfig) AckBuckets() []float64 {
+	if len(c.AckLatencyBuckets) == 0 {
+		return DefaultAckLatencyBuckets
+	}
+	return c.AckLatencyBuckets
+}
+
+// validateBuckets checks that histogram bucket boundaries are non-negative
+// and strictly increasing.
+func validateBuckets(name string, buckets []float64) error {
+	for i, b := range buckets {
+		if b < 0 {
+			return fmt.Errorf("%s must not be negative, got %v", name, b)
+		}
+		if i > 0 && b <= buckets[i-1] {
+			return fmt.Errorf("%s must be strictly increasing, got %v after %v", name, b, buckets[i-1])
+		}
+	}
+	return nil
+}
+
+// DefaultDatabasePingInterval is how often the database connection is checked
+// when no interval is configured.
+const DefaultDatabasePingInterval = 30 * time.Second
+
+// DatabaseConfig selects the database driver and its connection string.
+type DatabaseConfig struct {
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+	// PingInterval is how often the connection is checked for the readiness
+	// probe. A negative value disables the check.
+	PingInterval time.Duration `yaml:"ping_interval"`
 }
 
-// Load reads YAML config from path and returns an AppConfig.
+// Load reads YAML config from path and returns an AppConfig. A
+// comma-separated list of paths is merged with LoadMerged.
 func Load(path string) (*AppConfig, error) {
+	if strings.Contains(path, ",") {
+		return LoadMerged(strings.Split(path, ",")...)
+	}
 	return LoadFromFile(path)
 }
 
@@ -37,10 +168,84 @@ func LoadFromFile(path string) (*AppConfig, error) {
 	if err := decoder.Decode(config); err != nil {
 		return nil, fmt.Errorf("failed to decode config: %w", err)
 	}
+	return finishLoad(config)
+}
+
+// LoadMerged reads a base config followed by overlays, such as config.yaml
+// and config.prod.yaml, and returns their merged AppConfig. Maps, including
+// module settings, are merged key by key; any other value in a later file
+// replaces the earlier one. A key that is a map in one file and not in
+// another is an error.
+func LoadMerged(paths ...string) (*AppConfig, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no config files given")
+	}
+
+	merged := map[string]any{}
+	for _, path := range paths {
+		data, err := os.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open config file: %w", err)
+		}
+		var layer map[string]any
+		if err := yaml.Unmarshal(data, &layer); err != nil {
+			return nil, fmt.Errorf("failed to decode config %s: %w", path, err)
+		}
+		if err := mergeMaps(merged, layer, ""); err != nil {
+			return nil, fmt.Errorf("failed to merge config %s: %w", path, err)
+		}
+	}
 
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merged config: %w", err)
+	}
+	config := &AppConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	return finishLoad(config)
+}
+
+// mergeMaps merges src into dst, recursing into maps present in both.
+// prefix is the dotted key of dst, used in errors.
+func mergeMaps(dst, src map[string]any, prefix string) error {
+	for key, value := range src {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+
+		existing, ok := dst[key]
+		if !ok || existing == nil || value == nil {
+			dst[key] = value
+			continue
+		}
+		existingMap, existingIsMap := existing.(map[string]any)
+		valueMap, valueIsMap := value.(map[string]any)
+		switch {
+		case existingIsMap && valueIsMap:
+			if err := mergeMaps(existingMap, valueMap, name); err != nil {
+				return err
+			}
+		case existingIsMap != valueIsMap:
+			return fmt.Errorf("config key %q is a map in one file but not in another", name)
+		default:
+			dst[key] = value
+		}
+	}
+	return nil
+}
+
+// finishLoad applies defaults to a decoded config, validates it and logs a summary.
+func finishLoad(config *AppConfig) (*AppConfig, error) {
 	// Apply defaults
 	ApplyDefaults(config)
 
+	if err := Validate(config); err != nil {
+		return nil, err
+	}
+
 	// Log configuration summary
 	LogSummary(config)
 
@@ -49,8 +254,43 @@ func LoadFromFile(path string) (*AppConfig, error) {
 
 // Validate checks that all required config fields are present and valid.
 func Validate(config *AppConfig) error {
-	// No required fields in non-secret config
-	return nil
+	seen := make(map[string]string)
+	for _, p := range []struct{ name, path string }{
+		{"webhook_path", config.WebhookPath},
+		{"liveness_path", config.LivenessPath},
+		{"readiness_path", config.ReadinessPath},
+	} {
+		if !strings.HasPrefix(p.path, "/") {
+			return fmt.Errorf("%s %q must start with /", p.name, p.path)
+		}
+		if other, ok := seen[p.path]; ok {
+			return fmt.Errorf("%s %q is already used by %s", p.name, p.path, other)
+		}
+		seen[p.path] = p.name
+	}
+
+	if !slices.Contains(SupportedDrivers, config.Database.Driver) {
+		return fmt.Errorf("unsupported database driver %q, expected one of %v",
+			config.Database.Driver, SupportedDrivers)
+	}
+
+	if config.MaxEventAge < 0 {
+		return fmt.Errorf("max_event_age must not be negative, got %v", config.MaxEventAge)
+	}
+
+	for _, pattern := range config.Repositories {
+		if strings.Count(pattern, "/") != 1 {
+			return fmt.Errorf("invalid repository pattern %q: must be owner/name", pattern)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid repository pattern %q: %w", pattern, err)
+		}
+	}
+
+	if err := validateBuckets("metrics.request_latency_buckets_ms", config.Metrics.RequestLatencyBuckets); err != nil {
+		return err
+	}
+	return validateBuckets("metrics.ack_latency_buckets_ms", config.Metrics.AckLatencyBuckets)
 }
 
 // ApplyDefaults sets default values for optional config fields.
@@ -59,10 +299,45 @@ func ApplyDefaults(config *AppConfig) {
 		config.Port = "8080"
 	}
 
+	if config.WebhookPath == "" {
+		config.WebhookPath = DefaultWebhookPath
+	}
+	if config.LivenessPath == "" {
+		config.LivenessPath = DefaultLivenessPath
+	}
+	if config.ReadinessPath == "" {
+		config.ReadinessPath = DefaultReadinessPath
+	}
+
+	if config.MaxWebhookBodyBytes <= 0 {
+		config.MaxWebhookBodyBytes = DefaultMaxWebhookBodyBytes
+	}
+	if config.RecentEvents == 0 {
+		config.RecentEvents = DefaultRecentEvents
+	}
+
 	if config.DBPath == "" {
 		config.DBPath = "data.db"
 	}
 
+	if config.Database.Driver == "" {
+		config.Database.Driver = DriverSQLite
+	}
+	if config.Database.DSN == "" && config.Database.Driver == DriverSQLite {
+		config.Database.DSN = config.DBPath
+	}
+
+	if config.Database.PingInterval == 0 {
+		config.Database.PingInterval = DefaultDatabasePingInterval
+	}
+
+	if config.GitHubTimeout <= 0 {
+		config.GitHubTimeout = DefaultGitHubTimeout
+	}
+	if config.GitHubPerPage <= 0 || config.GitHubPerPage > MaxGitHubPerPage {
+		config.GitHubPerPage = MaxGitHubPerPage
+	}
+
 	if config.Log == nil {
 		config.Log = map[string]any{
 			"level":  "info",
@@ -71,15 +346,93 @@ func ApplyDefaults(config *AppConfig) {
 	}
 }
 
+// IsRepositoryAllowed reports whether events of the repository, given by its
+// full name, are dispatched to modules. Matching is case-insensitive, like
+// GitHub names.
+func (c *AppConfig) IsRepositoryAllowed(repo string) bool {
+	if len(c.Repositories) == 0 {
+		return true
+	}
+	repo = strings.ToLower(repo)
+	for _, pattern := range c.Repositories {
+		if ok, _ := path.Match(strings.ToLower(pattern), repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LogFormat returns the configured log format, "json" or "text".
+// Anything other than "text" is treated as "json".
+func (c *AppConfig) LogFormat() string {
+	if format, ok := c.Log["format"].(string); ok && format == "text" {
+		return "text"
+	}
+	return "json"
+}
+
 // LogSummary logs a sanitized summary of the loaded configuration.
 func LogSummary(config *AppConfig) {
 	slog.Info("configuration loaded",
 		"port", config.Port,
-		"db_path", config.DBPath,
+		"webhook_path", config.WebhookPath,
+		"db_driver", config.Database.Driver,
 		"log_level", config.Log["level"],
 		"modules_configured", len(config.Modules))
 }
 
+// RedactedValue replaces secret-looking values in Redacted.
+const RedactedValue = "[REDACTED]"
+
+// secretKeyWords are the words that make a configuration key look secret,
+// compared ignoring case, underscores and dashes.
+var secretKeyWords = []string{"secret", "token", "password", "passwd", "credential", "privatekey", "apikey"}
+
+// Redacted returns the configuration as a generic map keyed by its YAML
+// names, with the value of every key that looks secret, at any depth,
+// replaced by RedactedValue.
+func Redacted(config *AppConfig) (map[string]any, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	var out map[string]any
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	redact(out)
+	return out, nil
+}
+
+// redact replaces secret-looking values in maps nested anywhere in v.
+func redact(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if value != nil && value != "" && isSecretKey(key) {
+				v[key] = RedactedValue
+				continue
+			}
+			redact(value)
+		}
+	case []any:
+		for _, item := range v {
+			redact(item)
+		}
+	}
+}
+
+// isSecretKey reports whether a configuration key looks like it holds a secret.
+func isSecretKey(key string) bool {
+	key = strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	for _, word := range secretKeyWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetEnvOrDefault returns the value of the environment variable with the given key,
 // or the default value if the environment variable is not set.
 func GetEnvOrDefault(key, defaultValue string) string {
@@ -3,8 +3,13 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadFromFile(t *testing.T) {
@@ -19,6 +24,7 @@ func TestLoadFromFile(t *testing.T) {
 	testConfig := `
 port: "9090"
 db_path: "test.db"
+github_timeout: "30s"
 log:
   level: "debug"
   format: "json"
@@ -45,6 +51,9 @@ modules:
 	if config.DBPath != "test.db" {
 		t.Errorf("Expected db_path test.db, got %s", config.DBPath)
 	}
+	if config.GitHubTimeout != 30*time.Second {
+		t.Errorf("Expected github_timeout 30s, got %s", config.GitHubTimeout)
+	}
 	if config.Log["level"] != "debug" {
 		t.Errorf("Expected log level debug, got %s", config.Log["level"])
 	}
@@ -67,15 +76,332 @@ func TestApplyDefaults(t *testing.T) {
 	if config.Port != "8080" {
 		t.Errorf("Expected default port 8080, got %s", config.Port)
 	}
+	if config.WebhookPath != DefaultWebhookPath {
+		t.Errorf("Expected default webhook_path /webhook, got %s", config.WebhookPath)
+	}
 	if config.DBPath != "data.db" {
 		t.Errorf("Expected default db_path data.db, got %s", config.DBPath)
 	}
+	if config.GitHubTimeout != 15*time.Second {
+		t.Errorf("Expected default github_timeout 15s, got %s", config.GitHubTimeout)
+	}
+	if config.GitHubPerPage != MaxGitHubPerPage {
+		t.Errorf("Expected default github_per_page %d, got %d", MaxGitHubPerPage, config.GitHubPerPage)
+	}
+	if config.Database.Driver != DriverSQLite {
+		t.Errorf("Expected default database driver sqlite, got %s", config.Database.Driver)
+	}
+	if config.Database.DSN != "data.db" {
+		t.Errorf("Expected default database dsn data.db, got %s", config.Database.DSN)
+	}
 	if config.Log["level"] != "info" {
 		t.Errorf("Expected default log level info, got %s", config.Log["level"])
 	}
 	if config.Log["format"] != "json" {
 		t.Errorf("Expected default log format json, got %s", config.Log["format"])
 	}
+
+	// Page sizes above GitHub's maximum are capped
+	config = &AppConfig{GitHubPerPage: 500}
+	ApplyDefaults(config)
+	if config.GitHubPerPage != MaxGitHubPerPage {
+		t.Errorf("Expected github_per_page capped at %d, got %d", MaxGitHubPerPage, config.GitHubPerPage)
+	}
+}
+
+func TestDatabaseConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     AppConfig
+		wantDriver string
+		wantDSN    string
+		wantErr    bool
+	}{
+		{
+			name:       "db_path shorthand",
+			config:     AppConfig{DBPath: "otto.db"},
+			wantDriver: DriverSQLite,
+			wantDSN:    "otto.db",
+		},
+		{
+			name: "explicit dsn wins over db_path",
+			config: AppConfig{
+				DBPath:   "otto.db",
+				Database: DatabaseConfig{Driver: "sqlite", DSN: "file:other.db"},
+			},
+			wantDriver: DriverSQLite,
+			wantDSN:    "file:other.db",
+		},
+		{
+			name:       "unknown driver",
+			config:     AppConfig{Database: DatabaseConfig{Driver: "oracle", DSN: "x"}},
+			wantDriver: "oracle",
+			wantDSN:    "x",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			ApplyDefaults(&config)
+			if config.Database.Driver != tt.wantDriver {
+				t.Errorf("driver = %q, want %q", config.Database.Driver, tt.wantDriver)
+			}
+			if config.Database.DSN != tt.wantDSN {
+				t.Errorf("dsn = %q, want %q", config.Database.DSN, tt.wantDSN)
+			}
+			if err := Validate(&config); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AppConfig
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "custom webhook path", config: AppConfig{WebhookPath: "/hooks/abc123"}},
+		{
+			name:    "relative webhook path",
+			config:  AppConfig{WebhookPath: "webhook"},
+			wantErr: `webhook_path "webhook" must start with /`,
+		},
+		{
+			name:    "duplicate paths",
+			config:  AppConfig{ReadinessPath: "/check/liveness"},
+			wantErr: "is already used by liveness_path",
+		},
+		{
+			name:   "custom latency buckets",
+			config: AppConfig{Metrics: MetricsConfig{RequestLatencyBuckets: []float64{0, 10, 100}}},
+		},
+		{
+			name:    "unsorted latency buckets",
+			config:  AppConfig{Metrics: MetricsConfig{AckLatencyBuckets: []float64{100, 10}}},
+			wantErr: "metrics.ack_latency_buckets_ms must be strictly increasing",
+		},
+		{
+			name:    "negative latency bucket",
+			config:  AppConfig{Metrics: MetricsConfig{RequestLatencyBuckets: []float64{-1, 10}}},
+			wantErr: "metrics.request_latency_buckets_ms must not be negative",
+		},
+		{name: "max event age", config: AppConfig{MaxEventAge: time.Hour}},
+		{
+			name:    "negative max event age",
+			config:  AppConfig{MaxEventAge: -time.Minute},
+			wantErr: "max_event_age must not be negative",
+		},
+		{name: "repository patterns", config: AppConfig{Repositories: []string{"org/repo", "open-telemetry/*"}}},
+		{
+			name:    "repository pattern without owner",
+			config:  AppConfig{Repositories: []string{"repo"}},
+			wantErr: `invalid repository pattern "repo": must be owner/name`,
+		},
+		{
+			name:    "malformed repository pattern",
+			config:  AppConfig{Repositories: []string{"org/[repo"}},
+			wantErr: `invalid repository pattern "org/[repo"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			ApplyDefaults(&config)
+			err := Validate(&config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMerged(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	base := writeConfig("config.yaml", `
+port: "8080"
+github_timeout: "10s"
+modules:
+  oncall:
+    maintainers: [alice, bob]
+    default_schedule: primary
+  other:
+    enabled: true
+`)
+	prod := writeConfig("config.prod.yaml", `
+port: "9090"
+modules:
+  oncall:
+    maintainers: [carol]
+    read_only: true
+`)
+	conflict := writeConfig("conflict.yaml", `
+modules:
+  oncall: disabled
+`)
+
+	t.Run("later files win", func(t *testing.T) {
+		config, err := LoadMerged(base, prod)
+		if err != nil {
+			t.Fatalf("LoadMerged failed: %v", err)
+		}
+		if config.Port != "9090" {
+			t.Errorf("port: want 9090, got %s", config.Port)
+		}
+		if config.GitHubTimeout != 10*time.Second {
+			t.Errorf("github_timeout: want 10s from the base, got %s", config.GitHubTimeout)
+		}
+	})
+
+	t.Run("module settings are merged", func(t *testing.T) {
+		config, err := LoadMerged(base, prod)
+		if err != nil {
+			t.Fatalf("LoadMerged failed: %v", err)
+		}
+		oncall, _ := config.Modules["oncall"].(map[string]any)
+		if oncall["default_schedule"] != "primary" {
+			t.Errorf("default_schedule: want primary from the base, got %v", oncall["default_schedule"])
+		}
+		if oncall["read_only"] != true {
+			t.Errorf("read_only: want true from the overlay, got %v", oncall["read_only"])
+		}
+		if got := oncall["maintainers"]; !reflect.DeepEqual(got, []any{"carol"}) {
+			t.Errorf("maintainers: want the overlay's list, got %v", got)
+		}
+		if _, ok := config.Modules["other"]; !ok {
+			t.Errorf("module missing from the overlay was dropped")
+		}
+	})
+
+	t.Run("comma-separated paths", func(t *testing.T) {
+		config, err := Load(base + "," + prod)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if config.Port != "9090" {
+			t.Errorf("port: want 9090, got %s", config.Port)
+		}
+	})
+
+	t.Run("map replaced by scalar", func(t *testing.T) {
+		_, err := LoadMerged(base, conflict)
+		if err == nil || !strings.Contains(err.Error(), `"modules.oncall"`) {
+			t.Errorf("want an error naming modules.oncall, got %v", err)
+		}
+	})
+
+	t.Run("missing overlay", func(t *testing.T) {
+		if _, err := LoadMerged(base, filepath.Join(dir, "missing.yaml")); err == nil {
+			t.Errorf("want an error for a missing file")
+		}
+	})
+}
+
+func TestLogFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		log  map[string]any
+		want string
+	}{
+		{"text format", map[string]any{"format": "text"}, "text"},
+		{"json format", map[string]any{"format": "json"}, "json"},
+		{"unknown format", map[string]any{"format": "xml"}, "json"},
+		{"missing format", map[string]any{"level": "info"}, "json"},
+		{"no log config", nil, "json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &AppConfig{Log: tt.log}
+			if got := config.LogFormat(); got != tt.want {
+				t.Errorf("LogFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRepositoryAllowed(t *testing.T) {
+	tests := []struct {
+		name         string
+		repositories []string
+		repo         string
+		want         bool
+	}{
+		{"no allowlist allows all", nil, "any/repo", true},
+		{"exact match", []string{"org/repo"}, "org/repo", true},
+		{"case-insensitive match", []string{"Org/Repo"}, "org/repo", true},
+		{"owner glob", []string{"org/*"}, "org/other", true},
+		{"not listed", []string{"org/repo", "org/*-sdk"}, "other/repo", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &AppConfig{Repositories: tt.repositories}
+			if got := config.IsRepositoryAllowed(tt.repo); got != tt.want {
+				t.Errorf("IsRepositoryAllowed(%q) = %v, want %v", tt.repo, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	config := &AppConfig{
+		Port: "9090",
+		Modules: map[string]any{
+			"oncall": map[string]any{
+				"maintainers":          []any{"octocat"},
+				"allow_author_resolve": true,
+				"webhook_secret":       "hunter2",
+				"notify": []any{
+					map[string]any{"url": "https://example.com", "API-Token": "tok-123"},
+				},
+				"slack":       map[string]any{"password": "pa55", "credentials": map[string]any{"id": "cred-id"}},
+				"empty_token": "",
+			},
+		},
+	}
+	ApplyDefaults(config)
+
+	redacted, err := Redacted(config)
+	if err != nil {
+		t.Fatalf("Redacted failed: %v", err)
+	}
+	out, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	for _, secret := range []string{"hunter2", "tok-123", "pa55", "cred-id"} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("output contains secret %q: %s", secret, out)
+		}
+	}
+	for _, want := range []string{
+		`"port":"9090"`, `"webhook_path":"/webhook"`, `"github_timeout":"15s"`, `"maintainers":["octocat"]`,
+		`"allow_author_resolve":true`, `"webhook_secret":"[REDACTED]"`, `"empty_token":""`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output missing %s: %s", want, out)
+		}
+	}
 }
 
 func TestGetEnvOrDefault(t *testing.T) {
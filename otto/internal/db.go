@@ -5,8 +5,15 @@
 package internal
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
 
 	// Import sqlite driver for database/sql.
 	_ "modernc.org/sqlite"
@@ -14,12 +21,25 @@ import (
 
 // Database encapsulates database connection management.
 type Database struct {
-	db *sql.DB
+	db  *sql.DB
+	dsn string
+
+	// unhealthy is set while the keep-alive can't reach the database.
+	unhealthy atomic.Bool
 }
 
 // NewDatabase creates a new database connection with the provided path.
 func NewDatabase(dbPath string) (*Database, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := openSQLite(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Database{db: db, dsn: dbPath}, nil
+}
+
+// openSQLite opens and pings a SQLite database.
+func openSQLite(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -29,8 +49,17 @@ func NewDatabase(dbPath string) (*Database, error) {
 		db.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	return db, nil
+}
 
-	return &Database{db: db}, nil
+// NewDatabaseFromConfig opens the database selected by the configured driver.
+func NewDatabaseFromConfig(cfg config.DatabaseConfig) (*Database, error) {
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		return NewDatabase(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
 }
 
 // Close closes the database connection.
@@ -46,6 +75,62 @@ func (d *Database) DB() *sql.DB {
 	return d.db
 }
 
+// Stats returns the connection pool statistics.
+func (d *Database) Stats() sql.DBStats {
+	return d.DB().Stats()
+}
+
+// Healthy reports whether the last keep-alive check reached the database.
+func (d *Database) Healthy() bool {
+	return !d.unhealthy.Load()
+}
+
+// KeepAlive pings the database every interval until ctx is done, to report
+// whether it is reachable through Healthy. Broken connections are reopened by
+// the connection pool itself, so the handle callers hold stays valid. It
+// returns immediately for in-memory databases, which can't be reopened, and
+// when interval is not positive.
+func (d *Database) KeepAlive(ctx context.Context, interval time.Duration) {
+	if interval <= 0 || isInMemoryDSN(d.dsn) {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.checkConnection(ctx)
+		}
+	}
+}
+
+// checkConnection pings the database and records whether it was reached. A
+// ping that needs a new connection opens one, so a ping succeeding after a
+// failure means the pool has reconnected.
+func (d *Database) checkConnection(ctx context.Context) {
+	if err := d.db.PingContext(ctx); err != nil {
+		slog.Warn("Database ping failed", "error", err)
+		d.setHealthy(false)
+		return
+	}
+	d.setHealthy(true)
+}
+
+// setHealthy records the keep-alive result, logging recovery.
+func (d *Database) setHealthy(healthy bool) {
+	if d.unhealthy.Swap(!healthy) && healthy {
+		slog.Info("Database connection recovered")
+	}
+}
+
+// isInMemoryDSN reports whether dsn names a SQLite in-memory database.
+func isInMemoryDSN(dsn string) bool {
+	return dsn == "" || strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
+
 // OpenDB opens a new database connection with the given path.
 // Use this for tests or when you need a separate connection.
 // Deprecated: Use NewDatabase instead.
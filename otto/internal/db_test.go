@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+)
+
+func TestNewDatabaseFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.DatabaseConfig
+		wantErr bool
+	}{
+		{"sqlite", config.DatabaseConfig{Driver: config.DriverSQLite, DSN: ":memory:"}, false},
+		{
+			"sqlite file",
+			config.DatabaseConfig{Driver: config.DriverSQLite, DSN: filepath.Join(t.TempDir(), "otto.db")},
+			false,
+		},
+		{"unknown driver", config.DatabaseConfig{Driver: "oracle", DSN: "x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, err := NewDatabaseFromConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewDatabaseFromConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if database == nil {
+				return
+			}
+			defer database.Close()
+			if err := database.DB().Ping(); err != nil {
+				t.Errorf("Ping failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestDatabaseReconnects(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	database, err := NewDatabase(filepath.Join(dir, "otto.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	ctx := context.Background()
+	db := database.DB()
+
+	// Make every ping open a new connection, as after the old one broke, and
+	// make the file impossible to reopen
+	db.SetMaxIdleConns(0)
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	database.checkConnection(ctx)
+	if database.Healthy() {
+		t.Errorf("expected database to be unhealthy after a failed reconnect")
+	}
+
+	// Recover once the file can be opened again
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	database.checkConnection(ctx)
+	if !database.Healthy() {
+		t.Errorf("expected database to be healthy after reconnecting")
+	}
+	if database.DB() != db {
+		t.Errorf("expected callers' handle to be kept")
+	}
+	if err := db.Ping(); err != nil {
+		t.Errorf("Ping after reconnect failed: %v", err)
+	}
+}
+
+func TestKeepAliveSkipsInMemoryDatabase(t *testing.T) {
+	database := TestDatabase(t)
+	done := make(chan struct{})
+	go func() {
+		database.KeepAlive(context.Background(), time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("KeepAlive should return immediately for in-memory databases")
+	}
+}
+
+func TestDatabaseStats(t *testing.T) {
+	database, err := NewDatabase(filepath.Join(t.TempDir(), "otto.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	conn, err := database.DB().Conn(t.Context())
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	if got := database.Stats().InUse; got != 1 {
+		t.Errorf("InUse with a held connection: got %d want 1", got)
+	}
+	conn.Close()
+	stats := database.Stats()
+	if stats.InUse != 0 || stats.Idle != 1 {
+		t.Errorf("after release: got %d in use and %d idle, want 0 and 1", stats.InUse, stats.Idle)
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// github.go provides helpers for the GitHub API client.
+
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+)
+
+// timeoutTransport applies a per-call timeout to every GitHub API request.
+// A shorter deadline already set on the request context still wins.
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Keep the context alive until the caller is done reading the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a request context once the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context.
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// withGitHubTimeout returns a copy of client whose requests are bounded by timeout.
+func withGitHubTimeout(client *http.Client, timeout time.Duration) *http.Client {
+	if client == nil {
+		client = &http.Client{}
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	wrapped := *client
+	wrapped.Transport = &timeoutTransport{base: base, timeout: timeout}
+	return &wrapped
+}
+
+// Paginate calls list for each page of a GitHub API list, following the
+// response's NextPage until the last page, and returns the items of every
+// page. Pages hold perPage items, capped at config.MaxGitHubPerPage; zero
+// means the maximum.
+func Paginate[T any](perPage int, list func(opts github.ListOptions) ([]T, *github.Response, error)) ([]T, error) {
+	if perPage <= 0 || perPage > config.MaxGitHubPerPage {
+		perPage = config.MaxGitHubPerPage
+	}
+	opts := github.ListOptions{PerPage: perPage}
+	var all []T
+	for {
+		items, resp, err := list(opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if resp == nil || resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/secrets"
+)
+
+// slowTransport blocks every request until its context is done.
+type slowTransport struct{}
+
+func (slowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-time.After(5 * time.Second):
+		return nil, errors.New("slow transport was not canceled")
+	}
+}
+
+func TestGitHubTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		callerDeadline time.Duration
+		maxElapsed     time.Duration
+	}{
+		{
+			name:       "per-call timeout applies",
+			timeout:    50 * time.Millisecond,
+			maxElapsed: time.Second,
+		},
+		{
+			name:           "shorter caller deadline wins",
+			timeout:        time.Minute,
+			callerDeadline: 50 * time.Millisecond,
+			maxElapsed:     time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := withGitHubTimeout(&http.Client{Transport: slowTransport{}}, tt.timeout)
+			client := github.NewClient(httpClient)
+
+			ctx := t.Context()
+			if tt.callerDeadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.callerDeadline)
+				defer cancel()
+			}
+
+			start := time.Now()
+			_, _, err := client.Issues.Get(ctx, "owner", "repo", 1)
+			elapsed := time.Since(start)
+
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected deadline exceeded error, got %v", err)
+			}
+			if elapsed > tt.maxElapsed {
+				t.Errorf("call took %v, want less than %v", elapsed, tt.maxElapsed)
+			}
+		})
+	}
+}
+
+func TestInitializeGitHubClientValidatesKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		wantErr string
+	}{
+		{
+			name: "RSA key",
+			key:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+		},
+		{
+			name:    "garbage key",
+			key:     []byte("not a key"),
+			wantErr: "invalid GitHub App private key: no PEM block found",
+		},
+		{
+			name:    "EC key",
+			key:     pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecDER}),
+			wantErr: "invalid GitHub App private key: want an RSA key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{
+				Config:  &config.AppConfig{},
+				Secrets: secrets.NewFileManager("webhook-secret", 1, 2, "", tt.key),
+			}
+
+			err := app.initializeGitHubClient(t.Context())
+			if tt.wantErr == "" {
+				if err != nil || app.GitHubClient == nil {
+					t.Errorf("expected a client, got error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// pagedTransport serves a list of total users, page by page, recording the
+// per_page of each request.
+type pagedTransport struct {
+	total    int
+	perPages []string
+}
+
+func (p *pagedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	q := req.URL.Query()
+	p.perPages = append(p.perPages, q.Get("per_page"))
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	page, _ := strconv.Atoi(q.Get("page"))
+	page = max(page, 1)
+
+	var users []string
+	for i := (page-1)*perPage + 1; i <= min(page*perPage, p.total); i++ {
+		users = append(users, fmt.Sprintf(`{"login":"user%d"}`, i))
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if page*perPage < p.total {
+		next := *req.URL
+		q.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = q.Encode()
+		header.Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("[" + strings.Join(users, ",") + "]")),
+		Request:    req,
+	}, nil
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name         string
+		perPage      int
+		total        int
+		wantPerPages []string
+	}{
+		{"single page", 0, 30, []string{"100"}},
+		{"several pages", 10, 25, []string{"10", "10", "10"}},
+		{"page size is capped", 500, 150, []string{"100", "100"}},
+		{"empty list", 10, 0, []string{"10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &pagedTransport{total: tt.total}
+			client := github.NewClient(&http.Client{Transport: transport})
+
+			users, err := Paginate(tt.perPage, func(opts github.ListOptions) ([]*github.User, *github.Response, error) {
+				return client.Teams.ListTeamMembersBySlug(t.Context(), "org", "team",
+					&github.TeamListTeamMembersOptions{ListOptions: opts})
+			})
+			if err != nil {
+				t.Fatalf("Paginate failed: %v", err)
+			}
+			if len(users) != tt.total {
+				t.Errorf("got %d users, want %d", len(users), tt.total)
+			}
+			for i, user := range users {
+				if want := fmt.Sprintf("user%d", i+1); user.GetLogin() != want {
+					t.Errorf("user %d: got %q, want %q", i, user.GetLogin(), want)
+				}
+			}
+			if !slices.Equal(transport.perPages, tt.wantPerPages) {
+				t.Errorf("per_page of requests: got %v, want %v", transport.perPages, tt.wantPerPages)
+			}
+		})
+	}
+}
+
+func TestPaginateStopsAtError(t *testing.T) {
+	calls := 0
+	_, err := Paginate(10, func(opts github.ListOptions) ([]int, *github.Response, error) {
+		calls++
+		if opts.Page == 2 {
+			return nil, nil, errors.New("rate limited")
+		}
+		return []int{1}, &github.Response{NextPage: 2}, nil
+	})
+	if err == nil || calls != 2 {
+		t.Errorf("got %v after %d calls, want an error after 2", err, calls)
+	}
+}
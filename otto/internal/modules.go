@@ -8,7 +8,13 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
 )
 
 // CommandContext represents a slash command invocation.
@@ -23,10 +29,80 @@ type CommandContext struct {
 	App      *App   // reference to the app instance
 }
 
-// Module is the Otto feature/module interface.
+// Module is the Otto feature/module interface. HandleEvent reports whether
+// the module acted on the event, such as running a command; events no module
+// acts on are counted as unhandled.
 type Module interface {
 	Name() string
-	HandleEvent(eventType string, event any, raw json.RawMessage) error
+	HandleEvent(eventType string, event any, raw json.RawMessage) (handled bool, err error)
+}
+
+// EventEnvelope is a webhook event together with its delivery metadata.
+type EventEnvelope struct {
+	// Type is the GitHub event type, such as "issue_comment".
+	Type string
+	// Event is the parsed event, such as *github.IssueCommentEvent.
+	Event any
+	// Raw is the webhook payload.
+	Raw json.RawMessage
+	// DeliveryID is GitHub's unique ID of the delivery, from the
+	// X-GitHub-Delivery header. Redeliveries keep the ID, so it can make
+	// handling idempotent. Empty for events that weren't delivered by GitHub.
+	DeliveryID string
+	// InstallationTargetID is the ID of the account or repository the webhook
+	// is installed on, from X-GitHub-Hook-Installation-Target-ID, 0 if unknown.
+	InstallationTargetID int64
+	// ReceivedAt is when the event was received.
+	ReceivedAt time.Time
+}
+
+// ModuleEnvelopeHandler is an optional interface that modules can implement
+// to receive events with their delivery metadata. The dispatcher calls
+// HandleEnvelope instead of HandleEvent on modules that implement it.
+type ModuleEnvelopeHandler interface {
+	HandleEnvelope(env *EventEnvelope) (handled bool, err error)
+}
+
+// handleEnvelope hands an event to a module, with its metadata if the module
+// accepts it.
+func handleEnvelope(m Module, env *EventEnvelope) (bool, error) {
+	if h, ok := m.(ModuleEnvelopeHandler); ok {
+		return h.HandleEnvelope(env)
+	}
+	return m.HandleEvent(env.Type, env.Event, env.Raw)
+}
+
+// ModuleRouter is an optional interface that modules can implement to serve
+// HTTP endpoints on the app's server, registered once the module has
+// initialized. Only paths under /api/ are served, and they require the admin
+// token; modules should use paths under /api/<module name>/.
+type ModuleRouter interface {
+	RegisterRoutes(mux *http.ServeMux)
+}
+
+// ModuleEventFilter is an optional interface that modules can implement to
+// receive only the GitHub event types they handle. Modules that don't
+// implement it, or return no types, receive every event.
+type ModuleEventFilter interface {
+	InterestedEvents() []string
+}
+
+// wantsEvent reports whether a module should be dispatched an event type.
+func wantsEvent(m Module, eventType string) bool {
+	filter, ok := m.(ModuleEventFilter)
+	if !ok {
+		return true
+	}
+	events := filter.InterestedEvents()
+	return len(events) == 0 || slices.Contains(events, eventType)
+}
+
+// ModuleCommander is an optional interface that modules can implement to
+// claim the slash command verbs they handle, such as "ack" for /ack. Every
+// module sees every comment, so two modules handling the same verb would both
+// act on it; the registry refuses a module claiming a verb already claimed.
+type ModuleCommander interface {
+	Commands() []string
 }
 
 // ModuleInitializer is an optional interface that modules can implement
@@ -35,37 +111,113 @@ type ModuleInitializer interface {
 	Initialize(ctx context.Context, app *App) error
 }
 
+// ModuleValidator is an optional interface that modules can implement to
+// reject invalid configuration before the application starts.
+type ModuleValidator interface {
+	ValidateConfig(cfg *config.AppConfig) error
+}
+
 // ModuleShutdowner is an optional interface that modules can implement
 // for graceful shutdown.
 type ModuleShutdowner interface {
 	Shutdown(ctx context.Context) error
 }
 
+// ModuleInfo describes a registered module for dashboards and the admin API.
+type ModuleInfo struct {
+	Name string `json:"name"`
+	// Enabled is set once the module has initialized and is handling events.
+	Enabled bool `json:"enabled"`
+}
+
 // ModuleRegistry manages the registration and retrieval of modules.
 type ModuleRegistry struct {
 	modulesMu sync.RWMutex
 	modules   map[string]Module
+	enabled   map[string]bool
+	commands  map[string]string // lower-cased command verb to module name
+	// telemetry counts rejected registrations; nil disables the metric.
+	telemetry *TelemetryManager
 }
 
 // NewModuleRegistry creates a new module registry.
 func NewModuleRegistry() *ModuleRegistry {
+	return NewModuleRegistryWithTelemetry(nil)
+}
+
+// NewModuleRegistryWithTelemetry creates a new module registry that counts
+// rejected registrations as module errors of type "duplicate_registration"
+// or "duplicate_command". A nil telemetry records no metrics.
+func NewModuleRegistryWithTelemetry(telemetry *TelemetryManager) *ModuleRegistry {
 	return &ModuleRegistry{
-		modules: make(map[string]Module),
+		modules:   make(map[string]Module),
+		enabled:   make(map[string]bool),
+		commands:  make(map[string]string),
+		telemetry: telemetry,
 	}
 }
 
-// RegisterModule adds a module to the registry.
+// RegisterModule adds a module to the registry. A module with the name of a
+// registered module, or claiming a command verb another module claimed, is
+// logged and not registered.
 func (r *ModuleRegistry) RegisterModule(m Module) {
 	r.modulesMu.Lock()
 	defer r.modulesMu.Unlock()
 	if _, exists := r.modules[m.Name()]; exists {
 		slog.Error("module registered twice", "name", m.Name())
+		r.incError(m.Name(), "duplicate_registration")
 		return
 	}
+	var commands []string
+	if c, ok := m.(ModuleCommander); ok {
+		commands = c.Commands()
+	}
+	for _, command := range commands {
+		if owner, claimed := r.commands[strings.ToLower(command)]; claimed {
+			slog.Error("module command registered twice",
+				"command", command,
+				"name", m.Name(),
+				"registered_by", owner)
+			r.incError(m.Name(), "duplicate_command")
+			return
+		}
+	}
+	for _, command := range commands {
+		r.commands[strings.ToLower(command)] = m.Name()
+	}
 	r.modules[m.Name()] = m
 	slog.Info("module registered", "name", m.Name())
 }
 
+// incError counts a rejected registration of the named module.
+func (r *ModuleRegistry) incError(name, errType string) {
+	if r.telemetry != nil {
+		r.telemetry.IncModuleError(context.Background(), name, errType)
+	}
+}
+
+// SetEnabled records whether a registered module is enabled.
+func (r *ModuleRegistry) SetEnabled(name string, enabled bool) {
+	r.modulesMu.Lock()
+	defer r.modulesMu.Unlock()
+	if _, ok := r.modules[name]; ok {
+		r.enabled[name] = enabled
+	}
+}
+
+// ModuleInfo lists the registered modules sorted by name.
+func (r *ModuleRegistry) ModuleInfo() []ModuleInfo {
+	r.modulesMu.RLock()
+	defer r.modulesMu.RUnlock()
+
+	infos := make([]ModuleInfo, 0, len(r.modules))
+	for name := range r.modules {
+		infos = append(infos, ModuleInfo{Name: name, Enabled: r.enabled[name]})
+	}
+	slices.SortFunc(infos, func(a, b ModuleInfo) int { return strings.Compare(a.Name, b.Name) })
+	return infos
+}
+
 // GetModules returns a copy of the registered modules map.
 func (r *ModuleRegistry) GetModules() map[string]Module {
 	r.modulesMu.RLock()
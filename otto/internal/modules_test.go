@@ -3,10 +3,20 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/secrets"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type mockModule struct {
@@ -16,12 +26,12 @@ type mockModule struct {
 }
 
 func (m *mockModule) Name() string { return m.name }
-func (m *mockModule) HandleEvent(eventType string, event any, raw json.RawMessage) error {
+func (m *mockModule) HandleEvent(eventType string, event any, raw json.RawMessage) (bool, error) {
 	atomic.AddInt32(&m.handled, 1)
 	if m.eventWG != nil {
 		m.eventWG.Done()
 	}
-	return nil
+	return true, nil
 }
 
 func TestRegisterModuleAndDispatch(t *testing.T) {
@@ -47,3 +57,340 @@ func TestRegisterModuleAndDispatch(t *testing.T) {
 		t.Fatalf("module did not handle the event")
 	}
 }
+
+type reportingModule struct {
+	mockModule
+	handles bool
+}
+
+func (m *reportingModule) HandleEvent(eventType string, event any, raw json.RawMessage) (bool, error) {
+	_, _ = m.mockModule.HandleEvent(eventType, event, raw)
+	return m.handles, nil
+}
+
+func TestDispatchEventRecordsUnhandled(t *testing.T) {
+	tests := []struct {
+		name    string
+		modules []Module
+		want    int64
+	}{
+		{
+			name:    "no module handles the event",
+			modules: []Module{&reportingModule{mockModule: mockModule{name: "a"}}},
+			want:    1,
+		},
+		{
+			name: "one module handles the event",
+			modules: []Module{
+				&reportingModule{mockModule: mockModule{name: "a"}},
+				&reportingModule{mockModule: mockModule{name: "b"}, handles: true},
+			},
+			want: 0,
+		},
+		{
+			name: "no module registered",
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, reader := newTestTelemetry(t)
+			app := &App{
+				ModuleRegistry: NewModuleRegistry(),
+				Telemetry:      tm,
+			}
+			for _, m := range tt.modules {
+				app.RegisterModule(m)
+			}
+
+			app.DispatchEvent("push", struct{}{}, nil)
+
+			// Dispatch is asynchronous; poll until the expected count settles.
+			attr := attribute.String("event_type", "push")
+			deadline := time.Now().Add(time.Second)
+			got := counterValue(t, reader, "otto.server.unhandled_events_total", attr)
+			for got != tt.want && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+				got = counterValue(t, reader, "otto.server.unhandled_events_total", attr)
+			}
+			if tt.want == 0 {
+				// Give the dispatcher a moment to (incorrectly) record the event.
+				time.Sleep(20 * time.Millisecond)
+				got = counterValue(t, reader, "otto.server.unhandled_events_total", attr)
+			}
+			if got != tt.want {
+				t.Errorf("unhandled events = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// filteringModule only receives the events it declares.
+type filteringModule struct {
+	mockModule
+	events []string
+}
+
+func (m *filteringModule) InterestedEvents() []string { return m.events }
+
+func TestDispatchEventHonorsInterestedEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    []string
+		eventType string
+		want      int32
+	}{
+		{"declared event", []string{"issues", "issue_comment"}, "issue_comment", 1},
+		{"undeclared event", []string{"issues", "issue_comment"}, "push", 0},
+		{"no declared events receives everything", nil, "push", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := &filteringModule{mockModule: mockModule{name: "filtered"}, events: tt.events}
+			app := &App{ModuleRegistry: NewModuleRegistry()}
+			app.RegisterModule(mod)
+
+			app.DispatchEvent(tt.eventType, struct{}{}, nil)
+			app.dispatches.Wait()
+
+			if got := atomic.LoadInt32(&mod.handled); got != tt.want {
+				t.Errorf("module handled %d events, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// commandModule claims the command verbs it declares.
+type commandModule struct {
+	mockModule
+	commands []string
+}
+
+func (m *commandModule) Commands() []string { return m.commands }
+
+func TestRegisterModuleRejectsDuplicateCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  []string
+		second []string
+		want   []string
+	}{
+		{"distinct commands", []string{"ack", "resolve"}, []string{"triage"}, []string{"first", "second"}},
+		{"same command", []string{"ack", "resolve"}, []string{"label", "ack"}, []string{"first"}},
+		{"same command in another case", []string{"ack"}, []string{"ACK"}, []string{"first"}},
+		{"no commands", []string{"ack"}, nil, []string{"first", "second"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewModuleRegistry()
+			registry.RegisterModule(&commandModule{mockModule: mockModule{name: "first"}, commands: tt.first})
+			registry.RegisterModule(&commandModule{mockModule: mockModule{name: "second"}, commands: tt.second})
+
+			var got []string
+			for _, info := range registry.ModuleInfo() {
+				got = append(got, info.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("registered modules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRejectedRegistrationsAreCounted(t *testing.T) {
+	tm, reader := newTestTelemetry(t)
+	registry := NewModuleRegistryWithTelemetry(tm)
+	registry.RegisterModule(&commandModule{mockModule: mockModule{name: "first"}, commands: []string{"ack"}})
+	registry.RegisterModule(&mockModule{name: "first"})
+	registry.RegisterModule(&mockModule{name: "first"})
+	registry.RegisterModule(&commandModule{mockModule: mockModule{name: "second"}, commands: []string{"ack"}})
+
+	tests := []struct {
+		module  string
+		errType string
+		want    int64
+	}{
+		{"first", "duplicate_registration", 2},
+		{"second", "duplicate_command", 1},
+		{"second", "duplicate_registration", 0},
+	}
+	for _, tt := range tests {
+		got := counterValue(t, reader, "otto.module.errors_total",
+			attribute.String("module", tt.module), attribute.String("err_type", tt.errType))
+		if got != tt.want {
+			t.Errorf("%s %s errors = %d, want %d", tt.module, tt.errType, got, tt.want)
+		}
+	}
+
+	// Registries without telemetry still reject duplicates
+	registry = NewModuleRegistry()
+	registry.RegisterModule(&mockModule{name: "first"})
+	registry.RegisterModule(&mockModule{name: "first"})
+	if got := len(registry.GetModules()); got != 1 {
+		t.Errorf("registered modules = %d, want 1", got)
+	}
+}
+
+// validatingModule rejects configuration with err.
+type validatingModule struct {
+	mockModule
+	err error
+}
+
+func (m *validatingModule) ValidateConfig(cfg *config.AppConfig) error { return m.err }
+
+func TestValidateModules(t *testing.T) {
+	tests := []struct {
+		name     string
+		modules  []Module
+		wantErrs []string
+	}{
+		{
+			name: "all valid",
+			modules: []Module{
+				&validatingModule{mockModule: mockModule{name: "a"}},
+				&mockModule{name: "b"},
+			},
+		},
+		{
+			name: "errors from every module are reported",
+			modules: []Module{
+				&validatingModule{mockModule: mockModule{name: "a"}, err: errors.New("bad threshold")},
+				&validatingModule{mockModule: mockModule{name: "b"}},
+				&validatingModule{mockModule: mockModule{name: "c"}, err: errors.New("bad pattern")},
+			},
+			wantErrs: []string{"module a: bad threshold", "module c: bad pattern"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{ModuleRegistry: NewModuleRegistry()}
+			for _, m := range tt.modules {
+				app.RegisterModule(m)
+			}
+
+			err := app.validateModules()
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("want error containing %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStartRefusesInvalidModuleConfig(t *testing.T) {
+	app := &App{ModuleRegistry: NewModuleRegistry(), Logger: slog.Default()}
+	app.RegisterModule(&validatingModule{mockModule: mockModule{name: "a"}, err: errors.New("bad config")})
+
+	if err := app.Start(context.Background()); err == nil {
+		t.Fatal("expected Start to fail")
+	}
+}
+
+// slowModule takes delay to handle each event.
+type slowModule struct {
+	delay    time.Duration
+	finished atomic.Bool
+}
+
+func (m *slowModule) Name() string { return "slow" }
+func (m *slowModule) HandleEvent(eventType string, event any, raw json.RawMessage) (bool, error) {
+	time.Sleep(m.delay)
+	m.finished.Store(true)
+	return true, nil
+}
+
+func TestShutdownDrainsDispatchedEvents(t *testing.T) {
+	tests := []struct {
+		name         string
+		delay        time.Duration
+		timeout      time.Duration
+		wantFinished bool
+	}{
+		{"handler finishes before shutdown returns", 50 * time.Millisecond, time.Second, true},
+		{"shutdown gives up after its timeout", time.Second, 50 * time.Millisecond, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := &slowModule{delay: tt.delay}
+			app := &App{
+				ModuleRegistry: NewModuleRegistry(),
+				Logger:         slog.Default(),
+			}
+			app.RegisterModule(mod)
+
+			app.DispatchEvent("push", struct{}{}, nil)
+
+			ctx, cancel := context.WithTimeout(t.Context(), tt.timeout)
+			defer cancel()
+			start := time.Now()
+			if err := app.Shutdown(ctx); err != nil {
+				t.Fatalf("Shutdown failed: %v", err)
+			}
+
+			if got := mod.finished.Load(); got != tt.wantFinished {
+				t.Errorf("handler finished at shutdown: want %v, got %v", tt.wantFinished, got)
+			}
+			if elapsed := time.Since(start); elapsed > tt.timeout+500*time.Millisecond {
+				t.Errorf("Shutdown took %v, longer than its %v timeout", elapsed, tt.timeout)
+			}
+		})
+	}
+}
+
+func TestDispatchAfterShutdownIsDropped(t *testing.T) {
+	mod := &mockModule{name: "a"}
+	app := &App{ModuleRegistry: NewModuleRegistry(), Logger: slog.Default()}
+	app.RegisterModule(mod)
+
+	if err := app.Shutdown(t.Context()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if app.DispatchEventAndWait("push", struct{}{}, nil) {
+		t.Error("event dispatched after shutdown was handled")
+	}
+	if got := atomic.LoadInt32(&mod.handled); got != 0 {
+		t.Errorf("handler ran %d times after shutdown", got)
+	}
+}
+
+// closingSecrets is a secrets manager that records being closed.
+type closingSecrets struct {
+	secrets.EnvManager
+	closed bool
+}
+
+func (c *closingSecrets) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestShutdownClosesSecrets(t *testing.T) {
+	manager := &closingSecrets{}
+	app := &App{
+		Secrets:        manager,
+		ModuleRegistry: NewModuleRegistry(),
+		Logger:         slog.Default(),
+	}
+	if err := app.Shutdown(t.Context()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if !manager.closed {
+		t.Error("Shutdown did not close the secrets manager")
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// Outcomes of a received webhook, as listed by /debug/events.
+const (
+	// EventOutcomeHandled marks an event a module acted on.
+	EventOutcomeHandled = "handled"
+	// EventOutcomeUnhandled marks an event no module acted on.
+	EventOutcomeUnhandled = "unhandled"
+	// EventOutcomeFiltered marks an event of a repository outside the allowlist.
+	EventOutcomeFiltered = "filtered"
+	// EventOutcomeStale marks an event rejected for being older than max_event_age.
+	EventOutcomeStale = "stale"
+	// EventOutcomeInvalidSignature marks a webhook that failed verification.
+	EventOutcomeInvalidSignature = "invalid_signature"
+	// EventOutcomeParseError marks a webhook whose payload couldn't be parsed.
+	EventOutcomeParseError = "parse_error"
+)
+
+// RecentEvent describes a received webhook and what became of it.
+type RecentEvent struct {
+	Type       string    `json:"type"`
+	DeliveryID string    `json:"delivery_id"`
+	Repo       string    `json:"repo,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+	Outcome    string    `json:"outcome"`
+	// Count is how many webhooks in a row with an invalid signature the
+	// event stands for, when more than one.
+	Count int `json:"count,omitempty"`
+}
+
+// eventRing keeps the most recent events in a fixed-size ring buffer. Its
+// methods are safe for concurrent use, and a nil ring keeps nothing.
+type eventRing struct {
+	mu     sync.Mutex
+	events []RecentEvent
+	// next is the index the next event is written to.
+	next int
+	full bool
+}
+
+// newEventRing returns a ring keeping the last size events, or nil when size
+// isn't positive.
+func newEventRing(size int) *eventRing {
+	if size <= 0 {
+		return nil
+	}
+	return &eventRing{events: make([]RecentEvent, size)}
+}
+
+// add records an event, overwriting the oldest one once the ring is full.
+// Webhooks with an invalid signature in a row share one event, so that
+// unauthenticated requests can't push out the real ones.
+func (r *eventRing) add(e RecentEvent) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e.Outcome == EventOutcomeInvalidSignature && (r.next > 0 || r.full) {
+		last := &r.events[(r.next-1+len(r.events))%len(r.events)]
+		if last.Outcome == EventOutcomeInvalidSignature {
+			last.ReceivedAt = e.ReceivedAt
+			last.Count = max(last.Count, 1) + 1
+			return
+		}
+	}
+	r.events[r.next] = e
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the recorded events, newest first.
+func (r *eventRing) list() []RecentEvent {
+	if r == nil {
+		return []RecentEvent{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.next
+	if r.full {
+		n = len(r.events)
+	}
+	events := make([]RecentEvent, 0, n)
+	for i := 1; i <= n; i++ {
+		events = append(events, r.events[(r.next-i+len(r.events))%len(r.events)])
+	}
+	return events
+}
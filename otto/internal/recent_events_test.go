@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestEventRing(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		added int
+		want  []string
+	}{
+		{"empty", 3, 0, []string{}},
+		{"partly filled", 3, 2, []string{"d2", "d1"}},
+		{"full", 3, 3, []string{"d3", "d2", "d1"}},
+		{"wrapped around", 3, 5, []string{"d5", "d4", "d3"}},
+		{"wrapped around twice", 3, 7, []string{"d7", "d6", "d5"}},
+		{"disabled", 0, 2, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring := newEventRing(tt.size)
+			for i := 1; i <= tt.added; i++ {
+				ring.add(RecentEvent{DeliveryID: "d" + strconv.Itoa(i)})
+			}
+			got := []string{}
+			for _, e := range ring.list() {
+				got = append(got, e.DeliveryID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("list: got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventRingMergesInvalidSignatures(t *testing.T) {
+	ring := newEventRing(3)
+	ring.add(RecentEvent{DeliveryID: "d1", Outcome: EventOutcomeHandled})
+	for range 5 {
+		ring.add(RecentEvent{Outcome: EventOutcomeInvalidSignature})
+	}
+	ring.add(RecentEvent{DeliveryID: "d2", Outcome: EventOutcomeHandled})
+	ring.add(RecentEvent{Outcome: EventOutcomeInvalidSignature})
+
+	got := ring.list()
+	want := []RecentEvent{
+		{Outcome: EventOutcomeInvalidSignature},
+		{DeliveryID: "d2", Outcome: EventOutcomeHandled},
+		{Outcome: EventOutcomeInvalidSignature, Count: 5},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("list: got %+v want %+v", got, want)
+	}
+}
+
+func TestEventRingConcurrentAdds(t *testing.T) {
+	ring := newEventRing(10)
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ring.add(RecentEvent{DeliveryID: strconv.Itoa(i)})
+			_ = ring.list()
+		}()
+	}
+	wg.Wait()
+	if got := len(ring.list()); got != 10 {
+		t.Errorf("kept %d events, want 10", got)
+	}
+}
@@ -14,18 +14,20 @@ import (
 
 // FileConfig represents the secrets configuration in a YAML file.
 type FileConfig struct {
-	WebhookSecret        string `yaml:"webhook_secret"`
-	GitHubAppID          int64  `yaml:"github_app_id"`
-	GitHubInstallationID int64  `yaml:"github_installation_id"`
-	GitHubPrivateKeyPath string `yaml:"github_private_key_path"`
+	WebhookSecret         string `yaml:"webhook_secret"`
+	PreviousWebhookSecret string `yaml:"previous_webhook_secret"`
+	GitHubAppID           int64  `yaml:"github_app_id"`
+	GitHubInstallationID  int64  `yaml:"github_installation_id"`
+	GitHubPrivateKeyPath  string `yaml:"github_private_key_path"`
 }
 
 // OnePasswordConfig represents the 1Password secrets configuration in a YAML file.
 type OnePasswordConfig struct {
-	WebhookSecretRef string `yaml:"webhook_secret_ref"`
-	AppIDRef         string `yaml:"github_app_id_ref"`
-	InstallIDRef     string `yaml:"github_installation_id_ref"`
-	PrivateKeyRef    string `yaml:"github_private_key_ref"`
+	WebhookSecretRef         string `yaml:"webhook_secret_ref"`
+	PreviousWebhookSecretRef string `yaml:"previous_webhook_secret_ref"`
+	AppIDRef                 string `yaml:"github_app_id_ref"`
+	InstallIDRef             string `yaml:"github_installation_id_ref"`
+	PrivateKeyRef            string `yaml:"github_private_key_ref"`
 }
 
 // Manager implementations provide access to sensitive configuration.
@@ -58,7 +60,11 @@ func LoadSecrets(path string) (Manager, error) {
 			}
 
 			// Create an environment manager
-			return &EnvManager{}, nil
+			envManager, err := LoadFromEnv()
+			if err != nil {
+				return nil, err
+			}
+			return envManager, nil
 		}
 		return nil, err
 	}
@@ -88,6 +94,7 @@ func loadFileConfig(path string) (*FileManager, error) {
 		config.GitHubPrivateKeyPath,
 		nil, // Private key will be loaded below
 	)
+	manager.PreviousWebhookSecret = config.PreviousWebhookSecret
 
 	// Load private key from file if path is specified
 	if config.GitHubPrivateKeyPath != "" {
@@ -131,6 +138,7 @@ func loadOnePasswordConfig(path string) (*OnePasswordManager, error) {
 	if err != nil {
 		return nil, err
 	}
+	manager.previousWebhookSecretRef = config.PreviousWebhookSecretRef
 
 	slog.Info("1Password secrets configured successfully")
 	return manager, nil
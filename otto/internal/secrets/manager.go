@@ -4,7 +4,11 @@
 package secrets
 
 import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"strconv"
@@ -15,6 +19,10 @@ type Manager interface {
 	// GetWebhookSecret returns the GitHub webhook secret.
 	GetWebhookSecret() string
 
+	// GetPreviousWebhookSecret returns the webhook secret being rotated out,
+	// which is still accepted, or "" outside a rotation.
+	GetPreviousWebhookSecret() string
+
 	// GetGitHubAppID returns the GitHub App ID.
 	GetGitHubAppID() int64
 
@@ -27,16 +35,20 @@ type Manager interface {
 
 // EnvManager implements the Manager interface using environment variables.
 type EnvManager struct {
-	webhookSecret  string
-	gitHubAppID    int64
-	installationID int64
-	privateKey     []byte
+	webhookSecret         string
+	previousWebhookSecret string
+	gitHubAppID           int64
+	installationID        int64
+	privateKey            []byte
+	// privateKeyErr is why OTTO_GITHUB_PRIVATE_KEY_PATH couldn't be read.
+	privateKeyErr error
 }
 
 // NewEnvManager creates a new EnvManager that reads from environment variables once.
 func NewEnvManager() *EnvManager {
 	e := &EnvManager{
-		webhookSecret: os.Getenv("OTTO_WEBHOOK_SECRET"),
+		webhookSecret:         os.Getenv("OTTO_WEBHOOK_SECRET"),
+		previousWebhookSecret: os.Getenv("OTTO_PREVIOUS_WEBHOOK_SECRET"),
 	}
 
 	if appIDStr := os.Getenv("OTTO_GITHUB_APP_ID"); appIDStr != "" {
@@ -53,9 +65,7 @@ func NewEnvManager() *EnvManager {
 		}
 	}
 
-	if privateKey := os.Getenv("OTTO_GITHUB_PRIVATE_KEY"); privateKey != "" {
-		e.privateKey = []byte(privateKey)
-	}
+	e.privateKey, e.privateKeyErr = envPrivateKey()
 
 	return e
 }
@@ -65,6 +75,11 @@ func (e *EnvManager) GetWebhookSecret() string {
 	return e.webhookSecret
 }
 
+// GetPreviousWebhookSecret returns the previous GitHub webhook secret from environment variable.
+func (e *EnvManager) GetPreviousWebhookSecret() string {
+	return e.previousWebhookSecret
+}
+
 // GetGitHubAppID returns the GitHub App ID from environment variable.
 func (e *EnvManager) GetGitHubAppID() int64 {
 	return e.gitHubAppID
@@ -82,21 +97,25 @@ func (e *EnvManager) GetGitHubPrivateKey() []byte {
 
 // FileManager implements the Manager interface using a local file.
 type FileManager struct {
-	WebhookSecret        string
-	GitHubAppID          int64
-	GitHubInstallationID int64
-	GitHubPrivateKeyPath string
-	privateKey           []byte
+	WebhookSecret         string
+	PreviousWebhookSecret string
+	GitHubAppID           int64
+	GitHubInstallationID  int64
+	GitHubPrivateKeyPath  string
+	privateKey            []byte
 
 	// Environment values take precedence and are cached during initialization
-	envWebhookSecret  string
-	envGitHubAppID    int64
-	envInstallationID int64
-	envPrivateKey     []byte
-	hasEnvWebhook     bool
-	hasEnvAppID       bool
-	hasEnvInstallID   bool
-	hasEnvPrivateKey  bool
+	envWebhookSecret         string
+	envPreviousWebhookSecret string
+	envGitHubAppID           int64
+	envInstallationID        int64
+	envPrivateKey            []byte
+	envPrivateKeyErr         error
+	hasEnvWebhook            bool
+	hasEnvPreviousWebhook    bool
+	hasEnvAppID              bool
+	hasEnvInstallID          bool
+	hasEnvPrivateKey         bool
 }
 
 // NewFileManager creates a new FileManager with the given values.
@@ -119,6 +138,10 @@ func NewFileManager(
 		fm.envWebhookSecret = envVal
 		fm.hasEnvWebhook = true
 	}
+	if envVal := os.Getenv("OTTO_PREVIOUS_WEBHOOK_SECRET"); envVal != "" {
+		fm.envPreviousWebhookSecret = envVal
+		fm.hasEnvPreviousWebhook = true
+	}
 
 	if envVal := os.Getenv("OTTO_GITHUB_APP_ID"); envVal != "" {
 		id, err := strconv.ParseInt(envVal, 10, 64)
@@ -136,10 +159,8 @@ func NewFileManager(
 		}
 	}
 
-	if envVal := os.Getenv("OTTO_GITHUB_PRIVATE_KEY"); envVal != "" {
-		fm.envPrivateKey = []byte(envVal)
-		fm.hasEnvPrivateKey = true
-	}
+	fm.envPrivateKey, fm.envPrivateKeyErr = envPrivateKey()
+	fm.hasEnvPrivateKey = len(fm.envPrivateKey) > 0
 
 	return fm
 }
@@ -152,6 +173,14 @@ func (f *FileManager) GetWebhookSecret() string {
 	return f.WebhookSecret
 }
 
+// GetPreviousWebhookSecret returns the previous GitHub webhook secret, with environment variable fallback.
+func (f *FileManager) GetPreviousWebhookSecret() string {
+	if f.hasEnvPreviousWebhook {
+		return f.envPreviousWebhookSecret
+	}
+	return f.PreviousWebhookSecret
+}
+
 // GetGitHubAppID returns the GitHub App ID, with environment variable fallback.
 func (f *FileManager) GetGitHubAppID() int64 {
 	if f.hasEnvAppID {
@@ -178,6 +207,10 @@ func (f *FileManager) GetGitHubPrivateKey() []byte {
 
 // ValidateFileManager checks that all required fields are present and valid.
 func ValidateFileManager(secrets *FileManager) error {
+	if secrets.envPrivateKeyErr != nil {
+		return secrets.envPrivateKeyErr
+	}
+
 	// Skip validation if we have webhook secret from environment
 	if secrets.hasEnvWebhook {
 		return nil
@@ -226,6 +259,20 @@ func (c *Chain) GetWebhookSecret() string {
 	return ""
 }
 
+// GetPreviousWebhookSecret returns the previous GitHub webhook secret from the first manager that returns a
+// non-empty value.
+func (c *Chain) GetPreviousWebhookSecret() string {
+	for _, m := range c.managers {
+		if m == nil {
+			continue
+		}
+		if v := m.GetPreviousWebhookSecret(); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // GetGitHubAppID returns the GitHub App ID from the first manager that returns a non-zero value.
 func (c *Chain) GetGitHubAppID() int64 {
 	for _, m := range c.managers {
@@ -265,6 +312,82 @@ func (c *Chain) GetGitHubPrivateKey() []byte {
 	return nil
 }
 
+// Validate runs the package Validate on the values the chain resolves to.
+// Because each value may come from a different manager, it also catches GitHub
+// App credentials that are only complete when mixed across managers.
+func (c *Chain) Validate() error {
+	return Validate(c)
+}
+
+// StrictChain implements the Manager interface like Chain, except that the
+// GitHub App ID, installation ID and private key always come together from
+// the first manager that sets any of them. A partially configured manager
+// then can't be silently completed by a lower-priority one.
+type StrictChain struct {
+	managers []Manager
+}
+
+// NewStrictChain creates a new StrictChain with the given managers.
+func NewStrictChain(managers ...Manager) *StrictChain {
+	return &StrictChain{managers: managers}
+}
+
+// appSource returns the first manager that sets any GitHub App credential,
+// or nil if none does.
+func (c *StrictChain) appSource() Manager {
+	for _, m := range c.managers {
+		if m == nil {
+			continue
+		}
+		if m.GetGitHubAppID() != 0 || m.GetGitHubInstallationID() != 0 || len(m.GetGitHubPrivateKey()) > 0 {
+			return m
+		}
+	}
+	return nil
+}
+
+// GetWebhookSecret returns the GitHub webhook secret from the first manager that returns a non-empty value.
+func (c *StrictChain) GetWebhookSecret() string {
+	return NewChain(c.managers...).GetWebhookSecret()
+}
+
+// GetPreviousWebhookSecret returns the previous GitHub webhook secret from the first manager that returns a
+// non-empty value.
+func (c *StrictChain) GetPreviousWebhookSecret() string {
+	return NewChain(c.managers...).GetPreviousWebhookSecret()
+}
+
+// GetGitHubAppID returns the GitHub App ID from the manager supplying the App credentials.
+func (c *StrictChain) GetGitHubAppID() int64 {
+	if m := c.appSource(); m != nil {
+		return m.GetGitHubAppID()
+	}
+	return 0
+}
+
+// GetGitHubInstallationID returns the GitHub App Installation ID from the manager supplying the App credentials.
+func (c *StrictChain) GetGitHubInstallationID() int64 {
+	if m := c.appSource(); m != nil {
+		return m.GetGitHubInstallationID()
+	}
+	return 0
+}
+
+// GetGitHubPrivateKey returns the GitHub App private key from the manager supplying the App credentials.
+func (c *StrictChain) GetGitHubPrivateKey() []byte {
+	if m := c.appSource(); m != nil {
+		return m.GetGitHubPrivateKey()
+	}
+	return nil
+}
+
+// Validate runs the package Validate on the values the chain resolves to,
+// which checks that the manager supplying the GitHub App credentials has all
+// of them.
+func (c *StrictChain) Validate() error {
+	return Validate(c)
+}
+
 // LoadFileConfig loads secret configuration from a file.
 func LoadFileConfig(path string) (*FileManager, error) {
 	// Function implementation will be moved from config.go
@@ -277,6 +400,9 @@ func LoadFileConfig(path string) (*FileManager, error) {
 func LoadFromEnv() (*EnvManager, error) {
 	// Create a new EnvManager
 	envManager := NewEnvManager()
+	if envManager.privateKeyErr != nil {
+		return nil, envManager.privateKeyErr
+	}
 
 	// Check if required environment variables are present
 	if envManager.GetWebhookSecret() == "" {
@@ -304,6 +430,49 @@ func Validate(m Manager) error {
 			"github_app_id, github_installation_id, and github_private_key must all be set for GitHub App authentication",
 		)
 	}
+	if hasKeyData {
+		if err := ValidatePrivateKey(m.GetGitHubPrivateKey()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// envPrivateKey returns the GitHub App private key from OTTO_GITHUB_PRIVATE_KEY,
+// or else from the file named by OTTO_GITHUB_PRIVATE_KEY_PATH, such as a
+// mounted secret. It returns nil if neither is set.
+func envPrivateKey() ([]byte, error) {
+	if key := os.Getenv("OTTO_GITHUB_PRIVATE_KEY"); key != "" {
+		return []byte(key), nil
+	}
+	path := os.Getenv("OTTO_GITHUB_PRIVATE_KEY_PATH")
+	if path == "" {
+		return nil, nil
+	}
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OTTO_GITHUB_PRIVATE_KEY_PATH: %w", err)
+	}
+	return key, nil
+}
 
+// ValidatePrivateKey checks that key is a PEM-encoded RSA private key, in the
+// PKCS #1 or PKCS #8 form GitHub App keys come in.
+func ValidatePrivateKey(key []byte) error {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return errors.New("invalid GitHub App private key: no PEM block found")
+	}
+	if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	if _, ok := parsed.(*rsa.PrivateKey); !ok {
+		return fmt.Errorf("invalid GitHub App private key: want an RSA key, got %T", parsed)
+	}
 	return nil
 }
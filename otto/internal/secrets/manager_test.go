@@ -3,9 +3,39 @@
 package secrets
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
+// writePEM encodes der as a PEM block of the given type in a temp file and
+// returns the file's path and contents.
+func writePEM(t *testing.T, blockType string, der []byte) (string, []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path, data
+}
+
+// testRSAKey returns a new RSA private key.
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
 func TestEnvManager(t *testing.T) {
 	// Set environment variables
 	t.Setenv("OTTO_WEBHOOK_SECRET", "test-webhook-secret")
@@ -105,6 +135,40 @@ func TestFileManagerWithEnv(t *testing.T) {
 	}
 }
 
+func TestPreviousWebhookSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.yaml")
+	data := "webhook_secret: new-secret\nprevious_webhook_secret: old-secret\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write secrets: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		env  string
+		want string
+	}{
+		{"from file", "", "old-secret"},
+		{"environment wins", "env-old-secret", "env-old-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTTO_PREVIOUS_WEBHOOK_SECRET", tt.env)
+			fileManager, err := loadFileConfig(path)
+			if err != nil {
+				t.Fatalf("loadFileConfig failed: %v", err)
+			}
+			if got := fileManager.GetPreviousWebhookSecret(); got != tt.want {
+				t.Errorf("GetPreviousWebhookSecret() = %v, want %v", got, tt.want)
+			}
+			chain := NewChain(NewEnvManager(), fileManager)
+			if got := chain.GetPreviousWebhookSecret(); got != tt.want {
+				t.Errorf("chain GetPreviousWebhookSecret() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidateFileManager(t *testing.T) {
 	// Test complete config
 	complete := NewFileManager(
@@ -206,3 +270,169 @@ func TestChain(t *testing.T) {
 		t.Errorf("GetWebhookSecret() for chain3 = %v, want %v", got, "file-webhook-secret")
 	}
 }
+
+func TestPrivateKeyPath(t *testing.T) {
+	keyPath, keyPEM := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(testRSAKey(t)))
+
+	tests := []struct {
+		name    string
+		inline  string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "path", path: keyPath, want: string(keyPEM)},
+		{name: "inline takes precedence", inline: "inline-key", path: keyPath, want: "inline-key"},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.pem"), wantErr: true},
+		{name: "neither set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTTO_WEBHOOK_SECRET", "test-webhook-secret")
+			t.Setenv("OTTO_GITHUB_PRIVATE_KEY", tt.inline)
+			t.Setenv("OTTO_GITHUB_PRIVATE_KEY_PATH", tt.path)
+
+			envManager, err := LoadFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if got := string(envManager.GetGitHubPrivateKey()); got != tt.want {
+					t.Errorf("EnvManager.GetGitHubPrivateKey() = %q, want %q", got, tt.want)
+				}
+			}
+
+			fileManager := NewFileManager("test-webhook-secret", 12345, 67890, "", nil)
+			if err := ValidateFileManager(fileManager); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFileManager() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := string(fileManager.GetGitHubPrivateKey()); got != tt.want {
+				t.Errorf("FileManager.GetGitHubPrivateKey() = %q, want %q", got, tt.want)
+			}
+
+			chain := NewChain(NewEnvManager(), fileManager)
+			if got := string(chain.GetGitHubPrivateKey()); got != tt.want {
+				t.Errorf("Chain.GetGitHubPrivateKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePrivateKey(t *testing.T) {
+	rsaKey := testRSAKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecPKCS8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+
+	_, pkcs1PEM := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	_, pkcs8PEM := writePEM(t, "PRIVATE KEY", pkcs8)
+	_, ecPEM := writePEM(t, "PRIVATE KEY", ecPKCS8)
+	_, corruptPEM := writePEM(t, "RSA PRIVATE KEY", []byte("not a key"))
+
+	tests := []struct {
+		name    string
+		key     []byte
+		wantErr bool
+	}{
+		{"PKCS #1 RSA key", pkcs1PEM, false},
+		{"PKCS #8 RSA key", pkcs8PEM, false},
+		{"not PEM", []byte("test-private-key"), true},
+		{"corrupt key", corruptPEM, true},
+		{"EC key", ecPEM, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePrivateKey(tt.key); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePrivateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// Validate checks the key of any manager, such as a chain
+			chain := NewChain(&EnvManager{
+				webhookSecret:  "test-webhook-secret",
+				gitHubAppID:    12345,
+				installationID: 67890,
+				privateKey:     tt.key,
+			})
+			if err := Validate(chain); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStrictChain(t *testing.T) {
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(testRSAKey(t))})
+	full := &EnvManager{webhookSecret: "full-secret", gitHubAppID: 1, installationID: 2, privateKey: keyPEM}
+	appIDOnly := &EnvManager{gitHubAppID: 3}
+	webhookOnly := &EnvManager{webhookSecret: "webhook-only-secret"}
+
+	tests := []struct {
+		name           string
+		managers       []Manager
+		wantAppID      int64
+		wantInstallID  int64
+		wantStrictErr  bool
+		wantStrictHook string
+	}{
+		{
+			name:           "partial manager first",
+			managers:       []Manager{appIDOnly, full},
+			wantAppID:      3,
+			wantStrictErr:  true,
+			wantStrictHook: "full-secret",
+		},
+		{
+			name:           "full manager first",
+			managers:       []Manager{full, appIDOnly},
+			wantAppID:      1,
+			wantInstallID:  2,
+			wantStrictHook: "full-secret",
+		},
+		{
+			name:           "webhook from one manager, app from another",
+			managers:       []Manager{webhookOnly, nil, full},
+			wantAppID:      1,
+			wantInstallID:  2,
+			wantStrictHook: "webhook-only-secret",
+		},
+		{
+			name:           "no app credentials",
+			managers:       []Manager{webhookOnly},
+			wantStrictHook: "webhook-only-secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Chain mixes fields across managers, so it always sees a complete App
+			if err := NewChain(tt.managers...).Validate(); err != nil {
+				t.Errorf("Chain.Validate() error = %v", err)
+			}
+
+			strict := NewStrictChain(tt.managers...)
+			if got := strict.GetWebhookSecret(); got != tt.wantStrictHook {
+				t.Errorf("GetWebhookSecret() = %v, want %v", got, tt.wantStrictHook)
+			}
+			if got := strict.GetGitHubAppID(); got != tt.wantAppID {
+				t.Errorf("GetGitHubAppID() = %v, want %v", got, tt.wantAppID)
+			}
+			if got := strict.GetGitHubInstallationID(); got != tt.wantInstallID {
+				t.Errorf("GetGitHubInstallationID() = %v, want %v", got, tt.wantInstallID)
+			}
+			if err := strict.Validate(); (err != nil) != tt.wantStrictErr {
+				t.Errorf("StrictChain.Validate() error = %v, wantErr %v", err, tt.wantStrictErr)
+			}
+		})
+	}
+}
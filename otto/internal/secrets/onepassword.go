@@ -9,29 +9,38 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"sync"
 
 	"github.com/1password/onepassword-sdk-go"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/cache"
 )
 
 // OnePasswordManager implements the Manager interface using 1Password Connect.
+// It implements io.Closer to release the client.
 type OnePasswordManager struct {
-	client           *onepassword.Client
-	webhookSecretRef string
-	appIDRef         string
-	installIDRef     string
-	privateKeyRef    string
-	refs             map[string]string
-	cachedValues     map[string]string
+	// mu guards client, which Close resets.
+	mu                       sync.Mutex
+	client                   *onepassword.Client
+	webhookSecretRef         string
+	previousWebhookSecretRef string
+	appIDRef                 string
+	installIDRef             string
+	privateKeyRef            string
+	refs                     map[string]string
+	// cachedValues holds resolved references until Close.
+	cachedValues *cache.TTL[string, string]
 
 	// Environment values take precedence and are cached during initialization
-	envWebhookSecret  string
-	envGitHubAppID    int64
-	envInstallationID int64
-	envPrivateKey     []byte
-	hasEnvWebhook     bool
-	hasEnvAppID       bool
-	hasEnvInstallID   bool
-	hasEnvPrivateKey  bool
+	envWebhookSecret         string
+	envPreviousWebhookSecret string
+	envGitHubAppID           int64
+	envInstallationID        int64
+	envPrivateKey            []byte
+	hasEnvWebhook            bool
+	hasEnvPreviousWebhook    bool
+	hasEnvAppID              bool
+	hasEnvInstallID          bool
+	hasEnvPrivateKey         bool
 }
 
 // NewOnePasswordManager creates a new OnePasswordManager with the given references.
@@ -62,7 +71,7 @@ func NewOnePasswordManager(
 		installIDRef:     installIDRef,
 		privateKeyRef:    privateKeyRef,
 		refs:             make(map[string]string),
-		cachedValues:     make(map[string]string),
+		cachedValues:     newSecretCache(),
 	}
 
 	// Check for environment variables once during initialization
@@ -70,6 +79,10 @@ func NewOnePasswordManager(
 		manager.envWebhookSecret = envVal
 		manager.hasEnvWebhook = true
 	}
+	if envVal := os.Getenv("OTTO_PREVIOUS_WEBHOOK_SECRET"); envVal != "" {
+		manager.envPreviousWebhookSecret = envVal
+		manager.hasEnvPreviousWebhook = true
+	}
 
 	if envVal := os.Getenv("OTTO_GITHUB_APP_ID"); envVal != "" {
 		id, err := strconv.ParseInt(envVal, 10, 64)
@@ -87,10 +100,11 @@ func NewOnePasswordManager(
 		}
 	}
 
-	if envVal := os.Getenv("OTTO_GITHUB_PRIVATE_KEY"); envVal != "" {
-		manager.envPrivateKey = []byte(envVal)
-		manager.hasEnvPrivateKey = true
+	manager.envPrivateKey, err = envPrivateKey()
+	if err != nil {
+		return nil, err
 	}
+	manager.hasEnvPrivateKey = len(manager.envPrivateKey) > 0
 
 	// Validate references
 	if err := manager.validateReferences(); err != nil {
@@ -100,6 +114,12 @@ func NewOnePasswordManager(
 	return manager, nil
 }
 
+// newSecretCache returns a cache for resolved references, which are kept
+// until Close.
+func newSecretCache() *cache.TTL[string, string] {
+	return cache.New[string, string](0, 0, nil)
+}
+
 // validateReferences checks that the references are valid.
 func (o *OnePasswordManager) validateReferences() error {
 	// Skip validation if we have webhook secret from environment
@@ -129,19 +149,25 @@ func (o *OnePasswordManager) validateReferences() error {
 
 // resolveReference gets a secret value from 1Password using the op reference.
 func (o *OnePasswordManager) resolveReference(ctx context.Context, ref string) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
 	// Check cache first
-	if val, ok := o.cachedValues[ref]; ok {
+	if val, ok := o.cachedValues.Get(ref); ok {
 		return val, nil
 	}
 
 	// Resolve the reference
+	if o.client == nil {
+		return "", errors.New("1Password manager is closed")
+	}
 	value, err := o.client.Secrets().Resolve(ctx, ref)
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve reference %s: %w", ref, err)
 	}
 
 	// Cache the value
-	o.cachedValues[ref] = value
+	o.cachedValues.Set(ref, value)
 
 	return value, nil
 }
@@ -166,6 +192,23 @@ func (o *OnePasswordManager) GetWebhookSecret() string {
 	return ""
 }
 
+// GetPreviousWebhookSecret returns the previous GitHub webhook secret, if one
+// is configured.
+func (o *OnePasswordManager) GetPreviousWebhookSecret() string {
+	if o.hasEnvPreviousWebhook {
+		return o.envPreviousWebhookSecret
+	}
+	if o.previousWebhookSecretRef == "" {
+		return ""
+	}
+	val, err := o.resolveReference(context.Background(), o.previousWebhookSecretRef)
+	if err != nil {
+		slog.Error("Failed to retrieve previous webhook secret from 1Password", "error", err)
+		return ""
+	}
+	return val
+}
+
 // GetGitHubAppID returns the GitHub App ID.
 func (o *OnePasswordManager) GetGitHubAppID() int64 {
 	// Check cached environment variable first
@@ -243,6 +286,17 @@ func (o *OnePasswordManager) GetGitHubPrivateKey() []byte {
 	return nil
 }
 
+// Close releases the 1Password client and forgets the cached secret values.
+// The SDK frees a client's resources once it is no longer referenced. After
+// Close, only values from environment variables are returned.
+func (o *OnePasswordManager) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.client = nil
+	o.cachedValues.Clear()
+	return nil
+}
+
 // LoadOnePasswordConfig loads 1Password configuration from the given path.
 func LoadOnePasswordConfig(path string) (*OnePasswordManager, error) {
 	// Read the configuration file
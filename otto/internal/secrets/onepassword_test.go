@@ -5,6 +5,8 @@ package secrets
 import (
 	"os"
 	"testing"
+
+	"github.com/1password/onepassword-sdk-go"
 )
 
 func TestOnePasswordManagerValidation(t *testing.T) {
@@ -57,3 +59,26 @@ func TestOnePasswordManagerEnvironmentFallback(t *testing.T) {
 		t.Skip("Skipping 1Password test as OTTO_1PASSWORD_TOKEN is not set")
 	}
 }
+
+func TestOnePasswordManagerClose(t *testing.T) {
+	ref := "op://vault/item/webhook"
+	manager := &OnePasswordManager{
+		client:           &onepassword.Client{},
+		webhookSecretRef: ref,
+		cachedValues:     newSecretCache(),
+	}
+	manager.cachedValues.Set(ref, "cached-webhook-secret")
+	if got := manager.GetWebhookSecret(); got != "cached-webhook-secret" {
+		t.Fatalf("GetWebhookSecret() = %v, want %v", got, "cached-webhook-secret")
+	}
+
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if manager.cachedValues.Len() != 0 || manager.client != nil {
+		t.Errorf("Close() kept the client or %d cached values", manager.cachedValues.Len())
+	}
+	if got := manager.GetWebhookSecret(); got != "" {
+		t.Errorf("GetWebhookSecret() after Close = %v, want empty", got)
+	}
+}
@@ -5,27 +5,47 @@
 package internal
 
 import (
+	"bytes"
+	"cmp"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
 	"crypto/subtle"
-	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
 	"github.com/open-telemetry/sig-project-infra/otto/internal/secrets"
 )
 
 type Server struct {
 	webhookSecret []byte // from secrets config
-	mux           *http.ServeMux
-	server        *http.Server
-	app           *App // Reference to the app for dispatching events
+	// previousWebhookSecret is also from secrets config and is still accepted
+	// while the webhook secret is being rotated.
+	previousWebhookSecret []byte
+	adminToken            []byte // from OTTO_ADMIN_TOKEN, empty disables gated admin endpoints
+	maxBodyBytes          int64
+	mux                   *http.ServeMux
+	server                *http.Server
+	app                   *App // Reference to the app for dispatching events
+	// events keeps the last received webhooks for /debug/events.
+	events *eventRing
+	// clock is the source of the current time; nil means the system clock.
+	clock Clock
+}
+
+// now returns the current time from the server's clock.
+func (s *Server) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
 }
 
 // NewServer creates a new server with the provided webhook secret and address.
@@ -37,24 +57,59 @@ func NewServer(addr string, secretsManager secrets.Manager) *Server {
 func NewServerWithApp(addr string, secretsManager secrets.Manager, app *App) *Server {
 	mux := http.NewServeMux()
 	srv := &Server{
-		webhookSecret: []byte(secretsManager.GetWebhookSecret()),
-		mux:           mux,
+		webhookSecret:         []byte(secretsManager.GetWebhookSecret()),
+		previousWebhookSecret: []byte(secretsManager.GetPreviousWebhookSecret()),
+		adminToken:            []byte(os.Getenv("OTTO_ADMIN_TOKEN")),
+		mux:                   mux,
 		server: &http.Server{
 			Addr:              fmt.Sprintf(":%v", addr),
 			Handler:           mux,
 			ReadHeaderTimeout: 10 * time.Second,
+			// Bounds how long a slow client can hold a webhook body buffer
+			ReadTimeout: 30 * time.Second,
 		},
 		app: app,
 	}
-	mux.HandleFunc("/webhook", srv.handleWebhook)
+	var cfg config.AppConfig
+	if app != nil && app.Config != nil {
+		cfg = *app.Config
+	}
+	webhookPath := cmp.Or(cfg.WebhookPath, config.DefaultWebhookPath)
+	livenessPath := cmp.Or(cfg.LivenessPath, config.DefaultLivenessPath)
+	readinessPath := cmp.Or(cfg.ReadinessPath, config.DefaultReadinessPath)
+	srv.maxBodyBytes = cmp.Or(cfg.MaxWebhookBodyBytes, config.DefaultMaxWebhookBodyBytes)
+	srv.events = newEventRing(cmp.Or(cfg.RecentEvents, config.DefaultRecentEvents))
+	mux.HandleFunc(webhookPath, srv.handleWebhook)
 
 	// Health check endpoints
-	mux.HandleFunc("/check/liveness", srv.handleLivenessCheck)   // Kubernetes liveness probe
-	mux.HandleFunc("/check/readiness", srv.handleReadinessCheck) // Kubernetes readiness probe
+	mux.HandleFunc(livenessPath, srv.handleLivenessCheck)   // Kubernetes liveness probe
+	mux.HandleFunc(readinessPath, srv.handleReadinessCheck) // Kubernetes readiness probe
+
+	mux.HandleFunc("GET /admin/modules", srv.requireAdminToken(srv.handleListModules))
+	mux.HandleFunc("GET /admin/config", srv.requireAdminToken(srv.handleShowConfig))
+	mux.HandleFunc("GET /admin/db", srv.requireAdminToken(srv.handleDatabaseStats))
+	mux.HandleFunc("GET /debug/events", srv.requireAdminToken(srv.handleRecentEvents))
 
 	return srv
 }
 
+// registerModuleRoutes lets the modules that implement ModuleRouter register
+// their endpoints, which are served under /api/ behind the admin token.
+func (s *Server) registerModuleRoutes(modules map[string]Module) {
+	api := http.NewServeMux()
+	for _, m := range modules {
+		if router, ok := m.(ModuleRouter); ok {
+			router.RegisterRoutes(api)
+		}
+	}
+	s.mux.HandleFunc("/api/", s.requireAdminToken(api.ServeHTTP))
+}
+
+// Handler returns the server's HTTP handler, for serving it in tests.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
 // handleLivenessCheck implements a Kubernetes liveness probe.
 // It returns healthy if the server is running and can accept requests.
 func (s *Server) handleLivenessCheck(w http.ResponseWriter, r *http.Request) {
@@ -83,7 +138,7 @@ func (s *Server) handleReadinessCheck(w http.ResponseWriter, r *http.Request) {
 	// Check database connectivity if database exists
 	if s.app.Database != nil {
 		err := s.app.Database.DB().Ping()
-		if err != nil {
+		if err != nil || !s.app.Database.Healthy() {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			_, writeErr := w.Write(
@@ -105,6 +160,106 @@ func (s *Server) handleReadinessCheck(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleListModules lists the registered modules and whether each is enabled.
+func (s *Server) handleListModules(w http.ResponseWriter, r *http.Request) {
+	infos := []ModuleInfo{}
+	if s.app != nil && s.app.ModuleRegistry != nil {
+		infos = s.app.ModuleRegistry.ModuleInfo()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(infos); err != nil {
+		slog.Error("Failed to write modules response", "error", err)
+	}
+}
+
+// requireAdminToken only lets requests with the admin token as their bearer
+// token through to next. Without a configured token, the endpoint is not found.
+func (s *Server) requireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(s.adminToken) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), s.adminToken) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleShowConfig returns the effective configuration, with defaults applied
+// and config files merged, and with secret-looking values redacted.
+func (s *Server) handleShowConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := &config.AppConfig{}
+	if s.app != nil && s.app.Config != nil {
+		cfg = s.app.Config
+	}
+	redacted, err := config.Redacted(cfg)
+	if err != nil {
+		slog.Error("Failed to redact configuration", "error", err)
+		http.Error(w, "failed to encode configuration", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(redacted); err != nil {
+		slog.Error("Failed to write config response", "error", err)
+	}
+}
+
+// databaseStats is the JSON form of sql.DBStats served by /admin/db.
+type databaseStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMS     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// handleDatabaseStats returns the database connection pool statistics.
+func (s *Server) handleDatabaseStats(w http.ResponseWriter, r *http.Request) {
+	if s.app == nil || s.app.Database == nil {
+		http.Error(w, "no database configured", http.StatusServiceUnavailable)
+		return
+	}
+	stats := s.app.Database.Stats()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(databaseStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}); err != nil {
+		slog.Error("Failed to write database stats response", "error", err)
+	}
+}
+
+// handleRecentEvents lists the last received webhooks and their outcomes,
+// newest first, to diagnose why otto didn't react to one.
+func (s *Server) handleRecentEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.events.list()); err != nil {
+		slog.Error("Failed to write recent events response", "error", err)
+	}
+}
+
+// recordEvent keeps a received webhook with its outcome for /debug/events.
+func (s *Server) recordEvent(e RecentEvent, outcome string) {
+	e.Outcome = outcome
+	s.events.add(e)
+}
+
 // handleWebhook verifies signature and decodes GitHub webhook request.
 func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
@@ -114,8 +269,21 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	s.app.Telemetry.IncServerRequest(ctx, "webhook")
 	s.app.Telemetry.IncServerWebhook(ctx, eventType)
 
-	payload, err := io.ReadAll(r.Body)
+	recent := RecentEvent{Type: eventType, DeliveryID: r.Header.Get("X-GitHub-Delivery"), ReceivedAt: start}
+
+	payload, err := readWebhookBody(w, r, s.maxBodyBytes)
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.app.Telemetry.IncServerError(ctx, "webhook", "bodyTooLarge")
+			s.app.Telemetry.RecordServerLatency(
+				ctx,
+				"webhook",
+				float64(time.Since(start).Milliseconds()),
+			)
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		s.app.Telemetry.IncServerError(ctx, "webhook", "readBody")
 		s.app.Telemetry.RecordServerLatency(
 			ctx,
@@ -129,7 +297,14 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	sig := r.Header.Get("X-Hub-Signature-256")
 	if !s.verifySignature(payload, sig) {
-		s.app.Telemetry.IncServerError(ctx, "webhook", "badSig")
+		// Repeated failures may indicate someone probing the endpoint
+		slog.Warn("webhook signature verification failed",
+			"delivery_id", r.Header.Get("X-GitHub-Delivery"),
+			"remote_addr", r.RemoteAddr,
+			"event_type", eventType)
+		s.app.Telemetry.IncWebhookAuthFailure(ctx)
+		// The headers of an unverified request aren't kept
+		s.recordEvent(RecentEvent{ReceivedAt: start}, EventOutcomeInvalidSignature)
 		s.app.Telemetry.RecordServerLatency(
 			ctx,
 			"webhook",
@@ -143,6 +318,7 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	event, err := github.ParseWebHook(eventType, payload)
 	if err != nil {
 		s.app.Telemetry.IncServerError(ctx, "webhook", "parseEvent")
+		s.recordEvent(recent, EventOutcomeParseError)
 		s.app.Telemetry.RecordServerLatency(
 			ctx,
 			"webhook",
@@ -155,32 +331,131 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	slog.Info("received event",
 		"type", eventType,
 		"struct", fmt.Sprintf("%T", event))
+	repo, hasRepo := eventRepository(event)
+	recent.Repo = repo
+
+	// Reject replayed deliveries of events too old to act on
+	if s.app != nil && s.app.Config != nil && s.app.Config.MaxEventAge > 0 {
+		if at, ok := eventTime(event); ok && s.now().Sub(at) > s.app.Config.MaxEventAge {
+			slog.Warn("rejecting stale webhook event",
+				"delivery_id", r.Header.Get("X-GitHub-Delivery"),
+				"event_type", eventType,
+				"event_time", at,
+				"max_age", s.app.Config.MaxEventAge)
+			s.app.Telemetry.IncServerError(ctx, "webhook", "staleEvent")
+			s.recordEvent(recent, EventOutcomeStale)
+			s.app.Telemetry.RecordServerLatency(ctx, "webhook", float64(time.Since(start).Milliseconds()))
+			http.Error(w, "stale event", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	// Drop events of repositories no module should see before dispatching
+	if hasRepo && s.app != nil && s.app.Config != nil && !s.app.Config.IsRepositoryAllowed(repo) {
+		slog.Debug("ignoring event of repository not in allowlist", "type", eventType, "repo", repo)
+		s.app.Telemetry.IncFilteredEvent(ctx, eventType)
+		s.recordEvent(recent, EventOutcomeFiltered)
+		s.app.Telemetry.RecordServerLatency(ctx, "webhook", float64(time.Since(start).Milliseconds()))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 
 	// Dispatch event to all modules
-	if s.app != nil {
-		s.app.DispatchEvent(eventType, event, payload)
-	} else {
+	env := &EventEnvelope{
+		Type:       eventType,
+		Event:      event,
+		Raw:        payload,
+		DeliveryID: recent.DeliveryID,
+		ReceivedAt: start,
+	}
+	if target := r.Header.Get("X-GitHub-Hook-Installation-Target-ID"); target != "" {
+		env.InstallationTargetID, _ = strconv.ParseInt(target, 10, 64)
+	}
+	status := http.StatusOK
+	switch {
+	case s.app == nil:
 		slog.Error("No app reference in server, event dispatch failed")
+	case s.app.Config != nil && s.app.Config.SyncDispatch:
+		handled := s.app.DispatchEnvelopeAndWait(env)
+		if !handled {
+			status = http.StatusNoContent
+		}
+		s.recordEvent(recent, dispatchOutcome(handled))
+	default:
+		// Like DispatchEnvelope, recording the outcome once modules are done
+		wait := s.app.dispatch(env)
+		go func() {
+			s.recordEvent(recent, dispatchOutcome(wait()))
+		}()
 	}
 
 	s.app.Telemetry.RecordServerLatency(ctx, "webhook", float64(time.Since(start).Milliseconds()))
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 }
 
-// verifySignature checks the request payload using the shared secret (GitHub webhook HMAC SHA256).
-func (s *Server) verifySignature(payload []byte, sig string) bool {
-	if !strings.HasPrefix(sig, "sha256=") {
-		return false
-	}
-	sig = strings.TrimPrefix(sig, "sha256=")
-	mac := hmac.New(sha256.New, s.webhookSecret)
-	mac.Write(payload)
-	expectedMAC := mac.Sum(nil)
-	receivedMAC, err := hex.DecodeString(sig)
-	if err != nil {
-		return false
+// dispatchOutcome returns the recent event outcome of a dispatched event.
+func dispatchOutcome(handled bool) string {
+	if handled {
+		return EventOutcomeHandled
 	}
-	return subtle.ConstantTimeCompare(receivedMAC, expectedMAC) == 1
+	return EventOutcomeUnhandled
+}
+
+// eventRepository returns the full name of the repository an event refers to,
+// and false for events without one, such as installation events.
+func eventRepository(event any) (string, bool) {
+	e, ok := event.(interface{ GetRepo() *github.Repository })
+	if !ok || e.GetRepo() == nil {
+		return "", false
+	}
+	return e.GetRepo().GetFullName(), true
+}
+
+// eventTime returns when an event happened: when its comment or review was
+// last updated or submitted, or else when its issue or pull request was last
+// updated. It returns false for events without such a time.
+func eventTime(event any) (time.Time, bool) {
+	var at github.Timestamp
+	switch e := event.(type) {
+	case *github.IssueCommentEvent:
+		at = e.GetComment().GetUpdatedAt()
+	case *github.PullRequestReviewEvent:
+		at = e.GetReview().GetSubmittedAt()
+	case *github.PullRequestReviewCommentEvent:
+		at = e.GetComment().GetUpdatedAt()
+	case *github.IssuesEvent:
+		at = e.GetIssue().GetUpdatedAt()
+	case *github.PullRequestEvent:
+		at = e.GetPullRequest().GetUpdatedAt()
+	}
+	return at.Time, !at.IsZero()
+}
+
+// maxBodyPrealloc bounds how much of a declared Content-Length is allocated
+// before the body arrives, since the length comes from a client that hasn't
+// been authenticated yet.
+const maxBodyPrealloc = 64 << 10
+
+// readWebhookBody reads a request body of at most limit bytes. The raw bytes
+// are needed for signature verification and by modules, so the body can't be
+// stream-parsed; when the length is known the buffer starts at that size, up
+// to maxBodyPrealloc, and grows as the body is read.
+func readWebhookBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	body := http.MaxBytesReader(w, r.Body, limit)
+	var buf bytes.Buffer
+	if r.ContentLength > 0 && r.ContentLength <= limit {
+		buf.Grow(int(min(r.ContentLength, maxBodyPrealloc)))
+	}
+	if _, err := buf.ReadFrom(body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// verifySignature checks the request payload using the shared secret (GitHub webhook HMAC SHA256),
+// or the previous one during a rotation.
+func (s *Server) verifySignature(payload []byte, sig string) bool {
+	return VerifyWebhookSignature(payload, sig, s.webhookSecret, s.previousWebhookSecret)
 }
 
 // Start runs the HTTP server (blocking).
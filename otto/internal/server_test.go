@@ -3,10 +3,22 @@
 package internal
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/secrets"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func TestHealthEndpoints(t *testing.T) {
@@ -110,3 +122,655 @@ func TestReadinessWithApp(t *testing.T) {
 			actualResponse["status"], expectedResponse["status"])
 	}
 }
+
+func TestWebhookSignatureFailure(t *testing.T) {
+	tm, reader := newTestTelemetry(t)
+	app := &App{Telemetry: tm, ModuleRegistry: NewModuleRegistry()}
+	srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	payload := []byte(`{"action":"opened"}`)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.RemoteAddr = "203.0.113.7:4321"
+	req.Header.Set("X-GitHub-Event", "issues")
+	req.Header.Set("X-GitHub-Delivery", "delivery-123")
+	req.Header.Set("X-Hub-Signature-256", SignWebhookPayload([]byte("wrong"), payload))
+
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+	if got := counterValue(t, reader, "otto.server.webhook_auth_failures_total"); got != 1 {
+		t.Errorf("webhook auth failures = %d, want 1", got)
+	}
+	if got := counterValue(t, reader, "otto.server.errors_total"); got != 0 {
+		t.Errorf("server errors = %d, want 0", got)
+	}
+	for _, want := range []string{"level=WARN", "delivery_id=delivery-123", "remote_addr=203.0.113.7:4321"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log output %q does not contain %q", logs.String(), want)
+		}
+	}
+}
+
+func TestWebhookSecretRotation(t *testing.T) {
+	tm, _ := newTestTelemetry(t)
+	app := &App{Telemetry: tm, Logger: slog.Default(), ModuleRegistry: NewModuleRegistry()}
+	secretsManager := secrets.NewFileManager("new-secret", 0, 0, "", nil)
+	secretsManager.PreviousWebhookSecret = "old-secret"
+	srv := NewServerWithApp("0", secretsManager, app)
+
+	payload := []byte(`{"zen":"hi"}`)
+	tests := []struct {
+		name       string
+		secret     string
+		wantStatus int
+	}{
+		{"current secret", "new-secret", http.StatusOK},
+		{"previous secret", "old-secret", http.StatusOK},
+		{"unknown secret", "other-secret", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+			req.Header.Set("X-GitHub-Event", "ping")
+			req.Header.Set("X-Hub-Signature-256", SignWebhookPayload([]byte(tt.secret), payload))
+
+			rr := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestConfigurablePaths(t *testing.T) {
+	cfg := &config.AppConfig{
+		WebhookPath:   "/hooks/s3cr3t",
+		LivenessPath:  "/livez",
+		ReadinessPath: "/readyz",
+	}
+	tm, _ := newTestTelemetry(t)
+	app := &App{Config: cfg, Telemetry: tm, Logger: slog.Default(), ModuleRegistry: NewModuleRegistry()}
+	srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+	payload := []byte(`{"zen":"hi"}`)
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"webhook at custom path", http.MethodPost, "/hooks/s3cr3t", http.StatusOK},
+		{"default webhook path is not served", http.MethodPost, "/webhook", http.StatusNotFound},
+		{"liveness at custom path", http.MethodGet, "/livez", http.StatusOK},
+		{"default liveness path is not served", http.MethodGet, "/check/liveness", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(payload))
+			req.Header.Set("X-GitHub-Event", "ping")
+			req.Header.Set("X-Hub-Signature-256", SignWebhookPayload([]byte("secret"), payload))
+
+			rr := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWebhookBodyLimit(t *testing.T) {
+	tm, _ := newTestTelemetry(t)
+	cfg := &config.AppConfig{MaxWebhookBodyBytes: 64}
+	app := &App{Config: cfg, Telemetry: tm, Logger: slog.Default(), ModuleRegistry: NewModuleRegistry()}
+	srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+	tests := []struct {
+		name       string
+		payload    []byte
+		wantStatus int
+	}{
+		{"within limit", []byte(`{"zen":"hi"}`), http.StatusOK},
+		{"over limit", []byte(`{"zen":"` + strings.Repeat("x", 100) + `"}`), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(tt.payload))
+			req.Header.Set("X-GitHub-Event", "ping")
+			req.Header.Set("X-Hub-Signature-256", SignWebhookPayload([]byte("secret"), tt.payload))
+
+			rr := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWebhookBodyIsReadIncrementally(t *testing.T) {
+	srv := NewServer("0", secrets.NewFileManager("secret", 0, 0, "", nil))
+	if srv.server.ReadTimeout <= 0 {
+		t.Errorf("server has no read timeout")
+	}
+
+	// A declared length isn't allocated up front beyond maxBodyPrealloc
+	payload := []byte(`{"zen":"hi"}`)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.ContentLength = 25 << 20
+	got, err := readWebhookBody(httptest.NewRecorder(), req, 25<<20)
+	if err != nil {
+		t.Fatalf("readWebhookBody failed: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("body: got %q want %q", got, payload)
+	}
+	if cap(got) > maxBodyPrealloc {
+		t.Errorf("buffer capacity %d exceeds %d", cap(got), maxBodyPrealloc)
+	}
+}
+
+func TestSyncDispatchStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		syncDispatch bool
+		handles      bool
+		wantStatus   int
+	}{
+		{"async dispatch always accepts", false, false, http.StatusOK},
+		{"sync dispatch of a handled event", true, true, http.StatusOK},
+		{"sync dispatch of an unhandled event", true, false, http.StatusNoContent},
+	}
+
+	payload := []byte(`{"zen":"hi"}`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, _ := newTestTelemetry(t)
+			app := &App{
+				Config:         &config.AppConfig{SyncDispatch: tt.syncDispatch},
+				Telemetry:      tm,
+				Logger:         slog.Default(),
+				ModuleRegistry: NewModuleRegistry(),
+			}
+			mod := &reportingModule{mockModule: mockModule{name: "a"}, handles: tt.handles}
+			app.RegisterModule(mod)
+			srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+			req.Header.Set("X-GitHub-Event", "ping")
+			req.Header.Set("X-Hub-Signature-256", SignWebhookPayload([]byte("secret"), payload))
+
+			rr := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if tt.syncDispatch && atomic.LoadInt32(&mod.handled) != 1 {
+				t.Errorf("module had not handled the event when the response was written")
+			}
+		})
+	}
+}
+
+func TestWebhookDispatchesEnvelope(t *testing.T) {
+	tm, _ := newTestTelemetry(t)
+	app := &App{
+		Config:         &config.AppConfig{SyncDispatch: true},
+		Telemetry:      tm,
+		Logger:         slog.Default(),
+		ModuleRegistry: NewModuleRegistry(),
+	}
+	var got *EventEnvelope
+	envelopes := NewMockModule("envelopes")
+	envelopes.HandleEnvelopeFunc = func(env *EventEnvelope) (bool, error) {
+		got = env
+		return true, nil
+	}
+	var legacyType string
+	legacy := NewMockModule("legacy")
+	legacy.HandleEventFunc = func(eventType string, _ any, _ []byte) (bool, error) {
+		legacyType = eventType
+		return true, nil
+	}
+	app.RegisterModule(envelopes)
+	app.RegisterModule(legacy)
+	srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+	payload := []byte(`{"zen":"hi"}`)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.Header.Set("X-GitHub-Event", "ping")
+	req.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	req.Header.Set("X-GitHub-Hook-Installation-Target-ID", "79929171")
+	req.Header.Set("X-Hub-Signature-256", SignWebhookPayload([]byte("secret"), payload))
+	before := time.Now()
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	if got == nil {
+		t.Fatal("envelope module was not dispatched the event")
+	}
+	if got.Type != "ping" || got.DeliveryID != "72d3162e-cc78-11e3-81ab-4c9367dc0958" ||
+		got.InstallationTargetID != 79929171 || !bytes.Equal(got.Raw, payload) {
+		t.Errorf("unexpected envelope %+v", got)
+	}
+	if got.ReceivedAt.Before(before.Add(-time.Second)) || got.ReceivedAt.After(time.Now()) {
+		t.Errorf("received at %v, want about %v", got.ReceivedAt, before)
+	}
+	if legacyType != "ping" {
+		t.Errorf("legacy module event type: got %q want %q", legacyType, "ping")
+	}
+}
+
+func TestWebhookRepositoryAllowlist(t *testing.T) {
+	tests := []struct {
+		name         string
+		repositories []string
+		eventType    string
+		payload      string
+		wantDispatch bool
+		wantFiltered int64
+	}{
+		{"no allowlist", nil, "issues", `{"action":"opened","repository":{"full_name":"other/repo"}}`, true, 0},
+		{
+			"allowlisted repository",
+			[]string{"org/*"},
+			"issues",
+			`{"action":"opened","repository":{"full_name":"org/repo"}}`,
+			true,
+			0,
+		},
+		{
+			"repository not in allowlist",
+			[]string{"org/*"},
+			"issues",
+			`{"action":"opened","repository":{"full_name":"other/repo"}}`,
+			false,
+			1,
+		},
+		{"event without repository", []string{"org/*"}, "ping", `{"zen":"hi"}`, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, reader := newTestTelemetry(t)
+			app := &App{
+				Config:         &config.AppConfig{SyncDispatch: true, Repositories: tt.repositories},
+				Telemetry:      tm,
+				Logger:         slog.Default(),
+				ModuleRegistry: NewModuleRegistry(),
+			}
+			dispatched := false
+			mod := NewMockModule("recorder")
+			mod.HandleEventFunc = func(string, any, []byte) (bool, error) {
+				dispatched = true
+				return true, nil
+			}
+			app.RegisterModule(mod)
+			srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+			payload := []byte(tt.payload)
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+			req.Header.Set("X-GitHub-Event", tt.eventType)
+			req.Header.Set("X-Hub-Signature-256", SignWebhookPayload([]byte("secret"), payload))
+			rr := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+			if dispatched != tt.wantDispatch {
+				t.Errorf("dispatched: got %v want %v", dispatched, tt.wantDispatch)
+			}
+			attr := attribute.String("event_type", tt.eventType)
+			if got := counterValue(t, reader, "otto.server.filtered_events_total", attr); got != tt.wantFiltered {
+				t.Errorf("filtered events: got %d want %d", got, tt.wantFiltered)
+			}
+		})
+	}
+}
+
+func TestWebhookRejectsStaleEvents(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	comment := func(updated time.Time) string {
+		return fmt.Sprintf(`{"action":"created","comment":{"updated_at":%q}}`, updated.Format(time.RFC3339))
+	}
+	tests := []struct {
+		name         string
+		maxAge       time.Duration
+		eventType    string
+		payload      string
+		wantStatus   int
+		wantDispatch bool
+	}{
+		{"fresh event", time.Hour, "issue_comment", comment(now.Add(-time.Minute)), http.StatusOK, true},
+		{
+			"stale event",
+			time.Hour, "issue_comment", comment(now.Add(-2 * time.Hour)), http.StatusUnprocessableEntity, false,
+		},
+		{
+			"stale event with check disabled",
+			0, "issue_comment", comment(now.Add(-48 * time.Hour)), http.StatusOK, true,
+		},
+		{"event without a time", time.Hour, "ping", `{"zen":"hi"}`, http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, _ := newTestTelemetry(t)
+			app := &App{
+				Config:         &config.AppConfig{SyncDispatch: true, MaxEventAge: tt.maxAge},
+				Telemetry:      tm,
+				Logger:         slog.Default(),
+				ModuleRegistry: NewModuleRegistry(),
+			}
+			dispatched := false
+			mod := NewMockModule("recorder")
+			mod.HandleEventFunc = func(string, any, []byte) (bool, error) {
+				dispatched = true
+				return true, nil
+			}
+			app.RegisterModule(mod)
+			srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+			srv.clock = NewFakeClock(now)
+
+			payload := []byte(tt.payload)
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+			req.Header.Set("X-GitHub-Event", tt.eventType)
+			req.Header.Set("X-Hub-Signature-256", SignWebhookPayload([]byte("secret"), payload))
+			rr := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if dispatched != tt.wantDispatch {
+				t.Errorf("dispatched: got %v want %v", dispatched, tt.wantDispatch)
+			}
+		})
+	}
+}
+
+// routingModule serves a fixed response on a module endpoint.
+type routingModule struct {
+	mockModule
+}
+
+func (m *routingModule) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/"+m.name+"/status", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(m.name))
+	})
+}
+
+func TestModuleRoutes(t *testing.T) {
+	t.Setenv("OTTO_ADMIN_TOKEN", "admin")
+	app := &App{ModuleRegistry: NewModuleRegistry(), Logger: slog.Default()}
+	app.RegisterModule(&routingModule{mockModule{name: "a"}})
+	app.RegisterModule(&mockModule{name: "b"})
+	srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+	srv.registerModuleRoutes(app.GetModules())
+
+	tests := []struct {
+		path       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/api/a/status", "admin", http.StatusOK, "a"},
+		{"/api/a/status", "", http.StatusUnauthorized, ""},
+		{"/api/a/status", "wrong", http.StatusUnauthorized, ""},
+		{"/api/b/status", "admin", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s with token %q: status code: got %v want %v", tt.path, tt.token, rr.Code, tt.wantStatus)
+		}
+		if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+			t.Errorf("%s: body: got %q want %q", tt.path, rr.Body.String(), tt.wantBody)
+		}
+	}
+}
+
+func TestListModules(t *testing.T) {
+	t.Setenv("OTTO_ADMIN_TOKEN", "admin")
+	app := &App{ModuleRegistry: NewModuleRegistry(), Logger: slog.Default()}
+	app.RegisterModule(&mockModule{name: "b"})
+	app.RegisterModule(&mockModule{name: "a"})
+	app.ModuleRegistry.SetEnabled("b", true)
+	srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/modules", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var got []ModuleInfo
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+	}
+	want := []ModuleInfo{{Name: "a"}, {Name: "b", Enabled: true}}
+	if !slices.Equal(got, want) {
+		t.Errorf("modules: got %+v want %+v", got, want)
+	}
+}
+
+func TestShowConfig(t *testing.T) {
+	cfg := &config.AppConfig{Modules: map[string]any{"oncall": map[string]any{"api_token": "tok-123"}}}
+	config.ApplyDefaults(cfg)
+
+	tests := []struct {
+		name          string
+		path          string
+		adminToken    string
+		authorization string
+		wantStatus    int
+	}{
+		{"disabled without admin token", "/admin/config", "", "Bearer admin", http.StatusNotFound},
+		{"missing credentials", "/admin/config", "admin", "", http.StatusUnauthorized},
+		{"wrong token", "/admin/config", "admin", "Bearer nope", http.StatusUnauthorized},
+		{"valid token", "/admin/config", "admin", "Bearer admin", http.StatusOK},
+		{"modules disabled without admin token", "/admin/modules", "", "Bearer admin", http.StatusNotFound},
+		{"modules missing credentials", "/admin/modules", "admin", "", http.StatusUnauthorized},
+		{"modules wrong token", "/admin/modules", "admin", "Bearer nope", http.StatusUnauthorized},
+		{"modules valid token", "/admin/modules", "admin", "Bearer admin", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTTO_ADMIN_TOKEN", tt.adminToken)
+			app := &App{Config: cfg, Logger: slog.Default(), ModuleRegistry: NewModuleRegistry()}
+			srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if strings.Contains(rr.Body.String(), "tok-123") {
+				t.Errorf("response leaks a secret: %s", rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK || tt.path != "/admin/config" {
+				return
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+			}
+			if got["webhook_path"] != config.DefaultWebhookPath {
+				t.Errorf("webhook_path: got %v want %v", got["webhook_path"], config.DefaultWebhookPath)
+			}
+		})
+	}
+}
+
+func TestDatabaseStatsEndpoint(t *testing.T) {
+	t.Setenv("OTTO_ADMIN_TOKEN", "admin")
+	tests := []struct {
+		name       string
+		database   bool
+		wantStatus int
+	}{
+		{"with database", true, http.StatusOK},
+		{"without database", false, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{Logger: slog.Default()}
+			if tt.database {
+				app.Database = TestDatabase(t)
+			}
+			srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/db", nil)
+			req.Header.Set("Authorization", "Bearer admin")
+			rr := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+			}
+			for _, key := range []string{"open_connections", "in_use", "idle", "wait_count", "wait_duration_ms"} {
+				if _, ok := got[key]; !ok {
+					t.Errorf("response is missing %q: %v", key, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRecentEventsEndpoint(t *testing.T) {
+	t.Setenv("OTTO_ADMIN_TOKEN", "admin")
+	app := &App{
+		Config: &config.AppConfig{
+			SyncDispatch: true,
+			Repositories: []string{"org/*"},
+			RecentEvents: 3,
+		},
+		Telemetry:      NewNoopTelemetryManager(),
+		Logger:         slog.Default(),
+		ModuleRegistry: NewModuleRegistry(),
+	}
+	mod := NewMockModule("recorder")
+	mod.HandleEventFunc = func(_ string, event any, _ []byte) (bool, error) {
+		e, ok := event.(*github.IssuesEvent)
+		return ok && e.GetAction() == "opened", nil
+	}
+	app.RegisterModule(mod)
+	srv := NewServerWithApp("0", secrets.NewFileManager("secret", 0, 0, "", nil), app)
+
+	deliveries := []struct {
+		id      string
+		secret  string
+		payload string
+	}{
+		{"d1", "secret", `{"action":"opened","repository":{"full_name":"org/repo"}}`},
+		{"d2", "secret", `{"action":"opened","repository":{"full_name":"org/repo"}}`},
+		{"d3", "secret", `{"action":"closed","repository":{"full_name":"org/repo"}}`},
+		{"d4", "secret", `{"action":"opened","repository":{"full_name":"other/repo"}}`},
+		{"d5", "wrong", `{"action":"opened","repository":{"full_name":"org/repo"}}`},
+		{"d6", "wrong", `{"action":"opened","repository":{"full_name":"org/repo"}}`},
+	}
+	for _, d := range deliveries {
+		payload := []byte(d.payload)
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+		req.Header.Set("X-GitHub-Event", "issues")
+		req.Header.Set("X-GitHub-Delivery", d.id)
+		req.Header.Set("X-Hub-Signature-256", SignWebhookPayload([]byte(d.secret), payload))
+		srv.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/events", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var got []RecentEvent
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+	}
+
+	// Only the last three deliveries are kept, newest first, and the invalid
+	// ones share an entry without their unverified headers
+	want := []struct {
+		typ, id, repo, outcome string
+		count                  int
+	}{
+		{"", "", "", EventOutcomeInvalidSignature, 2},
+		{"issues", "d4", "other/repo", EventOutcomeFiltered, 0},
+		{"issues", "d3", "org/repo", EventOutcomeUnhandled, 0},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		e := got[i]
+		if e.Type != w.typ || e.DeliveryID != w.id || e.Repo != w.repo || e.Outcome != w.outcome || e.Count != w.count {
+			t.Errorf("event %d: got %+v, want %s %s of %q %s (%d)", i, e, w.typ, w.id, w.repo, w.outcome, w.count)
+		}
+		if e.ReceivedAt.IsZero() {
+			t.Errorf("event %d has no receive time", i)
+		}
+	}
+}
+
+func BenchmarkReadWebhookBody(b *testing.B) {
+	payload := bytes.Repeat([]byte("x"), 1<<20)
+	benchmarks := []struct {
+		name          string
+		contentLength int64
+	}{
+		{"known length", int64(len(payload))},
+		{"unknown length", -1},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+				req.ContentLength = bm.contentLength
+				if _, err := readWebhookBody(httptest.NewRecorder(), req, 2<<20); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// signature.go computes and checks GitHub webhook signatures.
+
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// SignWebhookPayload returns the X-Hub-Signature-256 header value GitHub would
+// send for payload when configured with secret.
+func SignWebhookPayload(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether header is a valid X-Hub-Signature-256
+// value for payload signed with any of secrets, so a secret can be rotated
+// while deliveries signed with the old one are still accepted. Empty secrets
+// never match, and SHA-1 (X-Hub-Signature) values are rejected.
+func VerifyWebhookSignature(payload []byte, header string, secrets ...[]byte) bool {
+	if !strings.HasPrefix(header, "sha256=") {
+		return false
+	}
+	receivedMAC, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil || len(receivedMAC) != sha256.Size {
+		return false
+	}
+
+	valid := false
+	for _, secret := range secrets {
+		if len(secret) == 0 {
+			continue
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(payload)
+		// Check every secret so timing doesn't reveal which one matched
+		if subtle.ConstantTimeCompare(receivedMAC, mac.Sum(nil)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // Only used to build a rejected legacy signature
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	payload := []byte(`{"action":"opened"}`)
+	secret := []byte("current")
+	valid := SignWebhookPayload(secret, payload)
+
+	legacy := hmac.New(sha1.New, secret)
+	legacy.Write(payload)
+	sha1Header := "sha1=" + hex.EncodeToString(legacy.Sum(nil))
+
+	tests := []struct {
+		name    string
+		header  string
+		payload []byte
+		secrets [][]byte
+		want    bool
+	}{
+		{"valid", valid, payload, [][]byte{secret}, true},
+		{"wrong secret", valid, payload, [][]byte{[]byte("other")}, false},
+		{"tampered payload", valid, []byte(`{"action":"closed"}`), [][]byte{secret}, false},
+		{"rotated secret", valid, payload, [][]byte{[]byte("next"), secret}, true},
+		{"empty header", "", payload, [][]byte{secret}, false},
+		{"missing prefix", valid[len("sha256="):], payload, [][]byte{secret}, false},
+		{"malformed hex", "sha256=not-hex", payload, [][]byte{secret}, false},
+		{"truncated digest", valid[:len(valid)-2], payload, [][]byte{secret}, false},
+		{"sha1 signature", sha1Header, payload, [][]byte{secret}, false},
+		{"no secrets", valid, payload, nil, false},
+		{"empty secret", SignWebhookPayload(nil, payload), payload, [][]byte{{}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhookSignature(tt.payload, tt.header, tt.secrets...); got != tt.want {
+				t.Errorf("VerifyWebhookSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,16 +6,24 @@ package internal
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"sync"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
 
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
@@ -25,6 +33,8 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 
 	"go.opentelemetry.io/contrib/bridges/otelslog"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
 )
 
 // InitMetrics initializes all metrics for the TelemetryManager.
@@ -61,9 +71,35 @@ func (t *TelemetryManager) InitMetrics() error {
 		return fmt.Errorf("failed to create server errors counter: %w", err)
 	}
 
+	t.ServerWebhookAuthFailures, err = meter.Int64Counter(
+		"otto.server.webhook_auth_failures_total",
+		metric.WithDescription("Webhooks rejected for an invalid signature"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create server webhook auth failures counter: %w", err)
+	}
+
+	t.ServerUnhandledEvents, err = meter.Int64Counter(
+		"otto.server.unhandled_events_total",
+		metric.WithDescription("Events no module acted on"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create server unhandled events counter: %w", err)
+	}
+
+	t.ServerFilteredEvents, err = meter.Int64Counter(
+		"otto.server.filtered_events_total",
+		metric.WithDescription("Webhook events of repositories not in the allowlist, dropped without dispatch"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create server filtered events counter: %w", err)
+	}
+
 	t.ServerLatencyHistogram, err = meter.Float64Histogram(
 		"otto.server.request_latency_ms",
 		metric.WithDescription("Request latency (ms)"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(t.Metrics.RequestBuckets()...),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create server latency histogram: %w", err)
@@ -78,6 +114,14 @@ func (t *TelemetryManager) InitMetrics() error {
 		return fmt.Errorf("failed to create module commands counter: %w", err)
 	}
 
+	t.ModuleCommandsSuppressed, err = meter.Int64Counter(
+		"otto.module.commands_suppressed_total",
+		metric.WithDescription("Repeated module commands ignored during their cooldown"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create module commands suppressed counter: %w", err)
+	}
+
 	t.ModuleErrors, err = meter.Int64Counter(
 		"otto.module.errors_total",
 		metric.WithDescription("Module errors"),
@@ -89,27 +133,54 @@ func (t *TelemetryManager) InitMetrics() error {
 	t.ModuleAckLatency, err = meter.Float64Histogram(
 		"otto.module.ack_latency_ms",
 		metric.WithDescription("Latency from issue to ack (ms)"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(t.Metrics.AckBuckets()...),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create module ack latency histogram: %w", err)
 	}
 
+	t.ModuleUnassignedEscalations, err = meter.Int64Counter(
+		"otto.module.unassigned_escalations_total",
+		metric.WithDescription("Escalations of tasks with no assignee"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create module unassigned escalations counter: %w", err)
+	}
+
+	t.ModuleInvalidRepositories, err = meter.Int64Counter(
+		"otto.module.invalid_repositories_total",
+		metric.WithDescription("Repository names rejected for not being owner/name"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create module invalid repositories counter: %w", err)
+	}
+
 	t.metricsInitialized = true
 	return nil
 }
 
 // IncServerRequest records an HTTP request in server metrics.
 func (t *TelemetryManager) IncServerRequest(ctx context.Context, handler string) {
+	if t.ServerRequests == nil {
+		return
+	}
 	t.ServerRequests.Add(ctx, 1, metric.WithAttributes(attribute.String("handler", handler)))
 }
 
 // IncServerWebhook records a webhook event in server metrics.
 func (t *TelemetryManager) IncServerWebhook(ctx context.Context, eventType string) {
+	if t.ServerWebhooks == nil {
+		return
+	}
 	t.ServerWebhooks.Add(ctx, 1, metric.WithAttributes(attribute.String("event_type", eventType)))
 }
 
 // IncServerError records a server error in metrics.
 func (t *TelemetryManager) IncServerError(ctx context.Context, handler string, errType string) {
+	if t.ServerErrors == nil {
+		return
+	}
 	t.ServerErrors.Add(
 		ctx,
 		1,
@@ -120,8 +191,40 @@ func (t *TelemetryManager) IncServerError(ctx context.Context, handler string, e
 	)
 }
 
+// IncWebhookAuthFailure records a webhook rejected for an invalid signature.
+func (t *TelemetryManager) IncWebhookAuthFailure(ctx context.Context) {
+	if t.ServerWebhookAuthFailures == nil {
+		return
+	}
+	t.ServerWebhookAuthFailures.Add(ctx, 1)
+}
+
+// IncFilteredEvent records an event dropped because its repository isn't
+// allowlisted.
+func (t *TelemetryManager) IncFilteredEvent(ctx context.Context, eventType string) {
+	if t.ServerFilteredEvents == nil {
+		return
+	}
+	t.ServerFilteredEvents.Add(ctx, 1, metric.WithAttributes(attribute.String("event_type", eventType)))
+}
+
+// IncUnhandledEvent records an event that no module acted on.
+func (t *TelemetryManager) IncUnhandledEvent(ctx context.Context, eventType string) {
+	if t.ServerUnhandledEvents == nil {
+		return
+	}
+	t.ServerUnhandledEvents.Add(
+		ctx,
+		1,
+		metric.WithAttributes(attribute.String("event_type", eventType)),
+	)
+}
+
 // RecordServerLatency records server request latency.
 func (t *TelemetryManager) RecordServerLatency(ctx context.Context, handler string, ms float64) {
+	if t.ServerLatencyHistogram == nil {
+		return
+	}
 	t.ServerLatencyHistogram.Record(
 		ctx,
 		ms,
@@ -129,20 +232,137 @@ func (t *TelemetryManager) RecordServerLatency(ctx context.Context, handler stri
 	)
 }
 
+// ObserveModules reports the modules listed by list as the otto.modules gauge,
+// with one series per module that is 1 while it is enabled and 0 otherwise.
+func (t *TelemetryManager) ObserveModules(list func() []ModuleInfo) error {
+	_, err := t.Meter().Int64ObservableGauge(
+		"otto.modules",
+		metric.WithDescription("Registered modules, 1 if enabled"),
+		metric.WithInt64Callback(func(_ context.Context, obs metric.Int64Observer) error {
+			for _, info := range list() {
+				var enabled int64
+				if info.Enabled {
+					enabled = 1
+				}
+				obs.Observe(enabled, metric.WithAttributes(attribute.String("module", info.Name)))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create modules gauge: %w", err)
+	}
+	return nil
+}
+
+// ObserveDatabase reports the connection pool statistics returned by stats:
+// the otto.db.connections gauge by state, and the number of waits for a free
+// connection and the total time spent waiting as counters. This shows the
+// pool running out of connections before requests time out.
+func (t *TelemetryManager) ObserveDatabase(stats func() sql.DBStats) error {
+	meter := t.Meter()
+	connections, err := meter.Int64ObservableGauge(
+		"otto.db.connections",
+		metric.WithDescription("Database connections by state"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create database connections gauge: %w", err)
+	}
+	waits, err := meter.Int64ObservableCounter(
+		"otto.db.wait_count",
+		metric.WithDescription("Waits for a free database connection"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create database wait counter: %w", err)
+	}
+	waitDuration, err := meter.Float64ObservableCounter(
+		"otto.db.wait_duration_ms",
+		metric.WithDescription("Time spent waiting for a free database connection (ms)"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create database wait duration counter: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, obs metric.Observer) error {
+		s := stats()
+		obs.ObserveInt64(connections, int64(s.InUse), metric.WithAttributes(attribute.String("state", "in_use")))
+		obs.ObserveInt64(connections, int64(s.Idle), metric.WithAttributes(attribute.String("state", "idle")))
+		obs.ObserveInt64(waits, s.WaitCount)
+		obs.ObserveFloat64(waitDuration, float64(s.WaitDuration)/float64(time.Millisecond))
+		return nil
+	}, connections, waits, waitDuration)
+	if err != nil {
+		return fmt.Errorf("failed to observe database stats: %w", err)
+	}
+	return nil
+}
+
+// OtherCommandLabel is the metric label for commands a module hasn't registered.
+const OtherCommandLabel = "other"
+
+// RegisterCommands declares the command names a module records in metrics.
+// Any other command is labeled OtherCommandLabel, so user-supplied text can't
+// create unbounded metric series.
+func (t *TelemetryManager) RegisterCommands(module string, commands ...string) {
+	t.commandsMu.Lock()
+	defer t.commandsMu.Unlock()
+	if t.commands == nil {
+		t.commands = make(map[string]map[string]bool)
+	}
+	if t.commands[module] == nil {
+		t.commands[module] = make(map[string]bool)
+	}
+	for _, c := range commands {
+		t.commands[module][c] = true
+	}
+}
+
+// commandLabel returns the metric label for a module's command.
+func (t *TelemetryManager) commandLabel(module, command string) string {
+	t.commandsMu.RLock()
+	defer t.commandsMu.RUnlock()
+	if t.commands[module][command] {
+		return command
+	}
+	return OtherCommandLabel
+}
+
 // IncModuleCommand records a module command execution in metrics.
 func (t *TelemetryManager) IncModuleCommand(ctx context.Context, module, command string) {
+	if t.ModuleCommands == nil {
+		return
+	}
 	t.ModuleCommands.Add(
 		ctx,
 		1,
 		metric.WithAttributes(
 			attribute.String("module", module),
-			attribute.String("command", command),
+			attribute.String("command", t.commandLabel(module, command)),
+		),
+	)
+}
+
+// IncModuleCommandSuppressed records a module command ignored during its cooldown.
+func (t *TelemetryManager) IncModuleCommandSuppressed(ctx context.Context, module, command string) {
+	if t.ModuleCommandsSuppressed == nil {
+		return
+	}
+	t.ModuleCommandsSuppressed.Add(
+		ctx,
+		1,
+		metric.WithAttributes(
+			attribute.String("module", module),
+			attribute.String("command", t.commandLabel(module, command)),
 		),
 	)
 }
 
 // IncModuleError records a module error in metrics.
 func (t *TelemetryManager) IncModuleError(ctx context.Context, module, errType string) {
+	if t.ModuleErrors == nil {
+		return
+	}
 	t.ModuleErrors.Add(
 		ctx,
 		1,
@@ -155,9 +375,28 @@ func (t *TelemetryManager) IncModuleError(ctx context.Context, module, errType s
 
 // RecordAckLatency records module acknowledgment latency.
 func (t *TelemetryManager) RecordAckLatency(ctx context.Context, module string, ms float64) {
+	if t.ModuleAckLatency == nil {
+		return
+	}
 	t.ModuleAckLatency.Record(ctx, ms, metric.WithAttributes(attribute.String("module", module)))
 }
 
+// IncUnassignedEscalation records an escalation of a task with no assignee.
+func (t *TelemetryManager) IncUnassignedEscalation(ctx context.Context, module string) {
+	if t.ModuleUnassignedEscalations == nil {
+		return
+	}
+	t.ModuleUnassignedEscalations.Add(ctx, 1, metric.WithAttributes(attribute.String("module", module)))
+}
+
+// IncInvalidRepository records a repository name a module rejected.
+func (t *TelemetryManager) IncInvalidRepository(ctx context.Context, module string) {
+	if t.ModuleInvalidRepositories == nil {
+		return
+	}
+	t.ModuleInvalidRepositories.Add(ctx, 1, metric.WithAttributes(attribute.String("module", module)))
+}
+
 // StartServerEventSpan creates a new tracing span for server event handling.
 func (t *TelemetryManager) StartServerEventSpan(
 	ctx context.Context,
@@ -174,29 +413,64 @@ func (t *TelemetryManager) StartModuleCommandSpan(
 	return t.Tracer().Start(ctx, "module."+module+"."+command)
 }
 
-// TelemetryManager encapsulates OpenTelemetry telemetry components.
+// TelemetryManager encapsulates OpenTelemetry telemetry components. Its
+// recording methods do nothing when their instrument is nil, so a manager
+// whose InitMetrics didn't run or failed part way loses measurements instead
+// of panicking in request handlers.
 type TelemetryManager struct {
 	TracerProvider *sdktrace.TracerProvider
 	MeterProvider  *sdkmetric.MeterProvider
 	LoggerProvider *sdklog.LoggerProvider
 	Logger         *slog.Logger
+	// Metrics sets the histogram bucket boundaries used by InitMetrics.
+	Metrics config.MetricsConfig
 
 	// Server metrics
-	ServerRequests         metric.Int64Counter
-	ServerWebhooks         metric.Int64Counter
-	ServerErrors           metric.Int64Counter
-	ServerLatencyHistogram metric.Float64Histogram
+	ServerRequests            metric.Int64Counter
+	ServerWebhooks            metric.Int64Counter
+	ServerErrors              metric.Int64Counter
+	ServerWebhookAuthFailures metric.Int64Counter
+	ServerUnhandledEvents     metric.Int64Counter
+	ServerFilteredEvents      metric.Int64Counter
+	ServerLatencyHistogram    metric.Float64Histogram
 
 	// Module metrics
-	ModuleCommands   metric.Int64Counter
-	ModuleErrors     metric.Int64Counter
-	ModuleAckLatency metric.Float64Histogram
+	ModuleCommands              metric.Int64Counter
+	ModuleCommandsSuppressed    metric.Int64Counter
+	ModuleErrors                metric.Int64Counter
+	ModuleAckLatency            metric.Float64Histogram
+	ModuleUnassignedEscalations metric.Int64Counter
+	ModuleInvalidRepositories   metric.Int64Counter
 
 	metricsInitialized bool
+
+	// commands holds the command labels each module registered
+	commandsMu sync.RWMutex
+	commands   map[string]map[string]bool
+}
+
+// NewNoopTelemetryManager returns a TelemetryManager whose providers have no
+// exporters or readers, for code running without configured telemetry.
+func NewNoopTelemetryManager() *TelemetryManager {
+	t := &TelemetryManager{
+		TracerProvider: sdktrace.NewTracerProvider(),
+		MeterProvider:  sdkmetric.NewMeterProvider(),
+		Logger:         slog.Default(),
+	}
+	// Creating instruments on a provider without readers can't fail
+	_ = t.InitMetrics()
+	return t
 }
 
 // NewTelemetryManager creates a new telemetry manager with OpenTelemetry components.
-func NewTelemetryManager(ctx context.Context) (*TelemetryManager, error) {
+// Logs are written to stdout in logFormat ("json" or "text") and exported through
+// the OpenTelemetry log bridge. Latency histograms use the bucket boundaries in
+// metrics.
+func NewTelemetryManager(
+	ctx context.Context,
+	logFormat string,
+	metrics config.MetricsConfig,
+) (*TelemetryManager, error) {
 	// Create resource
 	res, err := resource.Merge(
 		resource.Default(),
@@ -248,8 +522,11 @@ func NewTelemetryManager(ctx context.Context) (*TelemetryManager, error) {
 	otel.SetMeterProvider(meterProvider)
 	global.SetLoggerProvider(loggerProvider)
 
-	// Create slog bridge
-	handler := otelslog.NewHandler("otto")
+	// Create slog bridge alongside the local log output
+	handler := newFanoutHandler(
+		newLogHandler(os.Stdout, logFormat),
+		otelslog.NewHandler("otto"),
+	)
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
@@ -259,6 +536,7 @@ func NewTelemetryManager(ctx context.Context) (*TelemetryManager, error) {
 		MeterProvider:  meterProvider,
 		LoggerProvider: loggerProvider,
 		Logger:         logger,
+		Metrics:        metrics,
 	}
 
 	// Initialize metrics
@@ -270,32 +548,138 @@ func NewTelemetryManager(ctx context.Context) (*TelemetryManager, error) {
 	return telemetry, nil
 }
 
-// Tracer returns the tracer for Otto modules.
+// newLogHandler creates the local log handler for the given format.
+// Unknown formats fall back to JSON.
+func newLogHandler(w io.Writer, format string) slog.Handler {
+	if format == "text" {
+		return slog.NewTextHandler(w, nil)
+	}
+	return slog.NewJSONHandler(w, nil)
+}
+
+// fanoutHandler passes every log record to a set of handlers.
+type fanoutHandler struct {
+	handlers []slog.Handler
+}
+
+// newFanoutHandler creates a handler that writes to all the given handlers.
+func newFanoutHandler(handlers ...slog.Handler) *fanoutHandler {
+	return &fanoutHandler{handlers: handlers}
+}
+
+// Enabled reports whether any handler accepts records at the given level.
+func (f *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to every handler that accepts its level.
+func (f *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f.handlers {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a fanout handler whose handlers all include attrs.
+func (f *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(f.handlers))
+	for i, h := range f.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+// WithGroup returns a fanout handler whose handlers all use the group name.
+func (f *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(f.handlers))
+	for i, h := range f.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+// Tracer returns the tracer for Otto modules, or a no-op tracer when there is
+// no TracerProvider.
 func (t *TelemetryManager) Tracer() trace.Tracer {
+	if t.TracerProvider == nil {
+		return tracenoop.NewTracerProvider().Tracer("otto")
+	}
 	return t.TracerProvider.Tracer("otto")
 }
 
-// Meter returns the meter for Otto modules.
+// Meter returns the meter for Otto modules, or a no-op meter when there is no
+// MeterProvider.
 func (t *TelemetryManager) Meter() metric.Meter {
+	if t.MeterProvider == nil {
+		return metricnoop.NewMeterProvider().Meter("otto")
+	}
 	return t.MeterProvider.Meter("otto")
 }
 
-// Shutdown shuts down all telemetry providers.
+// Shutdown shuts down all telemetry providers. Every provider is flushed even
+// if another fails or hangs; Shutdown returns once all have finished or ctx is
+// done, joining their errors.
 func (t *TelemetryManager) Shutdown(ctx context.Context) error {
+	var providers []providerShutdown
 	if t.TracerProvider != nil {
-		if err := t.TracerProvider.Shutdown(ctx); err != nil {
-			return err
-		}
+		providers = append(providers, providerShutdown{"tracer", t.TracerProvider.Shutdown})
 	}
 	if t.MeterProvider != nil {
-		if err := t.MeterProvider.Shutdown(ctx); err != nil {
-			return err
-		}
+		providers = append(providers, providerShutdown{"meter", t.MeterProvider.Shutdown})
 	}
 	if t.LoggerProvider != nil {
-		if err := t.LoggerProvider.Shutdown(ctx); err != nil {
-			return err
+		providers = append(providers, providerShutdown{"logger", t.LoggerProvider.Shutdown})
+	}
+	return shutdownProviders(ctx, providers)
+}
+
+// providerShutdown is a named telemetry provider shutdown function.
+type providerShutdown struct {
+	name     string
+	shutdown func(context.Context) error
+}
+
+// shutdownProviders runs all shutdowns concurrently so a hung exporter can't
+// block the others, and stops waiting when ctx is done.
+func shutdownProviders(ctx context.Context, providers []providerShutdown) error {
+	type result struct {
+		index int
+		err   error
+	}
+	results := make(chan result, len(providers))
+	for i, p := range providers {
+		go func() {
+			results <- result{index: i, err: p.shutdown(ctx)}
+		}()
+	}
+
+	done := make([]bool, len(providers))
+	var errs []error
+	for range providers {
+		select {
+		case r := <-results:
+			done[r.index] = true
+			if r.err != nil {
+				errs = append(errs, fmt.Errorf("%s provider: %w", providers[r.index].name, r.err))
+			}
+		case <-ctx.Done():
+			for i, p := range providers {
+				if !done[i] {
+					errs = append(errs, fmt.Errorf("%s provider: %w", p.name, ctx.Err()))
+				}
+			}
+			return errors.Join(errs...)
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
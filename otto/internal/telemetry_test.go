@@ -0,0 +1,464 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newTestTelemetry creates a TelemetryManager backed by a manual metric reader.
+func newTestTelemetry(t *testing.T) (*TelemetryManager, *sdkmetric.ManualReader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	tm := &TelemetryManager{
+		TracerProvider: sdktrace.NewTracerProvider(),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	}
+	if err := tm.InitMetrics(); err != nil {
+		t.Fatalf("InitMetrics failed: %v", err)
+	}
+	return tm, reader
+}
+
+// counterValue sums the data points of an Int64 counter matching the given attributes.
+func counterValue(
+	t *testing.T,
+	reader *sdkmetric.ManualReader,
+	name string,
+	attrs ...attribute.KeyValue,
+) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("metric %s is not an int64 sum", name)
+			}
+			for _, dp := range sum.DataPoints {
+				match := true
+				for _, kv := range attrs {
+					if v, ok := dp.Attributes.Value(kv.Key); !ok || v != kv.Value {
+						match = false
+						break
+					}
+				}
+				if match {
+					total += dp.Value
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestIncUnhandledEvent(t *testing.T) {
+	tm, reader := newTestTelemetry(t)
+
+	tm.IncUnhandledEvent(t.Context(), "push")
+	tm.IncUnhandledEvent(t.Context(), "push")
+	tm.IncUnhandledEvent(t.Context(), "issues")
+
+	tests := []struct {
+		eventType string
+		want      int64
+	}{
+		{"push", 2},
+		{"issues", 1},
+		{"issue_comment", 0},
+	}
+	for _, tt := range tests {
+		got := counterValue(t, reader, "otto.server.unhandled_events_total",
+			attribute.String("event_type", tt.eventType))
+		if got != tt.want {
+			t.Errorf("unhandled events for %q = %d, want %d", tt.eventType, got, tt.want)
+		}
+	}
+}
+
+func TestUninitializedMetricsDoNotPanic(t *testing.T) {
+	tests := []struct {
+		name string
+		tm   *TelemetryManager
+	}{
+		{"zero value", &TelemetryManager{}},
+		{"providers without InitMetrics", &TelemetryManager{
+			TracerProvider: sdktrace.NewTracerProvider(),
+			MeterProvider:  sdkmetric.NewMeterProvider(),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("recording panicked: %v", r)
+				}
+			}()
+			ctx := t.Context()
+			tm := tt.tm
+			tm.IncServerRequest(ctx, "webhook")
+			tm.IncServerWebhook(ctx, "issues")
+			tm.IncServerError(ctx, "webhook", "parse")
+			tm.IncWebhookAuthFailure(ctx)
+			tm.IncFilteredEvent(ctx, "issues")
+			tm.IncUnhandledEvent(ctx, "push")
+			tm.RecordServerLatency(ctx, "webhook", 1)
+			tm.IncModuleCommand(ctx, "oncall", "ack")
+			tm.IncModuleCommandSuppressed(ctx, "oncall", "ack")
+			tm.IncModuleError(ctx, "oncall", "command")
+			tm.RecordAckLatency(ctx, "oncall", 1)
+			tm.IncUnassignedEscalation(ctx, "oncall")
+			tm.IncInvalidRepository(ctx, "oncall")
+			_, span := tm.StartServerEventSpan(ctx, "issues")
+			span.End()
+			_, span = tm.StartModuleCommandSpan(ctx, "oncall", "ack")
+			span.End()
+		})
+	}
+}
+
+func TestLatencyHistogramBuckets(t *testing.T) {
+	tests := []struct {
+		name        string
+		metrics     config.MetricsConfig
+		wantRequest []float64
+		wantAck     []float64
+	}{
+		{
+			name:        "defaults",
+			wantRequest: config.DefaultRequestLatencyBuckets,
+			wantAck:     config.DefaultAckLatencyBuckets,
+		},
+		{
+			name: "configured",
+			metrics: config.MetricsConfig{
+				RequestLatencyBuckets: []float64{1, 10, 100},
+				AckLatencyBuckets:     []float64{1000, 60_000},
+			},
+			wantRequest: []float64{1, 10, 100},
+			wantAck:     []float64{1000, 60_000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			tm := &TelemetryManager{
+				TracerProvider: sdktrace.NewTracerProvider(),
+				MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+				Metrics:        tt.metrics,
+			}
+			if err := tm.InitMetrics(); err != nil {
+				t.Fatalf("InitMetrics failed: %v", err)
+			}
+			tm.RecordServerLatency(t.Context(), "webhook", 42)
+			tm.ModuleAckLatency.Record(t.Context(), 42)
+
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(t.Context(), &rm); err != nil {
+				t.Fatalf("failed to collect metrics: %v", err)
+			}
+			want := map[string][]float64{
+				"otto.server.request_latency_ms": tt.wantRequest,
+				"otto.module.ack_latency_ms":     tt.wantAck,
+			}
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					bounds, ok := want[m.Name]
+					if !ok {
+						continue
+					}
+					delete(want, m.Name)
+					histogram, ok := m.Data.(metricdata.Histogram[float64])
+					if !ok || len(histogram.DataPoints) != 1 {
+						t.Fatalf("%s: want one float64 histogram data point, got %T", m.Name, m.Data)
+					}
+					if got := histogram.DataPoints[0].Bounds; !slices.Equal(got, bounds) {
+						t.Errorf("%s bounds = %v, want %v", m.Name, got, bounds)
+					}
+				}
+			}
+			for name := range want {
+				t.Errorf("histogram %s was not recorded", name)
+			}
+		})
+	}
+}
+
+func TestObserveModules(t *testing.T) {
+	tm, reader := newTestTelemetry(t)
+	infos := []ModuleInfo{{Name: "oncall", Enabled: true}, {Name: "triage"}}
+	if err := tm.ObserveModules(func() []ModuleInfo { return infos }); err != nil {
+		t.Fatalf("ObserveModules failed: %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	got := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			gauge, ok := m.Data.(metricdata.Gauge[int64])
+			if m.Name != "otto.modules" || !ok {
+				continue
+			}
+			for _, dp := range gauge.DataPoints {
+				name, _ := dp.Attributes.Value("module")
+				got[name.AsString()] = dp.Value
+			}
+		}
+	}
+
+	want := map[string]int64{"oncall": 1, "triage": 0}
+	if len(got) != len(want) {
+		t.Fatalf("modules gauge: got %v, want %v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("modules gauge for %q = %d, want %d", name, got[name], value)
+		}
+	}
+}
+
+func TestObserveDatabase(t *testing.T) {
+	tm, reader := newTestTelemetry(t)
+	calls := 0
+	stats := sql.DBStats{InUse: 3, Idle: 2, WaitCount: 5, WaitDuration: 1500 * time.Millisecond}
+	if err := tm.ObserveDatabase(func() sql.DBStats { calls++; return stats }); err != nil {
+		t.Fatalf("ObserveDatabase failed: %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	if calls == 0 {
+		t.Fatalf("stats callback was not run")
+	}
+	connections := make(map[string]int64)
+	var waits int64
+	var waitMS float64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					if state, ok := dp.Attributes.Value("state"); ok && m.Name == "otto.db.connections" {
+						connections[state.AsString()] = dp.Value
+					}
+				}
+			case metricdata.Sum[int64]:
+				if m.Name == "otto.db.wait_count" {
+					waits = data.DataPoints[0].Value
+				}
+			case metricdata.Sum[float64]:
+				if m.Name == "otto.db.wait_duration_ms" {
+					waitMS = data.DataPoints[0].Value
+				}
+			}
+		}
+	}
+
+	if connections["in_use"] != 3 || connections["idle"] != 2 {
+		t.Errorf("connections gauge: got %v, want in_use 3 and idle 2", connections)
+	}
+	if waits != 5 {
+		t.Errorf("wait count: got %d want 5", waits)
+	}
+	if waitMS != 1500 {
+		t.Errorf("wait duration: got %vms want 1500ms", waitMS)
+	}
+}
+
+func TestCommandMetricLabels(t *testing.T) {
+	tm, reader := newTestTelemetry(t)
+	tm.RegisterCommands("oncall", "ack", "resolve")
+
+	tm.IncModuleCommand(t.Context(), "oncall", "ack")
+	tm.IncModuleCommand(t.Context(), "oncall", "reassign my-secret-rotation")
+	tm.IncModuleCommand(t.Context(), "other-module", "ack")
+	tm.IncModuleCommandSuppressed(t.Context(), "oncall", "drop table")
+
+	tests := []struct {
+		metric  string
+		module  string
+		command string
+		want    int64
+	}{
+		{"otto.module.commands_total", "oncall", "ack", 1},
+		{"otto.module.commands_total", "oncall", OtherCommandLabel, 1},
+		{"otto.module.commands_total", "other-module", OtherCommandLabel, 1},
+		{"otto.module.commands_total", "oncall", "reassign my-secret-rotation", 0},
+		{"otto.module.commands_suppressed_total", "oncall", OtherCommandLabel, 1},
+	}
+	for _, tt := range tests {
+		got := counterValue(t, reader, tt.metric,
+			attribute.String("module", tt.module), attribute.String("command", tt.command))
+		if got != tt.want {
+			t.Errorf("%s{module=%q, command=%q} = %d, want %d", tt.metric, tt.module, tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestLogHandlerFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func(t *testing.T, out string)
+	}{
+		{
+			format: "json",
+			check: func(t *testing.T, out string) {
+				var entry map[string]any
+				if err := json.Unmarshal([]byte(out), &entry); err != nil {
+					t.Fatalf("expected JSON output, got %q: %v", out, err)
+				}
+				if entry["msg"] != "hello" || entry["key"] != "value" {
+					t.Errorf("unexpected JSON entry: %v", entry)
+				}
+			},
+		},
+		{
+			format: "text",
+			check: func(t *testing.T, out string) {
+				if !strings.Contains(out, "msg=hello") || !strings.Contains(out, "key=value") {
+					t.Errorf("expected text output, got %q", out)
+				}
+				if strings.HasPrefix(out, "{") {
+					t.Errorf("text output looks like JSON: %q", out)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(newLogHandler(&buf, tt.format))
+			logger.Info("hello", "key", "value")
+			tt.check(t, strings.TrimSpace(buf.String()))
+		})
+	}
+}
+
+func TestFanoutHandler(t *testing.T) {
+	var jsonBuf, textBuf bytes.Buffer
+	logger := slog.New(newFanoutHandler(
+		newLogHandler(&jsonBuf, "json"),
+		newLogHandler(&textBuf, "text"),
+	)).With("module", "test")
+
+	logger.Info("hello")
+
+	if !strings.Contains(jsonBuf.String(), `"module":"test"`) {
+		t.Errorf("JSON handler missing record: %q", jsonBuf.String())
+	}
+	if !strings.Contains(textBuf.String(), "module=test") {
+		t.Errorf("text handler missing record: %q", textBuf.String())
+	}
+}
+
+func TestShutdownProviders(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	block := func(context.Context) error {
+		<-make(chan struct{})
+		return nil
+	}
+
+	tests := []struct {
+		name      string
+		providers map[string]func(context.Context) error
+		wantErrs  []error
+	}{
+		{
+			name: "all succeed",
+			providers: map[string]func(context.Context) error{
+				"tracer": func(context.Context) error { return nil },
+				"meter":  func(context.Context) error { return nil },
+			},
+		},
+		{
+			name: "failure does not stop the others",
+			providers: map[string]func(context.Context) error{
+				"tracer": func(context.Context) error { return errFlush },
+				"meter":  func(context.Context) error { return nil },
+				"logger": func(context.Context) error { return nil },
+			},
+			wantErrs: []error{errFlush},
+		},
+		{
+			name: "hung provider is cut off by the deadline",
+			providers: map[string]func(context.Context) error{
+				"tracer": block,
+				"meter":  func(context.Context) error { return nil },
+				"logger": func(context.Context) error { return errFlush },
+			},
+			wantErrs: []error{context.DeadlineExceeded, errFlush},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			ran := make(map[string]bool)
+			var providers []providerShutdown
+			for name, fn := range tt.providers {
+				providers = append(providers, providerShutdown{name, func(ctx context.Context) error {
+					mu.Lock()
+					ran[name] = true
+					mu.Unlock()
+					return fn(ctx)
+				}})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := shutdownProviders(ctx, providers)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("shutdown took %v, want it bounded by the deadline", elapsed)
+			}
+
+			if len(tt.wantErrs) == 0 && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			for _, want := range tt.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("want error %v, got %v", want, err)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for name := range tt.providers {
+				if !ran[name] {
+					t.Errorf("%s provider was not shut down", name)
+				}
+			}
+		})
+	}
+}
@@ -5,9 +5,13 @@ package internal
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	// Import sqlite driver for database/sql.
 	_ "modernc.org/sqlite"
@@ -87,28 +91,87 @@ func TestDB(t *testing.T) *sql.DB {
 	return db
 }
 
+// TestDatabase creates a named shared-cache in-memory SQLite database for testing.
+// Unlike ":memory:", every pooled connection sees the same data, so queries behave
+// as they do against the on-disk database. The database is closed when the test ends.
+func TestDatabase(t *testing.T) *Database {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(t.Name()))
+	database, err := NewDatabase(dsn)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	return database
+}
+
 // TestRepository creates a repository with an in-memory database for testing.
 func TestRepository(t *testing.T) Repository {
 	db := TestDB(t)
 	return NewSQLiteRepository(db)
 }
 
+// FakeClock is a Clock for tests that only moves when told to.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements the Clock interface.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to now.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
 // Note: MockCommandHandler has been removed since commands are now
 // processed directly by modules in their HandleEvent implementation.
 
 // MockEventHandler is a function that can be used to mock an event handler.
+// HandleEnvelopeFunc, if set, receives events with their delivery metadata
+// instead of HandleEventFunc.
 type MockEventHandler struct {
-	HandleEventFunc func(eventType string, event any, raw []byte) error
+	HandleEventFunc    func(eventType string, event any, raw []byte) (bool, error)
+	HandleEnvelopeFunc func(env *EventEnvelope) (bool, error)
 }
 
 // HandleEvent implements the Module interface.
-func (m *MockEventHandler) HandleEvent(eventType string, event any, raw []byte) error {
+func (m *MockEventHandler) HandleEvent(eventType string, event any, raw json.RawMessage) (bool, error) {
 	if m.HandleEventFunc == nil {
-		return nil
+		return false, nil
 	}
 	return m.HandleEventFunc(eventType, event, raw)
 }
 
+// HandleEnvelope implements the ModuleEnvelopeHandler interface.
+func (m *MockEventHandler) HandleEnvelope(env *EventEnvelope) (bool, error) {
+	if m.HandleEnvelopeFunc == nil {
+		return m.HandleEvent(env.Type, env.Event, env.Raw)
+	}
+	return m.HandleEnvelopeFunc(env)
+}
+
 // MockModule is a mock implementation of the Module interface for testing.
 type MockModule struct {
 	MockEventHandler
@@ -166,6 +229,7 @@ func CreateTestWebhookPayload(eventType string, options map[string]interface{})
 		}
 		payload.Comment = map[string]interface{}{
 			"body": options["comment"].(string),
+			"user": payload.Sender,
 		}
 	case "pull_request":
 		payload.PullRequest = map[string]interface{}{
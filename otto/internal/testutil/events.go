@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package testutil provides fixtures and a test harness for exercising otto
+// end to end: GitHub events, signed webhook requests, a fake GitHub API and
+// an app wired with a module registry and an in-memory database.
+package testutil
+
+import (
+	"time"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// newRepository returns a repository with the given "owner/name" full name.
+func newRepository(repo string) *github.Repository {
+	return &github.Repository{FullName: github.Ptr(repo)}
+}
+
+// NewIssueCommentEvent builds the event for a comment by user on an issue.
+func NewIssueCommentEvent(repo string, issueNum int, body, user string) *github.IssueCommentEvent {
+	return &github.IssueCommentEvent{
+		Action: github.Ptr("created"),
+		Repo:   newRepository(repo),
+		Issue:  &github.Issue{Number: github.Ptr(issueNum)},
+		Comment: &github.IssueComment{
+			Body: github.Ptr(body),
+			User: &github.User{Login: github.Ptr(user)},
+		},
+		Sender: &github.User{Login: github.Ptr(user)},
+	}
+}
+
+// NewIssuesEvent builds an issues event, such as "opened" or "labeled", for
+// an issue opened by user.
+func NewIssuesEvent(repo string, issueNum int, action, user string) *github.IssuesEvent {
+	return &github.IssuesEvent{
+		Action: github.Ptr(action),
+		Repo:   newRepository(repo),
+		Issue: &github.Issue{
+			Number: github.Ptr(issueNum),
+			User:   &github.User{Login: github.Ptr(user)},
+		},
+		Sender: &github.User{Login: github.Ptr(user)},
+	}
+}
+
+// NewPullRequestEvent builds a pull_request event, such as "opened", for a
+// pull request opened by user.
+func NewPullRequestEvent(repo string, prNum int, action, user string) *github.PullRequestEvent {
+	return &github.PullRequestEvent{
+		Action: github.Ptr(action),
+		Number: github.Ptr(prNum),
+		Repo:   newRepository(repo),
+		PullRequest: &github.PullRequest{
+			Number: github.Ptr(prNum),
+			User:   &github.User{Login: github.Ptr(user)},
+		},
+		Sender: &github.User{Login: github.Ptr(user)},
+	}
+}
+
+// NewPullRequestReviewEvent builds the event for a review submitted by user
+// with the given state, such as "approved", and body.
+func NewPullRequestReviewEvent(repo string, prNum int, state, body, user string) *github.PullRequestReviewEvent {
+	return &github.PullRequestReviewEvent{
+		Action:      github.Ptr("submitted"),
+		Repo:        newRepository(repo),
+		PullRequest: &github.PullRequest{Number: github.Ptr(prNum)},
+		Review: &github.PullRequestReview{
+			State: github.Ptr(state),
+			Body:  github.Ptr(body),
+			User:  &github.User{Login: github.Ptr(user)},
+		},
+		Sender: &github.User{Login: github.Ptr(user)},
+	}
+}
+
+// newInstallation returns an installation on the organization account,
+// suspended when suspended is set.
+func newInstallation(id int64, account string, suspended bool) *github.Installation {
+	inst := &github.Installation{
+		ID: github.Ptr(id),
+		Account: &github.User{
+			Login: github.Ptr(account),
+			Type:  github.Ptr("Organization"),
+		},
+	}
+	if suspended {
+		inst.SuspendedAt = &github.Timestamp{Time: time.Now()}
+	}
+	return inst
+}
+
+// newRepositories returns repositories with the given full names.
+func newRepositories(repos []string) []*github.Repository {
+	var list []*github.Repository
+	for _, r := range repos {
+		list = append(list, newRepository(r))
+	}
+	return list
+}
+
+// NewInstallationEvent builds an installation event, such as "created" or
+// "deleted", for the app installed on an organization's repositories. A
+// "suspend" event's installation is suspended.
+func NewInstallationEvent(id int64, account, action string, repos ...string) *github.InstallationEvent {
+	return &github.InstallationEvent{
+		Action:       github.Ptr(action),
+		Installation: newInstallation(id, account, action == "suspend"),
+		Repositories: newRepositories(repos),
+		Sender:       &github.User{Login: github.Ptr(account)},
+	}
+}
+
+// NewInstallationRepositoriesEvent builds the event for repositories added to
+// or removed from an installation on an organization.
+func NewInstallationRepositoriesEvent(
+	id int64,
+	account, action string,
+	added, removed []string,
+) *github.InstallationRepositoriesEvent {
+	return &github.InstallationRepositoriesEvent{
+		Action:              github.Ptr(action),
+		Installation:        newInstallation(id, account, false),
+		RepositorySelection: github.Ptr("selected"),
+		RepositoriesAdded:   newRepositories(added),
+		RepositoriesRemoved: newRepositories(removed),
+		Sender:              &github.User{Login: github.Ptr(account)},
+	}
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// GitHubRecorder is a fake GitHub API that records posted and edited issue
+// comments. Comment IDs are their 1-based position in the posted comments.
+// Other API calls are not found.
+type GitHubRecorder struct {
+	// Delay slows every request, to observe how many run at once.
+	Delay time.Duration
+
+	mu          sync.Mutex
+	comments    []string
+	edits       []string
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (g *GitHubRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.Delay > 0 {
+		n := g.inFlight.Add(1)
+		defer g.inFlight.Add(-1)
+		for {
+			m := g.maxInFlight.Load()
+			if n <= m || g.maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(g.Delay)
+	}
+
+	isCreate := r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comments")
+	isEdit := r.Method == http.MethodPatch && strings.Contains(r.URL.Path, "/issues/comments/")
+	if !isCreate && !isEdit {
+		http.NotFound(w, r)
+		return
+	}
+	var comment github.IssueComment
+	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if isEdit {
+		id, err := strconv.Atoi(path.Base(r.URL.Path))
+		if err != nil || id < 1 || id > len(g.comments) {
+			http.NotFound(w, r)
+			return
+		}
+		g.edits = append(g.edits, comment.GetBody())
+		comment.ID = github.Ptr(int64(id))
+		_ = json.NewEncoder(w).Encode(comment)
+		return
+	}
+	g.comments = append(g.comments, comment.GetBody())
+	comment.ID = github.Ptr(int64(len(g.comments)))
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(comment)
+}
+
+// Comments returns the bodies of all comments posted so far.
+func (g *GitHubRecorder) Comments() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.comments...)
+}
+
+// Edits returns the new bodies of all comments edited so far.
+func (g *GitHubRecorder) Edits() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.edits...)
+}
+
+// MaxInFlight returns the most requests handled at once while Delay was set.
+func (g *GitHubRecorder) MaxInFlight() int {
+	return int(g.maxInFlight.Load())
+}
+
+// NewGitHubClient returns a GitHub client for a fake API served by handler,
+// such as a GitHubRecorder, until the test ends.
+func NewGitHubClient(t *testing.T, handler http.Handler) *github.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client := github.NewClient(srv.Client())
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return client
+}
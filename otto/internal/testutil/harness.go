@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package testutil
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/secrets"
+)
+
+// DefaultSecret is the webhook secret of a Harness.
+const DefaultSecret = "test-secret"
+
+// Harness is an app wired for tests: an empty module registry, an in-memory
+// database, a fake GitHub API and the webhook server. Webhooks are dispatched
+// synchronously, so modules have handled an event once Deliver returns.
+type Harness struct {
+	App    *internal.App
+	GitHub *GitHubRecorder
+	// Secret signs the webhooks of Deliver. The server always verifies
+	// DefaultSecret, so changing it makes deliveries fail verification.
+	Secret string
+
+	server *internal.Server
+}
+
+// NewHarness creates a Harness whose resources are released when the test ends.
+func NewHarness(t *testing.T) *Harness {
+	t.Helper()
+
+	cfg := &config.AppConfig{SyncDispatch: true}
+	config.ApplyDefaults(cfg)
+	recorder := &GitHubRecorder{}
+	telemetry := internal.NewNoopTelemetryManager()
+	app := &internal.App{
+		Config:         cfg,
+		Database:       internal.TestDatabase(t),
+		Telemetry:      telemetry,
+		Logger:         slog.Default(),
+		GitHubClient:   NewGitHubClient(t, recorder),
+		ModuleRegistry: internal.NewModuleRegistryWithTelemetry(telemetry),
+	}
+	return &Harness{
+		App:    app,
+		GitHub: recorder,
+		Secret: DefaultSecret,
+		server: internal.NewServerWithApp("0", secrets.NewFileManager(DefaultSecret, 0, 0, "", nil), app),
+	}
+}
+
+// Handler returns the app's HTTP handler, which verifies webhooks with
+// DefaultSecret.
+func (h *Harness) Handler() http.Handler {
+	return h.server.Handler()
+}
+
+// Deliver signs event with the harness secret and posts it to the webhook
+// endpoint, returning the response once registered modules have handled it.
+func (h *Harness) Deliver(t *testing.T, eventType string, event any) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.Handler().ServeHTTP(rr, NewWebhookRequest(t, h.App.Config.WebhookPath, h.Secret, eventType, event))
+	return rr
+}
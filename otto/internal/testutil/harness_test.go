@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package testutil
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+)
+
+func TestHarnessDeliver(t *testing.T) {
+	event := NewIssueCommentEvent("org/repo", 7, "/ack", "octocat")
+	tests := []struct {
+		name         string
+		secret       string
+		event        any
+		wantCode     int
+		wantDispatch bool
+	}{
+		{"signed event", DefaultSecret, event, http.StatusOK, true},
+		{"wrong secret", "wrong", event, http.StatusUnauthorized, false},
+		{"malformed body", DefaultSecret, []byte(`{"action":`), http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHarness(t)
+			var got *github.IssueCommentEvent
+			mod := internal.NewMockModule("recorder")
+			mod.HandleEventFunc = func(_ string, event any, _ []byte) (bool, error) {
+				got, _ = event.(*github.IssueCommentEvent)
+				return true, nil
+			}
+			h.App.RegisterModule(mod)
+
+			h.Secret = tt.secret
+			if rr := h.Deliver(t, "issue_comment", tt.event); rr.Code != tt.wantCode {
+				t.Fatalf("status code: got %v want %v", rr.Code, tt.wantCode)
+			}
+			if (got != nil) != tt.wantDispatch {
+				t.Fatalf("dispatched: got %v want %v", got != nil, tt.wantDispatch)
+			}
+			if got != nil && (got.GetComment().GetBody() != "/ack" || got.GetRepo().GetFullName() != "org/repo") {
+				t.Errorf("unexpected event %+v", got)
+			}
+		})
+	}
+}
+
+func TestGitHubRecorder(t *testing.T) {
+	recorder := &GitHubRecorder{}
+	client := NewGitHubClient(t, recorder)
+
+	comment, _, err := client.Issues.CreateComment(t.Context(), "org", "repo", 1, &github.IssueComment{
+		Body: github.Ptr("hello"),
+	})
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	if _, _, err := client.Issues.EditComment(t.Context(), "org", "repo", comment.GetID(), &github.IssueComment{
+		Body: github.Ptr("edited"),
+	}); err != nil {
+		t.Fatalf("EditComment failed: %v", err)
+	}
+
+	if got := recorder.Comments(); len(got) != 1 || got[0] != "hello" {
+		t.Errorf("comments: got %q want [hello]", got)
+	}
+	if got := recorder.Edits(); len(got) != 1 || got[0] != "edited" {
+		t.Errorf("edits: got %q want [edited]", got)
+	}
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+)
+
+// SignPayload returns the X-Hub-Signature-256 header GitHub sends for body
+// with the given webhook secret.
+func SignPayload(secret, body []byte) string {
+	return internal.SignWebhookPayload(secret, body)
+}
+
+// deliveries numbers the delivery IDs of webhook requests.
+var deliveries atomic.Int64
+
+// Payload returns event encoded as a webhook body. A []byte event is used
+// as is, which allows malformed bodies.
+func Payload(t *testing.T, event any) []byte {
+	t.Helper()
+	if body, ok := event.([]byte); ok {
+		return body
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to encode %T: %v", event, err)
+	}
+	return body
+}
+
+// NewWebhookRequest builds a webhook request to target for event, signed
+// with secret, with a unique delivery ID.
+func NewWebhookRequest(t *testing.T, target, secret, eventType string, event any) *http.Request {
+	t.Helper()
+	body := Payload(t, event)
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", eventType)
+	req.Header.Set("X-GitHub-Delivery", fmt.Sprintf("delivery-%d", deliveries.Add(1)))
+	req.Header.Set("X-Hub-Signature-256", SignPayload([]byte(secret), body))
+	return req
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// validate.go checks at startup that otto's dependencies are usable.
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/secrets"
+)
+
+// Validate checks the configuration, secrets, registered modules, database
+// and GitHub credentials. It reports every problem found as a single joined
+// error rather than stopping at the first one.
+func (a *App) Validate(ctx context.Context) error {
+	err := a.ValidateOffline(ctx)
+	if ghErr := a.CheckGitHubAuth(ctx); ghErr != nil {
+		err = errors.Join(err, fmt.Errorf("github: %w", ghErr))
+	}
+	return err
+}
+
+// ValidateOffline runs the checks of Validate that don't call GitHub, such as
+// to check a deployment's configuration in CI. The database check is a dry
+// run that leaves the database unchanged.
+func (a *App) ValidateOffline(ctx context.Context) error {
+	var errs []error
+
+	if a.Config == nil {
+		errs = append(errs, errors.New("config: not loaded"))
+	} else if err := config.Validate(a.Config); err != nil {
+		errs = append(errs, fmt.Errorf("config: %w", err))
+	}
+
+	if a.Secrets == nil {
+		errs = append(errs, errors.New("secrets: not loaded"))
+	} else if err := secrets.Validate(a.Secrets); err != nil {
+		errs = append(errs, fmt.Errorf("secrets: %w", err))
+	}
+
+	if a.Config != nil && a.ModuleRegistry != nil {
+		if err := a.validateModules(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := a.validateDatabase(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("database: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateDatabase checks that the database is reachable and that migrations
+// will be able to write to it.
+func (a *App) validateDatabase(ctx context.Context) error {
+	if a.Database == nil || a.Database.DB() == nil {
+		return errors.New("not initialized")
+	}
+	db := a.Database.DB()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+
+	// Creating a table inside a rolled back transaction proves write access
+	// without leaving anything behind
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE otto_startup_check (id INTEGER)`); err != nil {
+		return fmt.Errorf("not writable: %w", err)
+	}
+	return nil
+}
+
+// CheckGitHubAuth verifies that the configured GitHub App credentials are
+// accepted by GitHub. It does nothing when no credentials are configured.
+func (a *App) CheckGitHubAuth(ctx context.Context) error {
+	if a.GitHubClient == nil {
+		return errors.New("client not initialized")
+	}
+	if a.Secrets == nil || a.Secrets.GetGitHubAppID() <= 0 {
+		return nil
+	}
+
+	if _, _, err := a.GitHubClient.Apps.ListRepos(ctx, &github.ListOptions{PerPage: 1}); err != nil {
+		return fmt.Errorf("authentication check failed: %w", err)
+	}
+	return nil
+}
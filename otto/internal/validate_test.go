@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/secrets"
+)
+
+// newGitHubTestClient returns a GitHub client whose API calls answer with status.
+func newGitHubTestClient(t *testing.T, status int) *github.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"total_count": 0, "repositories": []}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := github.NewClient(srv.Client())
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return client
+}
+
+func TestValidateOffline(t *testing.T) {
+	tests := []struct {
+		name     string
+		module   Module
+		wantErrs []string
+	}{
+		{name: "valid", module: &validatingModule{mockModule: mockModule{name: "a"}}},
+		{
+			name:     "module rejects its configuration",
+			module:   &validatingModule{mockModule: mockModule{name: "a"}, err: errors.New("bad threshold")},
+			wantErrs: []string{"module a: bad threshold"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.AppConfig{}
+			config.ApplyDefaults(cfg)
+			// No GitHub client, which Validate would report
+			app := &App{
+				Config:         cfg,
+				Secrets:        secrets.NewFileManager("webhook-secret", 0, 0, "", nil),
+				Database:       TestDatabase(t),
+				ModuleRegistry: NewModuleRegistry(),
+			}
+			app.RegisterModule(tt.module)
+
+			err := app.ValidateOffline(t.Context())
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %q, got nil", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	validSecrets := secrets.NewFileManager("webhook-secret", 0, 0, "", nil)
+	appSecrets := secrets.NewFileManager("webhook-secret", 1, 2, "", keyPEM)
+
+	readOnlyDB := func(t *testing.T) *Database {
+		path := filepath.Join(t.TempDir(), "otto.db")
+		writable, err := NewDatabase(path)
+		if err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		if _, err := writable.DB().Exec(`CREATE TABLE t (id INTEGER)`); err != nil {
+			t.Fatalf("failed to initialize database: %v", err)
+		}
+		writable.Close()
+
+		database, err := NewDatabase("file:" + path + "?mode=ro")
+		if err != nil {
+			t.Fatalf("failed to open read-only database: %v", err)
+		}
+		t.Cleanup(func() { database.Close() })
+		return database
+	}
+
+	tests := []struct {
+		name     string
+		secrets  secrets.Manager
+		database func(t *testing.T) *Database
+		status   int
+		wantErrs []string
+	}{
+		{
+			name:     "valid without GitHub App",
+			secrets:  validSecrets,
+			database: TestDatabase,
+		},
+		{
+			name:     "valid with GitHub App",
+			secrets:  appSecrets,
+			database: TestDatabase,
+			status:   http.StatusOK,
+		},
+		{
+			name:     "missing webhook secret",
+			secrets:  secrets.NewFileManager("", 0, 0, "", nil),
+			database: TestDatabase,
+			wantErrs: []string{"secrets: webhook secret is required"},
+		},
+		{
+			name:     "malformed private key",
+			secrets:  secrets.NewFileManager("webhook-secret", 1, 2, "", []byte("key")),
+			database: TestDatabase,
+			status:   http.StatusOK,
+			wantErrs: []string{"secrets: invalid GitHub App private key"},
+		},
+		{
+			name:     "missing database",
+			secrets:  validSecrets,
+			database: func(*testing.T) *Database { return nil },
+			wantErrs: []string{"database: not initialized"},
+		},
+		{
+			name:     "read-only database",
+			secrets:  validSecrets,
+			database: readOnlyDB,
+			wantErrs: []string{"database: not writable"},
+		},
+		{
+			name:     "GitHub rejects credentials",
+			secrets:  appSecrets,
+			database: TestDatabase,
+			status:   http.StatusUnauthorized,
+			wantErrs: []string{"github: authentication check failed"},
+		},
+		{
+			name:     "all problems are reported",
+			secrets:  secrets.NewFileManager("", 1, 2, "", []byte("key")),
+			database: func(*testing.T) *Database { return nil },
+			status:   http.StatusUnauthorized,
+			wantErrs: []string{
+				"secrets: webhook secret is required",
+				"database: not initialized",
+				"github: authentication check failed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.AppConfig{}
+			config.ApplyDefaults(cfg)
+			app := &App{
+				Config:       cfg,
+				Secrets:      tt.secrets,
+				Database:     tt.database(t),
+				GitHubClient: newGitHubTestClient(t, tt.status),
+			}
+
+			err := app.Validate(t.Context())
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %q, got nil", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package modules
+
+import "github.com/open-telemetry/sig-project-infra/otto/internal"
+
+// All returns a new instance of every module otto runs, for registering with
+// an app or checking their configuration.
+func All() []internal.Module {
+	return []internal.Module{
+		&OnCallModule{},
+		&InstallationsModule{},
+	}
+}
@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+)
+
+// InstallationsModule records the GitHub App installations otto receives
+// events for, mapping each installation ID to the account it is installed
+// on, and logs repositories being added to or removed from them.
+type InstallationsModule struct {
+	database *internal.Database
+	// clock is the source of the current time; nil means the system clock.
+	clock internal.Clock
+	// logger tags every line with the module name; nil means slog.Default().
+	logger *slog.Logger
+}
+
+// Installation is a GitHub App installation on a user or organization.
+type Installation struct {
+	ID int64
+	// Account is the login of the user or organization.
+	Account string
+	// AccountType is "User" or "Organization".
+	AccountType string
+	// Suspended is set while the installation is suspended.
+	Suspended bool
+	UpdatedAt time.Time
+}
+
+func (m *InstallationsModule) Name() string { return "installations" }
+
+// InterestedEvents implements the ModuleEventFilter interface.
+func (m *InstallationsModule) InterestedEvents() []string {
+	return []string{"installation", "installation_repositories"}
+}
+
+// Initialize implements the ModuleInitializer interface.
+func (m *InstallationsModule) Initialize(_ context.Context, app *internal.App) error {
+	m.database = app.Database
+	if app.Logger != nil {
+		m.logger = app.Logger.With("module", m.Name())
+	}
+	if m.database == nil {
+		m.log().Warn("No database available; installations will only be logged")
+		return nil
+	}
+	return AutoMigrateInstallations(m.database.DB())
+}
+
+// now returns the current time from the module's clock.
+func (m *InstallationsModule) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock.Now()
+}
+
+// log returns the module's logger.
+func (m *InstallationsModule) log() *slog.Logger {
+	if m.logger == nil {
+		return slog.Default().With("module", m.Name())
+	}
+	return m.logger
+}
+
+// HandleEvent records installation changes. Every installation event is
+// acknowledged as handled, so that none is counted as unhandled.
+func (m *InstallationsModule) HandleEvent(eventType string, event any, _ json.RawMessage) (bool, error) {
+	switch e := event.(type) {
+	case *github.InstallationEvent:
+		return true, m.handleInstallation(e)
+	case *github.InstallationRepositoriesEvent:
+		return true, m.handleInstallationRepositories(e)
+	}
+	m.log().Debug("Ignoring unexpected event", "event_type", eventType)
+	return false, nil
+}
+
+// handleInstallation records an installation being created, suspended,
+// unsuspended or changed, and forgets it once deleted.
+func (m *InstallationsModule) handleInstallation(event *github.InstallationEvent) error {
+	inst := event.GetInstallation()
+	logger := m.log().With(
+		"installation_id", inst.GetID(),
+		"account", inst.GetAccount().GetLogin(),
+		"action", event.GetAction())
+	logger.Info("GitHub App installation changed",
+		"repositories", repositoryNames(event.Repositories),
+		"sender", event.GetSender().GetLogin())
+	if m.database == nil {
+		return nil
+	}
+
+	if event.GetAction() == "deleted" {
+		if err := DeleteInstallation(m.database.DB(), inst.GetID()); err != nil {
+			return LogAndWrapError(err, ErrorTypeCommand, "delete_installation", map[string]any{
+				"installation_id": inst.GetID(),
+			})
+		}
+		return nil
+	}
+	return m.recordInstallation(inst)
+}
+
+// handleInstallationRepositories logs repositories added to or removed from
+// an installation and records the installation.
+func (m *InstallationsModule) handleInstallationRepositories(event *github.InstallationRepositoriesEvent) error {
+	inst := event.GetInstallation()
+	m.log().Info("GitHub App installation repositories changed",
+		"installation_id", inst.GetID(),
+		"account", inst.GetAccount().GetLogin(),
+		"selection", event.GetRepositorySelection(),
+		"added", repositoryNames(event.RepositoriesAdded),
+		"removed", repositoryNames(event.RepositoriesRemoved))
+	if m.database == nil {
+		return nil
+	}
+	return m.recordInstallation(inst)
+}
+
+// recordInstallation stores an installation's account and whether it is
+// suspended.
+func (m *InstallationsModule) recordInstallation(inst *github.Installation) error {
+	err := RecordInstallation(m.database.DB(), Installation{
+		ID:          inst.GetID(),
+		Account:     inst.GetAccount().GetLogin(),
+		AccountType: inst.GetAccount().GetType(),
+		Suspended:   inst.SuspendedAt != nil,
+		UpdatedAt:   m.now(),
+	})
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "record_installation", map[string]any{
+			"installation_id": inst.GetID(),
+		})
+	}
+	return nil
+}
+
+// repositoryNames returns the full names of repositories.
+func repositoryNames(repos []*github.Repository) []string {
+	names := make([]string, 0, len(repos))
+	for _, r := range repos {
+		names = append(names, r.GetFullName())
+	}
+	return names
+}
+
+// AutoMigrateInstallations creates the installations table.
+func AutoMigrateInstallations(db *sql.DB) error {
+	stmt := `CREATE TABLE IF NOT EXISTS installations (
+		id INTEGER PRIMARY KEY,
+		account TEXT NOT NULL,
+		account_type TEXT NOT NULL DEFAULT '',
+		suspended BOOLEAN NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP NOT NULL
+	);`
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("failed migration: %w (SQL: %s)", err, stmt)
+	}
+	return nil
+}
+
+// RecordInstallation adds an installation or updates its account and state.
+func RecordInstallation(db *sql.DB, inst Installation) error {
+	_, err := db.Exec(
+		`INSERT INTO installations (id, account, account_type, suspended, updated_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET
+		   account = excluded.account, account_type = excluded.account_type,
+		   suspended = excluded.suspended, updated_at = excluded.updated_at`,
+		inst.ID,
+		inst.Account,
+		inst.AccountType,
+		inst.Suspended,
+		formatDBTime(inst.UpdatedAt),
+	)
+	return err
+}
+
+// DeleteInstallation forgets an installation. Deleting an unknown
+// installation is not an error.
+func DeleteInstallation(db *sql.DB, id int64) error {
+	_, err := db.Exec(`DELETE FROM installations WHERE id = ?`, id)
+	return err
+}
+
+// GetInstallation returns an installation, or nil if it isn't recorded.
+func GetInstallation(db *sql.DB, id int64) (*Installation, error) {
+	var inst Installation
+	err := db.QueryRow(
+		`SELECT id, account, account_type, suspended, updated_at FROM installations WHERE id = ?`,
+		id,
+	).Scan(&inst.ID, &inst.Account, &inst.AccountType, &inst.Suspended, dbTime{&inst.UpdatedAt})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &inst, nil
+}
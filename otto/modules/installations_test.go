@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package modules
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/testutil"
+)
+
+// newInstallationsHarness registers an installations module with a harness.
+func newInstallationsHarness(t *testing.T) *testutil.Harness {
+	t.Helper()
+	h := testutil.NewHarness(t)
+	if err := AutoMigrateInstallations(h.App.Database.DB()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	h.App.RegisterModule(&InstallationsModule{
+		database: h.App.Database,
+		clock:    internal.NewFakeClock(time.Now()),
+	})
+	return h
+}
+
+func TestInstallationEvents(t *testing.T) {
+	h := newInstallationsHarness(t)
+	db := h.App.Database.DB()
+
+	steps := []struct {
+		name          string
+		eventType     string
+		event         any
+		wantInstalled bool
+		wantSuspended bool
+	}{
+		{
+			"created", "installation",
+			testutil.NewInstallationEvent(42, "open-telemetry", "created", "open-telemetry/otto"),
+			true, false,
+		},
+		{
+			"repositories added", "installation_repositories",
+			testutil.NewInstallationRepositoriesEvent(42, "open-telemetry", "added", []string{"open-telemetry/x"}, nil),
+			true, false,
+		},
+		{"suspended", "installation", testutil.NewInstallationEvent(42, "open-telemetry", "suspend"), true, true},
+		{"unsuspended", "installation", testutil.NewInstallationEvent(42, "open-telemetry", "unsuspend"), true, false},
+		{"deleted", "installation", testutil.NewInstallationEvent(42, "open-telemetry", "deleted"), false, false},
+	}
+	for _, step := range steps {
+		// No module other than installations handles these events, so 200
+		// rather than 204 shows they weren't left unhandled
+		if rr := h.Deliver(t, step.eventType, step.event); rr.Code != http.StatusOK {
+			t.Fatalf("%s: status code: got %v want %v", step.name, rr.Code, http.StatusOK)
+		}
+
+		inst, err := GetInstallation(db, 42)
+		if err != nil {
+			t.Fatalf("%s: GetInstallation failed: %v", step.name, err)
+		}
+		if (inst != nil) != step.wantInstalled {
+			t.Fatalf("%s: installed: want %v, got %+v", step.name, step.wantInstalled, inst)
+		}
+		if inst == nil {
+			continue
+		}
+		if inst.Account != "open-telemetry" || inst.AccountType != "Organization" {
+			t.Errorf("%s: account: got %q (%q)", step.name, inst.Account, inst.AccountType)
+		}
+		if inst.Suspended != step.wantSuspended {
+			t.Errorf("%s: suspended: want %v, got %v", step.name, step.wantSuspended, inst.Suspended)
+		}
+	}
+}
@@ -5,14 +5,25 @@ package modules
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v71/github"
 	"github.com/open-telemetry/sig-project-infra/otto/internal"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/cache"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Import internal types for error handling.
@@ -25,32 +36,183 @@ var (
 type OnCallModule struct {
 	app      *internal.App
 	database *internal.Database
+	config   OnCallConfig
+	// clock is the source of the current time; nil means the system clock.
+	clock     internal.Clock
+	telemetry *internal.TelemetryManager
+	cooldown  commandCooldown
+	// deliveries makes handling idempotent across webhook redeliveries.
+	deliveries deliveryLog
+	// logger tags every line with the module name; nil means slog.Default().
+	logger *slog.Logger
+
+	// cachesOnce creates the caches below on first use, once the
+	// configuration is loaded.
+	cachesOnce     sync.Once
+	repoSettings   *cache.TTL[string, *OnCallRepoSetting]
+	maintainerTeam *cache.TTL[string, map[string]bool]
+	schedules      *cache.TTL[string, *OnCallSchedule]
+	// maintainerTeamMu serializes listing the maintainer team, so that
+	// lookups while the cache is empty share one request.
+	maintainerTeamMu sync.Mutex
+
+	// stopChecks ends the escalation check loop, which closes checksDone.
+	stopChecks context.CancelFunc
+	checksDone chan struct{}
+}
+
+// now returns the current time from the module's clock.
+func (o *OnCallModule) now() time.Time {
+	if o.clock == nil {
+		return time.Now()
+	}
+	return o.clock.Now()
+}
+
+// initCaches creates the module's caches from its configuration, the first
+// time it is called.
+func (o *OnCallModule) initCaches() {
+	o.cachesOnce.Do(func() {
+		o.repoSettings = cache.New[string, *OnCallRepoSetting](0, 0, o.clock)
+		o.maintainerTeam = cache.New[string, map[string]bool](o.config.MaintainerTeamCacheTTL(), 1, o.clock)
+		o.schedules = cache.New[string, *OnCallSchedule](
+			o.config.ScheduleCacheTTL, o.config.ScheduleCacheEntries(), o.clock)
+	})
+}
+
+// log returns the module's logger.
+func (o *OnCallModule) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.Default().With("module", o.Name())
+	}
+	return o.logger
+}
+
+// issueLogger returns the module's logger scoped to one issue or pull request.
+func (o *OnCallModule) issueLogger(repo string, issueNum int) *slog.Logger {
+	return o.log().With("repo", repo, "issue_num", issueNum)
+}
+
+// eventLogger returns the module's logger scoped to the repository and the
+// issue or pull request an event refers to, as far as the event has them.
+func (o *OnCallModule) eventLogger(event any) *slog.Logger {
+	logger := o.log()
+	if e, ok := event.(interface{ GetRepo() *github.Repository }); ok {
+		logger = logger.With("repo", e.GetRepo().GetFullName())
+	}
+	switch e := event.(type) {
+	case interface{ GetIssue() *github.Issue }:
+		logger = logger.With("issue_num", e.GetIssue().GetNumber())
+	case interface{ GetPullRequest() *github.PullRequest }:
+		logger = logger.With("issue_num", e.GetPullRequest().GetNumber())
+	}
+	return logger
 }
 
 func (o *OnCallModule) Name() string { return "oncall" }
 
+// Commands implements the ModuleCommander interface.
+func (o *OnCallModule) Commands() []string { return commandWords }
+
+// InterestedEvents implements the ModuleEventFilter interface. It lists only
+// the events HandleEvent acts on. Comments on pull requests arrive as
+// issue_comment, and commands in review bodies as pull_request_review, so
+// pull_request and pull_request_review_comment would only be dispatched to
+// be ignored.
+func (o *OnCallModule) InterestedEvents() []string {
+	return []string{"issues", "issue_comment", "pull_request_review"}
+}
+
+// ValidateConfig implements the ModuleValidator interface.
+func (o *OnCallModule) ValidateConfig(cfg *config.AppConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	_, err := LoadOnCallConfig(cfg.Modules)
+	return err
+}
+
+// noopTelemetry is used by modules running without configured telemetry.
+var noopTelemetry = sync.OnceValue(internal.NewNoopTelemetryManager)
+
 // Initialize implements the ModuleInitializer interface.
 func (o *OnCallModule) Initialize(ctx context.Context, app *internal.App) error {
 	o.app = app
 	o.database = app.Database
+	o.telemetry = app.Telemetry
+	if o.telemetry == nil {
+		o.telemetry = noopTelemetry()
+	}
+	if app.Logger != nil {
+		o.logger = app.Logger.With("module", o.Name())
+	}
+
+	// Load module configuration
+	if app.Config != nil {
+		cfg, err := LoadOnCallConfig(app.Config.Modules)
+		if err != nil {
+			return err
+		}
+		o.config = cfg
+	}
+	if app.GitHubClient == nil && !o.config.ReadOnly {
+		o.log().Warn("No GitHub client available; oncall comments will only be logged")
+	}
 
 	// Initialize database tables
 	if err := AutoMigrateOnCall(o.database.DB()); err != nil {
 		return err
 	}
+	if err := SeedSchedules(o.database.DB(), o.config.Seed); err != nil {
+		return err
+	}
+	// Seeding may have created schedules that were looked up and cached as
+	// missing
+	for _, seed := range o.config.Seed {
+		o.scheduleCache().Invalidate(seed.Name)
+	}
 
-	// Start a ticker to check unacknowledged tasks every minute
+	// Report task counts per status for dashboards
+	o.telemetry.RegisterCommands(o.Name(), oncallCommands...)
+	if err := o.registerTaskGauge(o.telemetry.Meter()); err != nil {
+		return err
+	}
+
+	// Start a ticker to check unacknowledged tasks every minute until the
+	// context ends or the module shuts down, replacing the loop of an earlier
+	// Initialize
+	if err := o.Shutdown(ctx); err != nil {
+		return err
+	}
+	checkCtx, stop := context.WithCancel(ctx)
+	o.stopChecks = stop
+	o.checksDone = make(chan struct{})
 	go func() {
+		defer close(o.checksDone)
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
 
 		for {
 			select {
-			case <-ctx.Done():
+			case <-checkCtx.Done():
 				return
 			case <-ticker.C:
+				if !o.waitJitter(checkCtx) {
+					return
+				}
 				if err := o.CheckUnacknowledgedTasks(); err != nil {
-					slog.Error("Error checking unacknowledged tasks", "error", err)
+					o.log().Error("Error checking unacknowledged tasks", "error", err)
+				}
+				if err := o.AdvanceRotations(); err != nil {
+					o.log().Error("Error advancing rotations", "error", err)
+				}
+				if _, err := PruneDeliveries(o.database.DB(), o.now().Add(-deliveryRetention)); err != nil {
+					o.log().Error("Error pruning recorded deliveries", "error", err)
+				}
+				if window := o.config.CommandCooldownWindow(); o.config.PersistCommandCooldown && window > 0 {
+					if _, err := PruneCommandRuns(o.database.DB(), o.now().Add(-window)); err != nil {
+						o.log().Error("Error pruning recorded command runs", "error", err)
+					}
 				}
 			}
 		}
@@ -59,6 +221,46 @@ func (o *OnCallModule) Initialize(ctx context.Context, app *internal.App) error
 	return nil
 }
 
+// registerTaskGauge exposes the number of tasks per status as an observable gauge.
+func (o *OnCallModule) registerTaskGauge(meter metric.Meter) error {
+	_, err := meter.Int64ObservableGauge(
+		"otto.oncall.tasks",
+		metric.WithDescription("On-call tasks by status"),
+		metric.WithInt64Callback(func(_ context.Context, obs metric.Int64Observer) error {
+			counts, err := CountTasksByStatus(o.database.DB())
+			if err != nil {
+				return err
+			}
+			for status, count := range counts {
+				obs.Observe(int64(count), metric.WithAttributes(attribute.String("status", status)))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create task gauge: %w", err)
+	}
+	return nil
+}
+
+// AddTask stores a new open task like the AddTask store function, logging
+// and counting a rejected repository name.
+func (o *OnCallModule) AddTask(
+	scheduleID int64,
+	repo string,
+	issueNum int,
+	title, description string,
+	assignedTo int64,
+) (*OnCallTask, error) {
+	task, err := AddTask(o.database.DB(), scheduleID, repo, issueNum, title, description, assignedTo)
+	var repoErr *InvalidRepositoryError
+	if errors.As(err, &repoErr) {
+		o.log().Warn("Invalid repository name", "error", err)
+		o.metrics().IncInvalidRepository(context.Background(), o.Name())
+	}
+	return task, err
+}
+
 func (o *OnCallModule) AcknowledgeTask(repo string, issueNum int, user string) error {
 	// Find the task
 	task, err := GetTaskByIssueNumber(o.database.DB(), repo, issueNum)
@@ -75,118 +277,441 @@ func (o *OnCallModule) AcknowledgeTask(repo string, issueNum int, user string) e
 		return fmt.Errorf("failed to update task status: %w", err)
 	}
 
+	if err := MarkUserActive(o.database.DB(), user, o.now()); err != nil {
+		return fmt.Errorf("failed to record user activity: %w", err)
+	}
+
 	return nil
 }
 
+// metrics returns the module's telemetry, which is a no-op before Initialize.
+func (o *OnCallModule) metrics() *internal.TelemetryManager {
+	if o.telemetry == nil {
+		return noopTelemetry()
+	}
+	return o.telemetry
+}
+
+// startSpan starts a span with the module's tracer.
+func (o *OnCallModule) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return o.metrics().Tracer().Start(ctx, name)
+}
+
+// defaultEscalationTiers apply to schedules without configured escalation tiers.
+var defaultEscalationTiers = []OnCallEscalationTier{
+	{Level: 1, Target: "@org/oncall-team, @org/leadership", After: 24 * time.Hour},
+}
+
+// CheckUnacknowledgedTasks escalates open tasks to the next tier of their
+// schedule's escalation chain once that tier's threshold has passed.
 func (o *OnCallModule) CheckUnacknowledgedTasks() error {
-	// Query for unacknowledged tasks older than 24 hours
-	rows, err := o.database.DB().Query(`
-		SELECT id, repo, issue_num, assigned_to, created_at
-		FROM oncall_tasks
-		WHERE status != 'ack'
-		AND created_at < datetime('now', '-24 hours')
-	`)
+	_, span := o.startSpan(context.Background(), "oncall.escalation_check")
+	defer span.End()
+
+	db := o.database.DB()
+	now := o.now()
+
+	// Only tasks older than the shortest tier delay can be due for escalation
+	minDelay := defaultEscalationTiers[0].After
+	shortest, configured, err := ShortestEscalationDelay(db)
 	if err != nil {
-		return fmt.Errorf("failed to query unacknowledged tasks: %w", err)
+		span.RecordError(err)
+		return fmt.Errorf("failed to query escalation tiers: %w", err)
+	}
+	if configured && shortest < minDelay {
+		minDelay = shortest
 	}
-	defer rows.Close()
-
-	// Process each unacknowledged task
-	for rows.Next() {
-		var taskID int64
-		var repo string
-		var issueNum int
-		var assignedToID int64
-		var createdAt time.Time
 
-		if err := rows.Scan(&taskID, &repo, &issueNum, &assignedToID, &createdAt); err != nil {
-			slog.Error("Failed to scan task row", "error", err)
+	tasks, err := FindUnacknowledgedTasksOlderThan(db, now.Add(-minDelay))
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to query unacknowledged tasks: %w", err)
+	}
+	span.SetAttributes(attribute.Int("oncall.aged_tasks", len(tasks)))
+
+	// Group the tasks by repository, loading each schedule's tiers once
+	tiersBySchedule := make(map[int64][]OnCallEscalationTier)
+	tasksByRepo := make(map[string][]*OnCallTask)
+	var repos []string
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Snoozed(now) {
 			continue
 		}
 
-		// Notify about escalation
-		err = o.EscalateTask(taskID, repo, issueNum)
-		if err != nil {
-			slog.Error("Task escalation failed",
-				"task_id", taskID,
-				"repo", repo,
-				"issue_num", issueNum,
-				"error", err)
+		if _, ok := tiersBySchedule[task.ScheduleID]; !ok {
+			tiers, err := ListEscalationTiers(db, task.ScheduleID)
+			if err != nil {
+				o.log().Error("Failed to load escalation tiers",
+					"schedule_id", task.ScheduleID,
+					"error", err)
+				continue
+			}
+			if len(tiers) == 0 {
+				tiers = defaultEscalationTiers
+			}
+			tiersBySchedule[task.ScheduleID] = tiers
 		}
+
+		if _, ok := tasksByRepo[task.Repo]; !ok {
+			repos = append(repos, task.Repo)
+		}
+		tasksByRepo[task.Repo] = append(tasksByRepo[task.Repo], task)
+	}
+
+	// Escalate each repository's tasks in order, a bounded number of
+	// repositories at a time
+	workers := make(chan struct{}, o.config.EscalationWorkers())
+	var wg sync.WaitGroup
+	for _, repo := range repos {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(tasks []*OnCallTask) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			for _, task := range tasks {
+				o.escalateIfDue(db, span, task, tiersBySchedule[task.ScheduleID], now)
+			}
+		}(tasksByRepo[repo])
 	}
+	wg.Wait()
 
 	return nil
 }
 
-func (o *OnCallModule) EscalateTask(taskID int64, repo string, issueNum int) error {
-	// Get the task details
-	task, err := GetTask(o.database.DB(), taskID)
+// escalateIfDue escalates a task to its next tier once that tier's threshold
+// has passed, recording the escalation on span.
+func (o *OnCallModule) escalateIfDue(
+	db *sql.DB,
+	span trace.Span,
+	task *OnCallTask,
+	tiers []OnCallEscalationTier,
+	now time.Time,
+) {
+	next, ok := nextEscalationTier(tiers, task.EscalationTier)
+	if !ok || now.Sub(task.CreatedAt) < next.After {
+		return
+	}
+
+	// Notify about escalation
+	logger := o.issueLogger(task.Repo, task.IssueNum)
+	if err := o.EscalateTask(task, next); err != nil {
+		logger.Error("Task escalation failed",
+			"task_id", task.ID,
+			"tier", next.Level,
+			"error", err)
+		return
+	}
+	span.AddEvent("escalation", trace.WithAttributes(
+		attribute.String("repo", task.Repo),
+		attribute.Int("issue_num", task.IssueNum),
+		attribute.Int("tier", next.Level),
+		attribute.String("target", next.Target),
+	))
+	if err := SetTaskEscalationTier(db, task.ID, next.Level); err != nil {
+		logger.Error("Failed to record escalation tier",
+			"task_id", task.ID,
+			"tier", next.Level,
+			"error", err)
+	}
+}
+
+// waitJitter waits a random part of the configured escalation jitter and
+// reports whether ctx is still live afterwards.
+func (o *OnCallModule) waitJitter(ctx context.Context) bool {
+	if o.config.EscalationJitter <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(rand.N(o.config.EscalationJitter)) //nolint:gosec // jitter needs no secure randomness
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// nextEscalationTier returns the first tier above the current level.
+func nextEscalationTier(tiers []OnCallEscalationTier, current int) (OnCallEscalationTier, bool) {
+	for _, tier := range tiers {
+		if tier.Level > current {
+			return tier, true
+		}
+	}
+	return OnCallEscalationTier{}, false
+}
+
+// EscalateTask posts an escalation comment mentioning the tier's target. A
+// task with no assignee is logged and counted, and with
+// EscalateToMaintainersWhenUnassigned the maintainers are mentioned too.
+func (o *OnCallModule) EscalateTask(task *OnCallTask, tier OnCallEscalationTier) error {
+	assignee := strconv.FormatInt(task.AssignedTo, 10)
+	target, err := o.escalationTarget(task, tier)
 	if err != nil {
-		return fmt.Errorf("failed to get task details: %w", err)
+		return err
 	}
+	if task.AssignedTo == 0 {
+		assignee = "no one"
+		o.issueLogger(task.Repo, task.IssueNum).Warn("Escalating a task with no assignee",
+			"task_id", task.ID,
+			"tier", tier.Level,
+			"maintainer_fallback", o.config.EscalateToMaintainersWhenUnassigned)
+		o.metrics().IncUnassignedEscalation(context.Background(), o.Name())
+		if mentions := o.maintainerMentions(); o.config.EscalateToMaintainersWhenUnassigned && mentions != "" {
+			target += ", " + mentions
+		}
+	}
+	return o.postTaskStatus(o.database.DB(), task, target,
+		fmt.Sprintf("⚠️ ESCALATION (tier %d): Task has been unacknowledged for over %s.\n"+
+			"Assigned to: %s\n"+
+			"Escalating to: %s",
+			tier.Level,
+			formatDuration(tier.After),
+			assignee,
+			target))
+}
 
-	// Determine escalation group (could be a configuration)
-	escalationGroup := []string{"@org/oncall-team", "@org/leadership"}
+// escalationTarget returns the mention for a tier: the current on-call user
+// of the tier's target schedule, or the tier's static target when it has no
+// schedule or no one is on call for it.
+func (o *OnCallModule) escalationTarget(task *OnCallTask, tier OnCallEscalationTier) (string, error) {
+	if tier.TargetScheduleID == 0 {
+		return tier.Target, nil
+	}
+	db := o.database.DB()
+	schedule, err := GetSchedule(db, tier.TargetScheduleID)
+	switch {
+	case err != nil:
+	case schedule == nil:
+		err = fmt.Errorf("schedule %d not found", tier.TargetScheduleID)
+	default:
+		var onCall *OnCallUser
+		if onCall, err = CurrentOnCallUser(db, schedule); err == nil {
+			return "@" + onCall.GitHub, nil
+		}
+	}
+	if tier.Target == "" {
+		return "", fmt.Errorf("failed to find on-call user for tier %d: %w", tier.Level, err)
+	}
+	o.issueLogger(task.Repo, task.IssueNum).Warn("No one on call for escalation tier, using its target",
+		"task_id", task.ID,
+		"tier", tier.Level,
+		"target_schedule_id", tier.TargetScheduleID,
+		"error", err)
+	return tier.Target, nil
+}
 
-	// Post escalation comment
-	err = o.PostGitHubComment(repo, issueNum,
-		fmt.Sprintf("⚠️ ESCALATION: Task has been unacknowledged for over 24 hours.\n"+
-			"Assigned to: %d\n"+
-			"Escalation Group: %s",
-			task.AssignedTo,
-			strings.Join(escalationGroup, ", ")))
+// formatDuration renders a duration in whole hours or minutes for comments.
+func formatDuration(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	}
+	return fmt.Sprintf("%d minutes", d/time.Minute)
+}
 
+func (o *OnCallModule) PostGitHubComment(repo string, issueNum int, message string) error {
+	_, err := o.createComment(repo, issueNum, message)
 	return err
 }
 
-func (o *OnCallModule) PostGitHubComment(repo string, issueNum int, message string) error {
+// createComment posts a comment on an issue and returns its ID, which is 0
+// when no comment was posted.
+func (o *OnCallModule) createComment(repo string, issueNum int, message string) (int64, error) {
+	logger := o.issueLogger(repo, issueNum)
+	if o.config.ReadOnly {
+		logger.Debug("Skipping GitHub comment in read-only mode", "message", message)
+		return 0, nil
+	}
+
 	// Check if we have GitHub client available
 	if o.app == nil || o.app.GitHubClient == nil {
 		// Log the action without posting to GitHub
-		slog.Info("GitHub comment would be posted (no GitHub client available)",
-			"repo", repo,
-			"issue_num", issueNum,
-			"message", message)
-		return nil
+		logger.Info("GitHub comment would be posted (no GitHub client available)", "message", message)
+		return 0, nil
 	}
 
-	// Parse repo into owner and repo name
-	parts := strings.Split(repo, "/")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid repository format: %s, expected owner/repo", repo)
+	owner, repoName, err := o.parseRepo(repo)
+	if err != nil {
+		return 0, err
 	}
-	owner, repoName := parts[0], parts[1]
 
 	// Create the comment
 	comment := &github.IssueComment{
-		Body: github.Ptr(message),
+		Body: github.Ptr(o.renderComment(message)),
 	}
 
 	// Create context
 	ctx := context.Background()
 
 	// Post the comment using the app's GitHub client
-	_, _, err := o.app.GitHubClient.Issues.CreateComment(ctx, owner, repoName, issueNum, comment)
+	created, _, err := o.app.GitHubClient.Issues.CreateComment(ctx, owner, repoName, issueNum, comment)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post GitHub comment: %w", err)
+	}
+
+	logger.Info("GitHub comment posted successfully")
+	return created.GetID(), nil
+}
+
+// renderComment returns the body of a comment with message, followed by the
+// configured footer if any. Every posted and edited comment goes through it.
+func (o *OnCallModule) renderComment(message string) string {
+	if o.config.CommentFooter == "" {
+		return message
+	}
+	return message + "\n\n" + o.config.CommentFooter
+}
+
+// editComment replaces the body of a comment the module posted.
+func (o *OnCallModule) editComment(repo string, commentID int64, message string) error {
+	if o.config.ReadOnly || o.app == nil || o.app.GitHubClient == nil {
+		return nil
+	}
+	owner, repoName, err := o.parseRepo(repo)
 	if err != nil {
-		return fmt.Errorf("failed to post GitHub comment: %w", err)
+		return err
+	}
+	comment := &github.IssueComment{Body: github.Ptr(o.renderComment(message))}
+	if _, _, err := o.app.GitHubClient.Issues.EditComment(
+		context.Background(), owner, repoName, commentID, comment,
+	); err != nil {
+		return fmt.Errorf("failed to edit GitHub comment: %w", err)
 	}
+	return nil
+}
 
-	slog.Info("GitHub comment posted successfully",
-		"repo", repo,
-		"issue_num", issueNum)
+// postTaskStatus posts a change in a task's status, which mentions mention.
+// With sticky status comments the task's earlier status comment is edited
+// instead, as long as it mentions the same target: GitHub doesn't notify
+// mentions added by an edit, so a new target always gets a new comment. A
+// new one is also posted when the earlier comment was deleted.
+func (o *OnCallModule) postTaskStatus(db *sql.DB, task *OnCallTask, mention, message string) error {
+	if !o.config.StickyStatusComment {
+		return o.PostGitHubComment(task.Repo, task.IssueNum, message)
+	}
+
+	if task.StatusCommentID != 0 && task.StatusCommentMention == mention {
+		err := o.editComment(task.Repo, task.StatusCommentID, message)
+		if err == nil {
+			return nil
+		}
+		if !isNotFound(err) {
+			return err
+		}
+		o.issueLogger(task.Repo, task.IssueNum).Warn("Status comment is gone, posting a new one",
+			"comment_id", task.StatusCommentID,
+			"error", err)
+	}
+
+	id, err := o.createComment(task.Repo, task.IssueNum, message)
+	if err != nil || id == 0 {
+		return err
+	}
+	task.StatusCommentID, task.StatusCommentMention = id, mention
+	if err := SetTaskStatusComment(db, task.ID, id, mention); err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "set_task_status_comment", map[string]any{
+			"task_id":    task.ID,
+			"comment_id": id,
+		})
+	}
 	return nil
 }
 
+// isNotFound reports whether err is a GitHub API 404 response.
+func isNotFound(err error) bool {
+	var ghErr *github.ErrorResponse
+	return errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound
+}
+
+// splitRepo splits a repository's full name into its owner and name. It
+// returns an *InvalidRepositoryError unless the name is "owner/name".
+func splitRepo(repo string) (owner, name string, err error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") || len(repo) > maxRepositoryLength {
+		return "", "", &InvalidRepositoryError{Repo: repo}
+	}
+	return owner, name, nil
+}
+
+// parseRepo splits a repository's full name like splitRepo, counting and
+// logging invalid ones, such as those of tasks stored before names were
+// validated.
+func (o *OnCallModule) parseRepo(repo string) (owner, name string, err error) {
+	owner, name, err = splitRepo(repo)
+	if err != nil {
+		o.log().Warn("Invalid repository name", "error", err)
+		o.metrics().IncInvalidRepository(context.Background(), o.Name())
+	}
+	return owner, name, err
+}
+
 // Shutdown implements the ModuleShutdowner interface.
+// It stops the escalation checks and waits for a running check to finish. It
+// is safe to call before Initialize and more than once.
 func (o *OnCallModule) Shutdown(ctx context.Context) error {
-	// Nothing to clean up
-	return nil
+	if o.stopChecks == nil {
+		return nil
+	}
+	o.stopChecks()
+	select {
+	case <-o.checksDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HandleEnvelope implements the internal.ModuleEnvelopeHandler interface.
+// It ignores redeliveries of a delivery that was handled, or is being
+// handled, and otherwise handles the event like HandleEvent. Deliveries are
+// also recorded in the database, so that redeliveries are ignored across
+// restarts and instances sharing it.
+func (o *OnCallModule) HandleEnvelope(env *internal.EventEnvelope) (bool, error) {
+	if env.DeliveryID == "" {
+		return o.HandleEvent(env.Type, env.Event, env.Raw)
+	}
+	logger := o.eventLogger(env.Event).With("event_type", env.Type, "delivery_id", env.DeliveryID)
+	now := o.now()
+	if !o.deliveries.claim(env.DeliveryID, now) {
+		logger.Info("Ignoring redelivered event")
+		return true, nil
+	}
+
+	db := o.database.DB()
+	recorded, err := RecordDelivery(db, env.DeliveryID, now)
+	switch {
+	case err != nil:
+		// Handling twice beats not handling at all
+		logger.Warn("Failed to record delivery, handling it anyway", "error", err)
+	case !recorded:
+		logger.Info("Ignoring event already processed")
+		return true, nil
+	}
+
+	handled, err := o.HandleEvent(env.Type, env.Event, env.Raw)
+	if err != nil {
+		o.deliveries.release(env.DeliveryID)
+		if recorded {
+			if forgetErr := ForgetDelivery(db, env.DeliveryID); forgetErr != nil {
+				logger.Error("Failed to forget failed delivery", "error", forgetErr)
+			}
+		}
+	}
+	return handled, err
 }
 
-func (o *OnCallModule) HandleEvent(eventType string, event any, raw json.RawMessage) error {
+// HandleEvent implements the Module interface. It reports whether the event
+// was one the module acts on: a recognized action or command in an enabled
+// repository.
+func (o *OnCallModule) HandleEvent(
+	eventType string,
+	event any,
+	raw json.RawMessage,
+) (bool, error) {
 	db := o.database.DB()
 	if db == nil {
-		return internal.LogAndWrapError(
+		return false, internal.LogAndWrapError(
 			nil,
 			internal.ErrorTypeCommand,
 			"no_db_connection",
@@ -196,12 +721,21 @@ func (o *OnCallModule) HandleEvent(eventType string, event any, raw json.RawMess
 		)
 	}
 
+	logger := o.eventLogger(event)
+	if repoEvent, ok := event.(interface{ GetRepo() *github.Repository }); ok {
+		repo := repoEvent.GetRepo().GetFullName()
+		if !o.isRepositoryEnabled(db, repo) && !isEnableCommand(event) {
+			logger.Debug("Ignoring event for repository not enabled for oncall", "event_type", eventType)
+			return false, nil
+		}
+	}
+
 	switch eventType {
 	case "issues":
 		// Cast to GitHub issues event
 		issuesEvent, ok := event.(*github.IssuesEvent)
 		if !ok {
-			return internal.LogAndWrapError(
+			return false, internal.LogAndWrapError(
 				nil,
 				internal.ErrorTypeCommand,
 				"invalid_event_type",
@@ -211,89 +745,23 @@ func (o *OnCallModule) HandleEvent(eventType string, event any, raw json.RawMess
 			)
 		}
 
-		// Check if the issue is closed
-		if issuesEvent.GetAction() == "closed" {
-			// Find the task associated with this issue
-			repo := issuesEvent.GetRepo().GetFullName()
-			issueNum := issuesEvent.GetIssue().GetNumber()
-
-			task, err := GetTaskByIssueNumber(db, repo, issueNum)
-			if err != nil {
-				return LogAndWrapError(err, ErrorTypeCommand, "get_task", map[string]any{
-					"repo":  repo,
-					"issue": issueNum,
-				})
-			}
-
-			// If task exists and is not already done, mark it as done
-			if task != nil && task.Status != "done" {
-				if err := UpdateTaskStatus(db, task.ID, "done"); err != nil {
-					return LogAndWrapError(
-						err,
-						ErrorTypeCommand,
-						"update_task_status",
-						map[string]any{
-							"task_id": task.ID,
-							"status":  "done",
-						},
-					)
-				}
-				slog.Info("Task marked as done due to issue closure",
-					"task_id", task.ID,
-					"repo", repo,
-					"issue_num", issueNum)
-			}
-		}
-	case "comment":
+		return o.handleIssuesEvent(db, logger, issuesEvent)
+	case "issue_comment":
 		commentEvent, ok := event.(*github.IssueCommentEvent)
 		if !ok {
-			return LogAndWrapError(nil, ErrorTypeCommand, "invalid_event_type", map[string]any{
-				"event_type": "comment",
+			return false, LogAndWrapError(nil, ErrorTypeCommand, "invalid_event_type", map[string]any{
+				"event_type": eventType,
 			})
 		}
-		task, err := GetTaskByIssueNumber(db, *commentEvent.Repo.Name, *commentEvent.Issue.Number)
-		if err != nil {
-			return LogAndWrapError(
-				err,
-				ErrorTypeCommand,
-				"get_task_by_issue_number",
-				map[string]any{
-					"repo":      *commentEvent.Repo.Name,
-					"issue_num": *commentEvent.Issue.Number,
-				},
-			)
-		}
-		if strings.Contains(*commentEvent.GetComment().Body, "/ack") {
-			currentOnCall, err := GetCurrentOnCallUser(db, "primary")
-			if err != nil {
-				return LogAndWrapError(
-					err,
-					ErrorTypeCommand,
-					"get_current_oncall_user",
-					map[string]any{
-						"schedule_name": "primary",
-					},
-				)
-			}
-			if currentOnCall.GitHub == *commentEvent.GetComment().User.Login {
-				if err := UpdateTaskStatus(db, task.ID, "ack"); err != nil {
-					return LogAndWrapError(
-						err,
-						ErrorTypeCommand,
-						"update_task_status",
-						map[string]any{
-							"task_id": task.ID,
-							"status":  "ack",
-						},
-					)
-				}
-				slog.Info("Task marked as acknowledged.",
-					"task_id", task.ID,
-					"repo", task.Repo,
-					"issue_num", task.IssueNum,
-					"acknowledged_by", currentOnCall.GitHub)
-			}
+		return o.handleIssueComment(db, logger, commentEvent)
+	case "pull_request_review":
+		reviewEvent, ok := event.(*github.PullRequestReviewEvent)
+		if !ok {
+			return false, LogAndWrapError(nil, ErrorTypeCommand, "invalid_event_type", map[string]any{
+				"event_type": eventType,
+			})
 		}
+		return o.handlePullRequestReview(db, logger, reviewEvent)
 	}
-	return nil
+	return false, nil
 }
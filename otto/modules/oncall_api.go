@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_api.go serves read-only oncall status over HTTP, such as for an
+// org-wide dashboard of who is on call.
+
+package modules
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// RegisterRoutes implements the internal.ModuleRouter interface.
+func (o *OnCallModule) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/oncall/current", o.handleCurrentOnCall)
+}
+
+// handleCurrentOnCall lists who is on call for every enabled schedule.
+func (o *OnCallModule) handleCurrentOnCall(w http.ResponseWriter, r *http.Request) {
+	views, err := FindCurrentOnCallForAllSchedules(o.database.DB())
+	if err != nil {
+		o.log().Error("Failed to list current on-call users", "error", err)
+		http.Error(w, "failed to list current on-call users", http.StatusInternalServerError)
+		return
+	}
+	if views == nil {
+		views = []CurrentOnCallView{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(views); err != nil {
+		o.log().Error("Failed to write current on-call response", "error", err)
+	}
+}
@@ -0,0 +1,1052 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_commands.go implements the slash commands understood by the oncall module.
+
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// Command patterns recognized in issue and pull request comments.
+var (
+	ackPattern            = regexp.MustCompile(`/ack\b`)
+	resolvePattern        = regexp.MustCompile(`/resolve\b`)
+	dismissPattern        = regexp.MustCompile(`/dismiss\b`)
+	removeSchedulePattern = regexp.MustCompile(`(?m)^\s*/oncall\s+remove\s+schedule\s+(\S.*?)\s*$`)
+	reassignPattern       = regexp.MustCompile(`(?m)^\s*/oncall\s+reassign\s+(\S.*?)\s*$`)
+	snoozePattern         = regexp.MustCompile(`(?m)^\s*/oncall\s+snooze(?:\s+(\S+))?\s*$`)
+	repoTogglePattern     = regexp.MustCompile(`(?m)^\s*/oncall\s+(enable|disable)\s*$`)
+	showSchedulePattern   = regexp.MustCompile(`(?m)^\s*/oncall\s+schedule(?:\s+@?([A-Za-z0-9-]+))?\s*$`)
+	orderPattern          = regexp.MustCompile(`(?m)^\s*/oncall\s+order\s+(\S.*?)((?:\s+@[A-Za-z0-9-]+)+)\s*$`)
+	membersPattern        = regexp.MustCompile(`(?m)^\s*/oncall\s+members\s+(\S.*?)\s*$`)
+	helpPattern           = regexp.MustCompile(`(?m)^\s*/oncall\s+help\s*$`)
+	// commandWordPattern finds a word that starts a line with a slash and
+	// could be a mistyped command; slashes inside URLs and paths don't match.
+	commandWordPattern = regexp.MustCompile(`(?m)^\s*/([A-Za-z][A-Za-z-]*)(?:\s|$)`)
+)
+
+// oncallCommands names the commands handleCommand runs, as used in metrics.
+var oncallCommands = []string{
+	"ack", "resolve", "dismiss", "reassign", "snooze", "remove_schedule", "enable", "disable", "schedule", "order",
+	"members", "import", "help", "unknown",
+}
+
+// commandWords are the words that start a command, which mistyped commands
+// are matched against.
+var commandWords = []string{"ack", "resolve", "dismiss", "oncall"}
+
+// oncallHelp is the reply to /oncall help.
+const oncallHelp = "Oncall commands:\n" +
+	"- `/ack`: acknowledge this issue's task when you are on call\n" +
+	"- `/resolve`: mark this issue's task as done when you are its assignee or a maintainer\n" +
+	"- `/dismiss`: close this issue's task without acting on it, such as for won't fix, " +
+	"when you are its assignee or a maintainer\n" +
+	"- `/oncall reassign <schedule>`: move this issue's task to another schedule " +
+	"when you are its assignee or a maintainer\n" +
+	"- `/oncall snooze [duration]`: pause escalation of this issue's task, such as `/oncall snooze 2h`\n" +
+	"- `/oncall schedule [@user]`: list a user's schedules and upcoming shifts\n" +
+	"- `/oncall members <schedule>`: list a schedule's members in rotation order\n" +
+	"- `/oncall order <schedule> @user1 @user2 ...`: set a schedule's members and their order (maintainers only)\n" +
+	"- `/oncall remove schedule <name>`: delete a schedule (maintainers only)\n" +
+	"- `/oncall import` and a code block of `github_username,name` lines: add users (maintainers only)\n" +
+	"- `/oncall enable`, `/oncall disable`: turn oncall on or off for this repository (maintainers only)\n" +
+	"- `/oncall help`: show this list"
+
+// upcomingShiftsHorizon is how far ahead /oncall schedule lists shifts.
+const upcomingShiftsHorizon = 14 * 24 * time.Hour
+
+// isEnableCommand reports whether event is a new comment running /oncall
+// enable, which must reach the module even in a disabled repository.
+func isEnableCommand(event any) bool {
+	commentEvent, ok := event.(*github.IssueCommentEvent)
+	if !ok || commentEvent.GetAction() != "created" {
+		return false
+	}
+	match := repoTogglePattern.FindStringSubmatch(commentEvent.GetComment().GetBody())
+	return match != nil && match[1] == "enable"
+}
+
+// isBot reports whether a comment's author is a bot account, such as otto
+// itself, whose comments may quote commands without running them.
+func isBot(user *github.User) bool {
+	return user.GetType() == "Bot"
+}
+
+// handleIssueComment runs the command in an issue or pull request comment and
+// reports whether the comment contained a command.
+func (o *OnCallModule) handleIssueComment(
+	db *sql.DB,
+	logger *slog.Logger,
+	event *github.IssueCommentEvent,
+) (bool, error) {
+	if isBot(event.GetComment().GetUser()) {
+		return false, nil
+	}
+	return o.handleCommand(
+		db,
+		logger,
+		event.GetRepo().GetFullName(),
+		event.GetIssue().GetNumber(),
+		event.GetComment().GetUser().GetLogin(),
+		event.GetIssue().GetUser().GetLogin(),
+		event.GetComment().GetBody(),
+	)
+}
+
+// handlePullRequestReview runs the command in the body of a submitted pull
+// request review and reports whether the review contained a command.
+func (o *OnCallModule) handlePullRequestReview(
+	db *sql.DB,
+	logger *slog.Logger,
+	event *github.PullRequestReviewEvent,
+) (bool, error) {
+	if event.GetAction() != "submitted" || isBot(event.GetReview().GetUser()) {
+		return false, nil
+	}
+	return o.handleCommand(
+		db,
+		logger,
+		event.GetRepo().GetFullName(),
+		event.GetPullRequest().GetNumber(),
+		event.GetReview().GetUser().GetLogin(),
+		event.GetPullRequest().GetUser().GetLogin(),
+		event.GetReview().GetBody(),
+	)
+}
+
+// handleIssuesEvent finishes a task when its issue is closed or a maintainer
+// adds a resolve or dismiss label, and optionally reopens it when a maintainer
+// removes that label or announces who is on call when an issue is opened.
+func (o *OnCallModule) handleIssuesEvent(db *sql.DB, logger *slog.Logger, event *github.IssuesEvent) (bool, error) {
+	repo := event.GetRepo().GetFullName()
+	issueNum := event.GetIssue().GetNumber()
+
+	switch event.GetAction() {
+	case "opened":
+		if !o.config.AnnounceOnCallOnOpen {
+			return false, nil
+		}
+		return o.announceOnCall(db, logger, repo, issueNum)
+	case "closed":
+		task, err := GetTaskByIssueNumber(db, repo, issueNum)
+		if err != nil {
+			return false, LogAndWrapError(err, ErrorTypeCommand, "get_task", map[string]any{
+				"repo":  repo,
+				"issue": issueNum,
+			})
+		}
+
+		// If task exists and is not already finished, mark it as done
+		if task != nil && !IsTaskFinished(task.Status) {
+			if err := UpdateTaskStatus(db, task.ID, TaskStatusDone); err != nil {
+				return false, LogAndWrapError(
+					err,
+					ErrorTypeCommand,
+					"update_task_status",
+					map[string]any{
+						"task_id": task.ID,
+						"status":  TaskStatusDone,
+					},
+				)
+			}
+			logger.Info("Task marked as done due to issue closure", "task_id", task.ID)
+		}
+		return true, nil
+	case "labeled":
+		label, sender := event.GetLabel().GetName(), event.GetSender().GetLogin()
+		resolve, dismiss := o.config.IsResolveLabel(label), o.config.IsDismissLabel(label)
+		if !resolve && !dismiss {
+			return false, nil
+		}
+		if !o.isMaintainer(sender) {
+			logger.Debug("Ignoring label added by a non-maintainer", "label", label, "sender", sender)
+			return false, nil
+		}
+		if resolve {
+			return true, o.resolveIssueTask(db, logger, repo, issueNum, sender)
+		}
+		return true, o.dismissIssueTask(db, logger, repo, issueNum, sender)
+	case "unlabeled":
+		label, sender := event.GetLabel().GetName(), event.GetSender().GetLogin()
+		if !o.config.ReopenOnUnlabel || !(o.config.IsResolveLabel(label) || o.config.IsDismissLabel(label)) {
+			return false, nil
+		}
+		if !o.isMaintainer(sender) {
+			logger.Debug("Ignoring label removed by a non-maintainer", "label", label, "sender", sender)
+			return false, nil
+		}
+		return true, o.reopenTask(db, logger, repo, issueNum, sender)
+	}
+	return false, nil
+}
+
+// announceOnCall comments on a new issue with the repository's current
+// on-call user and how soon they are expected to respond: the first
+// escalation threshold of their schedule. It reports whether it commented,
+// which it doesn't when the repository has no schedule or no one on call.
+func (o *OnCallModule) announceOnCall(db *sql.DB, logger *slog.Logger, repo string, issueNum int) (bool, error) {
+	scheduleName, err := o.repositoryScheduleName(db, repo)
+	if err != nil {
+		return false, LogAndWrapError(err, ErrorTypeCommand, "repository_schedule_name", map[string]any{
+			"repo": repo,
+		})
+	}
+	schedule, err := o.scheduleByName(db, scheduleName)
+	if err != nil {
+		return false, LogAndWrapError(err, ErrorTypeCommand, "get_schedule", map[string]any{
+			"schedule_name": scheduleName,
+		})
+	}
+	if schedule == nil {
+		logger.Debug("Not announcing on-call user, repository has no schedule", "schedule", scheduleName)
+		return false, nil
+	}
+	onCall, err := CurrentOnCallUser(db, schedule)
+	if err != nil {
+		logger.Debug("Not announcing on-call user, no one is on call", "schedule", schedule.Name, "error", err)
+		return false, nil
+	}
+
+	tiers, err := ListEscalationTiers(db, schedule.ID)
+	if err != nil {
+		return false, LogAndWrapError(err, ErrorTypeCommand, "list_escalation_tiers", map[string]any{
+			"schedule_id": schedule.ID,
+		})
+	}
+	if len(tiers) == 0 {
+		tiers = defaultEscalationTiers
+	}
+	return true, o.PostGitHubComment(repo, issueNum, fmt.Sprintf(
+		"Thanks for the report! @%s is on call for `%s` and is expected to respond within %s.",
+		onCall.GitHub, repo, formatDuration(tiers[0].After)))
+}
+
+// reopenTask returns a finished task for an issue to the open state.
+func (o *OnCallModule) reopenTask(db *sql.DB, logger *slog.Logger, repo string, issueNum int, user string) error {
+	task, err := GetTaskByIssueNumber(db, repo, issueNum)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_task_by_issue_number", map[string]any{
+			"repo":      repo,
+			"issue_num": issueNum,
+		})
+	}
+	if task == nil || !IsTaskFinished(task.Status) {
+		return nil
+	}
+
+	if err := ReopenTask(db, task.ID); err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "reopen_task", map[string]any{
+			"task_id": task.ID,
+		})
+	}
+	logger.Info("Task reopened", "task_id", task.ID, "reopened_by", user)
+	return nil
+}
+
+// handleCommand routes a comment body to the matching command handler and
+// reports whether it contained a command. author is the login that opened the
+// issue or pull request, which some commands treat specially.
+func (o *OnCallModule) handleCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user, author, body string,
+) (bool, error) {
+	switch {
+	case importPattern.MatchString(body):
+		return o.runCommand(logger, repo, issueNum, user, "import", "", func() error {
+			return o.handleImportUsersCommand(db, logger, repo, issueNum, user, body)
+		})
+	case removeSchedulePattern.MatchString(body):
+		name := removeSchedulePattern.FindStringSubmatch(body)[1]
+		return o.runCommand(logger, repo, issueNum, user, "remove_schedule", name, func() error {
+			return o.handleRemoveScheduleCommand(db, logger, repo, issueNum, user, name)
+		})
+	case repoTogglePattern.MatchString(body):
+		command := repoTogglePattern.FindStringSubmatch(body)[1]
+		return o.runCommand(logger, repo, issueNum, user, command, "", func() error {
+			return o.handleRepoToggleCommand(db, logger, repo, issueNum, user, command == "enable")
+		})
+	case orderPattern.MatchString(body):
+		match := orderPattern.FindStringSubmatch(body)
+		name, logins := match[1], strings.Fields(match[2])
+		return o.runCommand(logger, repo, issueNum, user, "order", match[1]+match[2], func() error {
+			return o.handleOrderCommand(db, logger, repo, issueNum, user, name, logins)
+		})
+	case membersPattern.MatchString(body):
+		name := membersPattern.FindStringSubmatch(body)[1]
+		return o.runCommand(logger, repo, issueNum, user, "members", name, func() error {
+			return o.handleMembersCommand(db, repo, issueNum, name)
+		})
+	case showSchedulePattern.MatchString(body):
+		target := showSchedulePattern.FindStringSubmatch(body)[1]
+		if target == "" {
+			target = user
+		}
+		return o.runCommand(logger, repo, issueNum, user, "schedule", target, func() error {
+			return o.handleShowScheduleCommand(db, repo, issueNum, target)
+		})
+	case snoozePattern.MatchString(body):
+		arg := snoozePattern.FindStringSubmatch(body)[1]
+		return o.runCommand(logger, repo, issueNum, user, "snooze", arg, func() error {
+			return o.handleSnoozeCommand(db, logger, repo, issueNum, user, arg)
+		})
+	case reassignPattern.MatchString(body):
+		name := reassignPattern.FindStringSubmatch(body)[1]
+		return o.runCommand(logger, repo, issueNum, user, "reassign", name, func() error {
+			return o.handleReassignCommand(db, logger, repo, issueNum, user, name)
+		})
+	case resolvePattern.MatchString(body):
+		return o.runCommand(logger, repo, issueNum, user, "resolve", "", func() error {
+			return o.handleResolveCommand(db, logger, repo, issueNum, user, author)
+		})
+	case dismissPattern.MatchString(body):
+		return o.runCommand(logger, repo, issueNum, user, "dismiss", "", func() error {
+			return o.handleDismissCommand(db, logger, repo, issueNum, user)
+		})
+	case ackPattern.MatchString(body):
+		return o.runCommand(logger, repo, issueNum, user, "ack", "", func() error {
+			return o.handleAckCommand(db, logger, repo, issueNum, user)
+		})
+	case helpPattern.MatchString(body):
+		return o.runCommand(logger, repo, issueNum, user, "help", "", func() error {
+			return o.PostGitHubComment(repo, issueNum, oncallHelp)
+		})
+	}
+
+	if o.config.SuggestCommands {
+		if typed, suggestion, ok := suggestCommand(body); ok {
+			return o.runCommand(logger, repo, issueNum, user, "unknown", typed, func() error {
+				return o.PostGitHubComment(repo, issueNum, fmt.Sprintf(
+					"Unknown command `/%s`, did you mean `/%s`? Run `/oncall help` to list the commands.",
+					typed, suggestion))
+			})
+		}
+	}
+	return false, nil
+}
+
+// suggestCommand finds a line starting with an unknown /word close enough to
+// a command word to be a typo of it, and returns both words.
+func suggestCommand(body string) (typed, suggestion string, ok bool) {
+	for _, match := range commandWordPattern.FindAllStringSubmatch(body, -1) {
+		word := strings.ToLower(match[1])
+		if slices.Contains(commandWords, word) {
+			continue
+		}
+		best, bestDistance := "", 0
+		for _, command := range commandWords {
+			// Short commands tolerate one edit so that words like /cc aren't taken for /ack
+			maxDistance := 2
+			if len(command) <= 4 {
+				maxDistance = 1
+			}
+			d := levenshtein(word, command)
+			if d <= maxDistance && (best == "" || d < bestDistance) {
+				best, bestDistance = command, d
+			}
+		}
+		if best != "" {
+			return match[1], best, true
+		}
+	}
+	return "", "", false
+}
+
+// levenshtein returns the number of single-character insertions, deletions
+// and substitutions needed to turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// runCommand runs a command unless anyone ran it with the same argument on the
+// same issue within the cooldown window.
+func (o *OnCallModule) runCommand(
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user, command, arg string,
+	run func() error,
+) (bool, error) {
+	key := commandKey{repo: repo, issueNum: issueNum, command: command + " " + arg}
+	if !o.cooldown.allow(key, o.now(), o.config.CommandCooldownWindow()) || !o.claimCommandRun(logger, key) {
+		logger.Info("Suppressed repeated command", "command", command, "user", user)
+		o.metrics().IncModuleCommandSuppressed(context.Background(), o.Name(), command)
+		return true, nil
+	}
+	o.metrics().IncModuleCommand(context.Background(), o.Name(), command)
+	return true, run()
+}
+
+// claimCommandRun records a command run in the database when the cooldown is
+// persisted, and reports whether the command may run.
+func (o *OnCallModule) claimCommandRun(logger *slog.Logger, key commandKey) bool {
+	window := o.config.CommandCooldownWindow()
+	if !o.config.PersistCommandCooldown || window <= 0 {
+		return true
+	}
+	claimed, err := ClaimCommandRun(o.database.DB(), key.repo, key.issueNum, key.command, o.now(), window)
+	if err != nil {
+		// Running twice beats not running at all
+		logger.Warn("Failed to record command run, running it anyway", "error", err)
+		return true
+	}
+	return claimed
+}
+
+// handleAckCommand acknowledges the task for an issue when the commenter is on
+// call or a maintainer. Other commenters are ignored, unless
+// RequireAssignedAck is set: then they are told they can't acknowledge.
+func (o *OnCallModule) handleAckCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user string,
+) error {
+	task, err := GetTaskByIssueNumber(db, repo, issueNum)
+	if err != nil {
+		return LogAndWrapError(
+			err,
+			ErrorTypeCommand,
+			"get_task_by_issue_number",
+			map[string]any{
+				"repo":      repo,
+				"issue_num": issueNum,
+			},
+		)
+	}
+	if task == nil || task.Status != TaskStatusOpen {
+		logger.Debug("Ignoring /ack for issue without an open task")
+		return nil
+	}
+
+	scheduleName, err := o.repositoryScheduleName(db, repo)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "repository_schedule_name", map[string]any{
+			"repo": repo,
+		})
+	}
+	currentOnCall, err := o.currentOnCallUser(db, scheduleName)
+	if err != nil {
+		return LogAndWrapError(
+			err,
+			ErrorTypeCommand,
+			"get_current_oncall_user",
+			map[string]any{
+				"schedule_name": scheduleName,
+			},
+		)
+	}
+	if !strings.EqualFold(currentOnCall.GitHub, user) && !o.isMaintainer(user) {
+		if !o.config.RequireAssignedAck {
+			logger.Debug("Ignoring /ack from someone who isn't on call", "user", user)
+			return nil
+		}
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("@%s only the on-call engineer can acknowledge this task.", user))
+	}
+
+	now := o.now()
+	if err := AcknowledgeTask(db, task.ID, user, now); err != nil {
+		return LogAndWrapError(
+			err,
+			ErrorTypeCommand,
+			"acknowledge_task",
+			map[string]any{
+				"task_id": task.ID,
+				"user":    user,
+			},
+		)
+	}
+	if err := MarkUserActive(db, user, now); err != nil {
+		return LogAndWrapError(
+			err,
+			ErrorTypeCommand,
+			"mark_user_active",
+			map[string]any{
+				"user": user,
+			},
+		)
+	}
+	logger.Info("Task marked as acknowledged.",
+		"task_id", task.ID,
+		"acknowledged_by", user,
+		"acknowledged_at", now)
+	return nil
+}
+
+// handleResolveCommand marks the task for an issue as done when user may
+// resolve it: maintainers, the task's assignee and, with AllowAuthorResolve,
+// the author of the issue or pull request.
+func (o *OnCallModule) handleResolveCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user, author string,
+) error {
+	task, err := unfinishedIssueTask(db, repo, issueNum)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		logger.Debug("Ignoring /resolve for issue without an unfinished task")
+		return nil
+	}
+
+	allowed, err := o.mayResolve(db, task, user, author)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_user_by_github", map[string]any{
+			"user": user,
+		})
+	}
+	if !allowed {
+		who := "the assignee or maintainers"
+		if o.config.AllowAuthorResolve {
+			who = "the author, the assignee or maintainers"
+		}
+		return o.PostGitHubComment(repo, issueNum, fmt.Sprintf("@%s only %s can resolve this task.", user, who))
+	}
+	return o.resolveTask(db, logger, task, user)
+}
+
+// handleDismissCommand dismisses the task for an issue when user is its
+// assignee or a maintainer.
+func (o *OnCallModule) handleDismissCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user string,
+) error {
+	task, err := unfinishedIssueTask(db, repo, issueNum)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		logger.Debug("Ignoring /dismiss for issue without an unfinished task")
+		return nil
+	}
+
+	allowed, err := o.isAssigneeOrMaintainer(db, task, user)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_user_by_github", map[string]any{
+			"user": user,
+		})
+	}
+	if !allowed {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("@%s only the assignee or maintainers can dismiss this task.", user))
+	}
+	return o.dismissTask(db, logger, task, user)
+}
+
+// dismissIssueTask dismisses the unfinished task for an issue, if any.
+func (o *OnCallModule) dismissIssueTask(db *sql.DB, logger *slog.Logger, repo string, issueNum int, user string) error {
+	task, err := unfinishedIssueTask(db, repo, issueNum)
+	if err != nil || task == nil {
+		return err
+	}
+	return o.dismissTask(db, logger, task, user)
+}
+
+// dismissTask marks a task as dismissed, finished without being resolved.
+func (o *OnCallModule) dismissTask(db *sql.DB, logger *slog.Logger, task *OnCallTask, user string) error {
+	if err := UpdateTaskStatus(db, task.ID, TaskStatusDismissed); err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "update_task_status", map[string]any{
+			"task_id": task.ID,
+			"status":  TaskStatusDismissed,
+		})
+	}
+	logger.Info("Task dismissed", "task_id", task.ID, "dismissed_by", user)
+	return nil
+}
+
+// mayResolve reports whether user may resolve a task with /resolve.
+func (o *OnCallModule) mayResolve(db *sql.DB, task *OnCallTask, user, author string) (bool, error) {
+	if o.config.AllowAuthorResolve && author != "" && strings.EqualFold(user, author) {
+		return true, nil
+	}
+	return o.isAssigneeOrMaintainer(db, task, user)
+}
+
+// isAssigneeOrMaintainer reports whether user is a maintainer or the user a
+// task is assigned to.
+func (o *OnCallModule) isAssigneeOrMaintainer(db *sql.DB, task *OnCallTask, user string) (bool, error) {
+	if o.isMaintainer(user) {
+		return true, nil
+	}
+	assignee, err := GetUserByGitHub(db, user)
+	if err != nil {
+		return false, err
+	}
+	return assignee != nil && assignee.ID == task.AssignedTo, nil
+}
+
+// resolveIssueTask marks the task for an issue as done, if it has an
+// unfinished one.
+func (o *OnCallModule) resolveIssueTask(db *sql.DB, logger *slog.Logger, repo string, issueNum int, user string) error {
+	task, err := unfinishedIssueTask(db, repo, issueNum)
+	if err != nil || task == nil {
+		return err
+	}
+	return o.resolveTask(db, logger, task, user)
+}
+
+// unfinishedIssueTask returns the task for an issue, or nil if it has none or
+// the task is done or dismissed.
+func unfinishedIssueTask(db *sql.DB, repo string, issueNum int) (*OnCallTask, error) {
+	task, err := GetTaskByIssueNumber(db, repo, issueNum)
+	if err != nil {
+		return nil, LogAndWrapError(err, ErrorTypeCommand, "get_task_by_issue_number", map[string]any{
+			"repo":      repo,
+			"issue_num": issueNum,
+		})
+	}
+	if task == nil || IsTaskFinished(task.Status) {
+		return nil, nil
+	}
+	return task, nil
+}
+
+// resolveTask marks a task as done.
+func (o *OnCallModule) resolveTask(db *sql.DB, logger *slog.Logger, task *OnCallTask, user string) error {
+	if err := UpdateTaskStatus(db, task.ID, TaskStatusDone); err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "update_task_status", map[string]any{
+			"task_id": task.ID,
+			"status":  TaskStatusDone,
+		})
+	}
+	logger.Info("Task resolved", "task_id", task.ID, "resolved_by", user)
+	return nil
+}
+
+// handleSnoozeCommand pauses escalation of the open task for an issue for the
+// given duration, or the configured default when arg is empty. Only the
+// task's assignee and maintainers may snooze it.
+func (o *OnCallModule) handleSnoozeCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user, arg string,
+) error {
+	duration := o.config.SnoozeWindow()
+	if arg != "" {
+		d, err := time.ParseDuration(arg)
+		if err != nil || d <= 0 {
+			return o.PostGitHubComment(repo, issueNum,
+				fmt.Sprintf("Invalid snooze duration `%s`, use a duration such as `30m` or `2h`.", arg))
+		}
+		duration = d
+	}
+
+	task, err := GetTaskByIssueNumber(db, repo, issueNum)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_task_by_issue_number", map[string]any{
+			"repo":      repo,
+			"issue_num": issueNum,
+		})
+	}
+	if task == nil || task.Status != TaskStatusOpen {
+		return o.PostGitHubComment(repo, issueNum, "There is no unacknowledged on-call task for this issue to snooze.")
+	}
+
+	allowed, err := o.isAssigneeOrMaintainer(db, task, user)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_user_by_github", map[string]any{
+			"user": user,
+		})
+	}
+	if !allowed {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("@%s only the assignee or maintainers can snooze this task.", user))
+	}
+
+	until := o.now().Add(duration)
+	if err := SnoozeTask(db, task.ID, until); err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "snooze_task", map[string]any{
+			"task_id": task.ID,
+		})
+	}
+	logger.Info("Task escalation snoozed", "task_id", task.ID, "until", until, "snoozed_by", user)
+	return o.PostGitHubComment(repo, issueNum,
+		fmt.Sprintf("Escalation of this task is snoozed until %s.", until.UTC().Format("Mon 2006-01-02 15:04 UTC")))
+}
+
+// handleRemoveScheduleCommand deletes a schedule by name. Only maintainers may
+// remove schedules, and schedules with unfinished tasks are kept.
+func (o *OnCallModule) handleRemoveScheduleCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user, name string,
+) error {
+	if !o.isMaintainer(user) {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("@%s only maintainers can remove schedules.", user))
+	}
+
+	schedule, err := FindScheduleByName(db, name)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_schedule", map[string]any{
+			"schedule_name": name,
+		})
+	}
+	if schedule == nil {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("Schedule `%s` does not exist.", name))
+	}
+
+	openTasks, err := CountUnfinishedTasksForSchedule(db, schedule.ID)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "count_schedule_tasks", map[string]any{
+			"schedule_id": schedule.ID,
+		})
+	}
+	if openTasks > 0 {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("Schedule `%s` still has %d unfinished task(s) and was not removed.",
+				schedule.Name, openTasks))
+	}
+
+	if err := DeleteSchedule(db, schedule.ID); err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "delete_schedule", map[string]any{
+			"schedule_id": schedule.ID,
+		})
+	}
+	o.scheduleCache().Invalidate(schedule.Name)
+	logger.Info("Schedule removed", "schedule", schedule.Name, "removed_by", user)
+
+	return o.PostGitHubComment(repo, issueNum,
+		fmt.Sprintf("Schedule `%s` has been removed.", schedule.Name))
+}
+
+// handleOrderCommand replaces the members of a schedule with the given users
+// in the given order. Only maintainers may order schedules, and every user
+// must exist.
+func (o *OnCallModule) handleOrderCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user, name string,
+	logins []string,
+) error {
+	if !o.isMaintainer(user) {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("@%s only maintainers can change the order of schedules.", user))
+	}
+
+	schedule, err := FindScheduleByName(db, name)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_schedule", map[string]any{
+			"schedule_name": name,
+		})
+	}
+	if schedule == nil {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("Schedule `%s` does not exist.", name))
+	}
+
+	members := make([]OnCallUser, 0, len(logins))
+	seen := make(map[int64]bool, len(logins))
+	var unknown []string
+	for _, login := range logins {
+		login = strings.TrimPrefix(login, "@")
+		member, err := GetUserByGitHub(db, login)
+		if err != nil {
+			return LogAndWrapError(err, ErrorTypeCommand, "get_user", map[string]any{
+				"github": login,
+			})
+		}
+		if member == nil {
+			unknown = append(unknown, "@"+login)
+			continue
+		}
+		if seen[member.ID] {
+			return o.PostGitHubComment(repo, issueNum,
+				fmt.Sprintf("@%s is listed more than once; the order of `%s` was not changed.",
+					member.GitHub, schedule.Name))
+		}
+		seen[member.ID] = true
+		members = append(members, *member)
+	}
+	if len(unknown) > 0 {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("Unknown users: %s. Add them with `/oncall import` first; the order of `%s` was not changed.",
+				strings.Join(unknown, ", "), schedule.Name))
+	}
+
+	ids := make([]int64, len(members))
+	for i, member := range members {
+		ids[i] = member.ID
+	}
+	if err := SetScheduleMembers(db, schedule.ID, ids); err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "set_schedule_members", map[string]any{
+			"schedule_id": schedule.ID,
+		})
+	}
+	o.scheduleCache().Invalidate(schedule.Name)
+	logger.Info("Schedule members ordered", "schedule", schedule.Name, "members", len(ids), "ordered_by", user)
+
+	return o.PostGitHubComment(repo, issueNum, formatScheduleMembers(schedule.Name, members))
+}
+
+// handleMembersCommand lists the members of a schedule in rotation order.
+func (o *OnCallModule) handleMembersCommand(db *sql.DB, repo string, issueNum int, name string) error {
+	schedule, err := FindScheduleByName(db, name)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_schedule", map[string]any{
+			"schedule_name": name,
+		})
+	}
+	if schedule == nil {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("Schedule `%s` does not exist.", name))
+	}
+
+	members, err := ListScheduleMembers(db, schedule.ID)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "list_schedule_members", map[string]any{
+			"schedule_id": schedule.ID,
+		})
+	}
+	return o.PostGitHubComment(repo, issueNum, formatScheduleMembers(schedule.Name, members))
+}
+
+// formatScheduleMembers lists the members of a schedule as a numbered list
+// in rotation order.
+func formatScheduleMembers(schedule string, members []OnCallUser) string {
+	if len(members) == 0 {
+		return fmt.Sprintf("Schedule `%s` has no members.", schedule)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rotation order of `%s`:", schedule)
+	for i, member := range members {
+		fmt.Fprintf(&b, "\n%d. @%s", i+1, member.GitHub)
+	}
+	return b.String()
+}
+
+// handleReassignCommand moves the task for an issue to another schedule and
+// assigns it to that schedule's current on-call user, when the commenter is
+// the task's assignee or a maintainer.
+func (o *OnCallModule) handleReassignCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user, name string,
+) error {
+	task, err := GetTaskByIssueNumber(db, repo, issueNum)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_task_by_issue_number", map[string]any{
+			"repo":      repo,
+			"issue_num": issueNum,
+		})
+	}
+	if task == nil {
+		return o.PostGitHubComment(repo, issueNum, "There is no on-call task for this issue to reassign.")
+	}
+	if IsTaskFinished(task.Status) {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("The on-call task for this issue is already %s.", task.Status))
+	}
+	allowed, err := o.isAssigneeOrMaintainer(db, task, user)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_user_by_github", map[string]any{
+			"user": user,
+		})
+	}
+	if !allowed {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("@%s only the assignee or maintainers can reassign this task.", user))
+	}
+
+	schedule, err := FindScheduleByName(db, name)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_schedule", map[string]any{
+			"schedule_name": name,
+		})
+	}
+	if schedule == nil {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("Schedule `%s` does not exist.", name))
+	}
+
+	assignee, err := CurrentOnCallUser(db, schedule)
+	if err != nil {
+		logger.Warn("No on-call user for reassignment target",
+			"schedule", schedule.Name,
+			"error", err)
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("Schedule `%s` has no one on call.", schedule.Name))
+	}
+
+	if err := ReassignTask(db, task.ID, schedule.ID, assignee.ID); err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "reassign_task", map[string]any{
+			"task_id":     task.ID,
+			"schedule_id": schedule.ID,
+		})
+	}
+	logger.Info("Task reassigned",
+		"task_id", task.ID,
+		"schedule", schedule.Name,
+		"assignee", assignee.GitHub,
+		"reassigned_by", user)
+
+	return o.postTaskStatus(db, task, "@"+assignee.GitHub,
+		fmt.Sprintf("Reassigned to schedule `%s`; @%s is now on call for this issue.",
+			schedule.Name, assignee.GitHub))
+}
+
+// handleRepoToggleCommand enables or disables the module for a repository.
+func (o *OnCallModule) handleRepoToggleCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user string,
+	enable bool,
+) error {
+	if !o.isMaintainer(user) {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("@%s only maintainers can enable or disable oncall.", user))
+	}
+
+	if err := SetRepositoryEnabled(db, repo, enable, user, o.now()); err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "set_repository_enabled", map[string]any{
+			"repo":    repo,
+			"enabled": enable,
+		})
+	}
+	setting, err := GetRepoSetting(db, repo)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_repo_setting", map[string]any{
+			"repo": repo,
+		})
+	}
+	o.repoSettingsCache().Set(strings.ToLower(repo), setting)
+
+	state := "disabled"
+	if enable {
+		state = "enabled"
+	}
+	logger.Info("Repository oncall setting changed", "enabled", enable, "changed_by", user)
+	return o.PostGitHubComment(repo, issueNum, fmt.Sprintf("Oncall is now %s for `%s`.", state, repo))
+}
+
+// onCallShift is one upcoming period a user is on call for a schedule.
+type onCallShift struct {
+	schedule string
+	start    time.Time
+	end      time.Time
+}
+
+// handleShowScheduleCommand lists a user's schedules and their upcoming shifts.
+func (o *OnCallModule) handleShowScheduleCommand(db *sql.DB, repo string, issueNum int, login string) error {
+	user, err := GetUserByGitHub(db, login)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "get_user", map[string]any{
+			"user": login,
+		})
+	}
+	if user == nil {
+		return o.PostGitHubComment(repo, issueNum, fmt.Sprintf("@%s is not an on-call user.", login))
+	}
+
+	schedules, err := ListSchedulesForUser(db, user.ID)
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "list_schedules_for_user", map[string]any{
+			"user_id": user.ID,
+		})
+	}
+	if len(schedules) == 0 {
+		return o.PostGitHubComment(repo, issueNum, fmt.Sprintf("@%s is not on any schedule.", user.GitHub))
+	}
+
+	now := o.now()
+	var shifts []onCallShift
+	var manual []string
+	for _, schedule := range schedules {
+		if schedule.Recurrence == "" {
+			current, err := CurrentOnCallUser(db, &schedule)
+			status := "in rotation"
+			if err == nil && current.ID == user.ID {
+				status = "on call now"
+			}
+			manual = append(manual, fmt.Sprintf("- `%s`: %s; handoffs are manual", schedule.Name, status))
+			continue
+		}
+
+		handoffs, err := GenerateUpcomingHandoffs(db, schedule.ID, now, upcomingShiftsHorizon)
+		if err != nil {
+			return LogAndWrapError(err, ErrorTypeCommand, "generate_upcoming_handoffs", map[string]any{
+				"schedule_id": schedule.ID,
+			})
+		}
+		for _, h := range handoffs {
+			if h.UserID == user.ID {
+				shifts = append(shifts, onCallShift{schedule: schedule.Name, start: h.Start, end: h.End})
+			}
+		}
+	}
+	sort.SliceStable(shifts, func(i, j int) bool { return shifts[i].start.Before(shifts[j].start) })
+
+	var b strings.Builder
+	days := int(upcomingShiftsHorizon / (24 * time.Hour))
+	if len(shifts) == 0 {
+		fmt.Fprintf(&b, "@%s has no scheduled shifts in the next %d days.\n", user.GitHub, days)
+	} else {
+		fmt.Fprintf(&b, "On-call shifts for @%s in the next %d days:\n\n", user.GitHub, days)
+		b.WriteString("| Schedule | Start | End |\n|---|---|---|\n")
+		for _, shift := range shifts {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", shift.schedule, formatShiftTime(shift.start, now),
+				formatShiftTime(shift.end, now))
+		}
+	}
+	if len(manual) > 0 {
+		b.WriteString("\n" + strings.Join(manual, "\n") + "\n")
+	}
+	return o.PostGitHubComment(repo, issueNum, strings.TrimSuffix(b.String(), "\n"))
+}
+
+// formatShiftTime formats a shift boundary in UTC, marking one that is now.
+func formatShiftTime(t, now time.Time) string {
+	if t.Equal(now) {
+		return "now"
+	}
+	return t.UTC().Format("Mon 2006-01-02 15:04 UTC")
+}
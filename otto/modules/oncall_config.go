@@ -0,0 +1,406 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package modules
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultResolveLabels are the issue labels that resolve a task when none are configured.
+var DefaultResolveLabels = []string{"resolved"}
+
+// DefaultDismissLabels are the issue labels that dismiss a task when none are configured.
+var DefaultDismissLabels = []string{"wontfix"}
+
+// DefaultEscalationConcurrency is how many repositories' tasks are escalated
+// at once when no concurrency is configured.
+const DefaultEscalationConcurrency = 4
+
+// DefaultSnoozeDuration is how long /oncall snooze pauses escalation when no
+// duration is given or configured.
+const DefaultSnoozeDuration = time.Hour
+
+// DefaultScheduleTemplate is the default schedule name used when none is configured.
+const DefaultScheduleTemplate = "primary"
+
+// DefaultScheduleDescriptionTemplate is the default description of a
+// repository's default schedule used when none is configured.
+const DefaultScheduleDescriptionTemplate = "Default on-call schedule for {{.Repo}}"
+
+// OnCallConfig holds the oncall module settings from the "oncall" entry
+// under "modules" in config.yaml.
+type OnCallConfig struct {
+	// Maintainers lists the GitHub logins allowed to run administrative commands.
+	Maintainers []string `yaml:"maintainers"`
+
+	// MaintainerTeam is a GitHub team, as "org/team-slug", whose members are
+	// the maintainers instead of Maintainers. Maintainers is still used while
+	// the team can't be listed.
+	MaintainerTeam string `yaml:"maintainer_team"`
+
+	// MaintainerTeamTTL is how long the team's members are cached. Defaults
+	// to DefaultMaintainerTeamTTL.
+	MaintainerTeamTTL time.Duration `yaml:"maintainer_team_ttl"`
+
+	// AllowAuthorResolve lets the author of an issue or pull request resolve
+	// its task with /resolve, in addition to maintainers and the assignee.
+	AllowAuthorResolve bool `yaml:"allow_author_resolve"`
+
+	// RequireAssignedAck replies to anyone but the schedule's current on-call
+	// user and maintainers, who may always acknowledge, that they can't
+	// acknowledge. Without it, /ack from anyone else is silently ignored.
+	RequireAssignedAck bool `yaml:"require_assigned_ack"`
+
+	// EscalateToMaintainersWhenUnassigned also mentions the maintainer team,
+	// or else the configured maintainers, when escalating a task that has no
+	// assignee because no one was on call for its schedule.
+	EscalateToMaintainersWhenUnassigned bool `yaml:"escalate_to_maintainers_when_unassigned"`
+
+	// Repositories lists the "owner/name" repositories the module acts on.
+	// Either part may be a glob pattern, such as "open-telemetry/*".
+	// An empty list enables every repository.
+	Repositories []string `yaml:"repositories"`
+
+	// DefaultSchedule names the schedule whose on-call user handles a
+	// repository's tasks. It is a text/template with .Repo set to the
+	// repository's full name, such as "{{.Repo}} on-call". Defaults to
+	// DefaultScheduleTemplate.
+	DefaultSchedule string `yaml:"default_schedule"`
+
+	// DefaultScheduleDescription describes a repository's default schedule,
+	// so that orgs can standardize it like the name. It is a text/template
+	// with .Repo set to the repository's full name. Defaults to
+	// DefaultScheduleDescriptionTemplate.
+	DefaultScheduleDescription string `yaml:"default_schedule_description"`
+
+	// FallbackSchedule names the schedule used for a repository whose
+	// default schedule doesn't exist, such as an org-wide triage schedule
+	// for repositories without their own.
+	FallbackSchedule string `yaml:"fallback_schedule"`
+
+	// CommandCooldown is how long a repeated command on the same issue is
+	// ignored, whoever repeats it. Zero means DefaultCommandCooldown and a negative
+	// value disables the cooldown.
+	CommandCooldown time.Duration `yaml:"command_cooldown"`
+
+	// PersistCommandCooldown also records command runs in the database, so
+	// that the cooldown holds across restarts and instances sharing it.
+	PersistCommandCooldown bool `yaml:"persist_command_cooldown"`
+
+	// ResolveLabels lists the issue labels that resolve an issue's task when
+	// added by a maintainer. Defaults to DefaultResolveLabels.
+	ResolveLabels []string `yaml:"resolve_labels"`
+
+	// DismissLabels lists the issue labels that dismiss an issue's task when
+	// added by a maintainer, like /dismiss, so that it doesn't count as
+	// resolved. Defaults to DefaultDismissLabels.
+	DismissLabels []string `yaml:"dismiss_labels"`
+
+	// ReopenOnUnlabel reopens a finished task when a maintainer removes a
+	// resolve or dismiss label.
+	ReopenOnUnlabel bool `yaml:"reopen_on_unlabel"`
+
+	// AnnounceOnCallOnOpen comments on newly opened issues with the current
+	// on-call user and the expected response time, the first escalation
+	// threshold of their schedule.
+	AnnounceOnCallOnOpen bool `yaml:"announce_oncall_on_open"`
+
+	// HandoffIssue is an issue, as "owner/repo#123", where a comment
+	// announces each handoff of a schedule with a recurrence rule. Empty
+	// means handoffs are only logged.
+	HandoffIssue string `yaml:"handoff_issue"`
+
+	// ReadOnly records tasks and commands without posting GitHub comments,
+	// such as while migrating from another tool.
+	ReadOnly bool `yaml:"read_only"`
+
+	// SuggestCommands replies to a mistyped command, such as /ak, with the
+	// command it most likely meant.
+	SuggestCommands bool `yaml:"suggest_commands"`
+
+	// CommentFooter is appended to every comment the module posts or edits,
+	// separated by a blank line, such as a signature linking to the docs.
+	// Empty by default.
+	CommentFooter string `yaml:"comment_footer"`
+
+	// StickyStatusComment edits one comment per task as its escalation or
+	// assignment changes, instead of posting a new comment each time. A
+	// change that mentions someone new still gets a new comment, since
+	// GitHub doesn't notify mentions added by an edit.
+	StickyStatusComment bool `yaml:"sticky_status_comment"`
+
+	// EscalationConcurrency is how many repositories' tasks are escalated at
+	// once, bounding bursts of GitHub calls. Defaults to
+	// DefaultEscalationConcurrency.
+	EscalationConcurrency int `yaml:"escalation_concurrency"`
+
+	// SnoozeDuration is how long /oncall snooze pauses a task's escalation
+	// when the command gives no duration. Defaults to DefaultSnoozeDuration.
+	SnoozeDuration time.Duration `yaml:"snooze_duration"`
+
+	// EscalationJitter delays each escalation check by a random duration up
+	// to this long, so that instances don't all call GitHub at once.
+	EscalationJitter time.Duration `yaml:"escalation_jitter"`
+
+	// ScheduleCacheTTL caches schedule lookups by name for this long, sparing
+	// a query per command. Schedules changed by the module, such as by seeding,
+	// handoffs or /oncall remove schedule, are forgotten at once; other
+	// changes show once the TTL passes. Zero disables the cache.
+	ScheduleCacheTTL time.Duration `yaml:"schedule_cache_ttl"`
+
+	// ScheduleCacheSize bounds the number of cached schedule lookups.
+	// Defaults to DefaultScheduleCacheSize.
+	ScheduleCacheSize int `yaml:"schedule_cache_size"`
+
+	// Seed declares schedules and members created at startup when missing,
+	// so that they can be managed declaratively. See SeedSchedules.
+	Seed []ScheduleSeed `yaml:"seed"`
+}
+
+// LoadOnCallConfig extracts the oncall module configuration from the
+// application's module configuration map.
+func LoadOnCallConfig(modules map[string]any) (OnCallConfig, error) {
+	var cfg OnCallConfig
+
+	raw, ok := modules["oncall"]
+	if !ok || raw == nil {
+		return cfg, nil
+	}
+
+	// Round-trip through YAML to decode the generic map into the typed config
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to encode oncall config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to decode oncall config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// Validate reports repository patterns that could never match, malformed
+// templates and team names, empty resolve or dismiss labels, negative
+// durations and escalation settings, and invalid or duplicate seed schedules.
+func (c OnCallConfig) Validate() error {
+	var errs []error
+	if _, err := c.defaultScheduleTemplate(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.defaultScheduleDescriptionTemplate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, pattern := range c.Repositories {
+		if strings.Count(pattern, "/") != 1 {
+			errs = append(errs, fmt.Errorf("invalid repository pattern %q: must be owner/name", pattern))
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid repository pattern %q: %w", pattern, err))
+		}
+	}
+	if c.MaintainerTeam != "" {
+		if org, slug, _ := strings.Cut(c.MaintainerTeam, "/"); org == "" || slug == "" || strings.Contains(slug, "/") {
+			errs = append(errs, fmt.Errorf("invalid maintainer_team %q: must be org/team", c.MaintainerTeam))
+		}
+	}
+	if c.MaintainerTeamTTL < 0 {
+		errs = append(errs, fmt.Errorf("maintainer_team_ttl %s must not be negative", c.MaintainerTeamTTL))
+	}
+	for _, label := range c.ResolveLabels {
+		if strings.TrimSpace(label) == "" {
+			errs = append(errs, errors.New("resolve_labels must not contain empty labels"))
+			break
+		}
+	}
+	for _, label := range c.DismissLabels {
+		if strings.TrimSpace(label) == "" {
+			errs = append(errs, errors.New("dismiss_labels must not contain empty labels"))
+			break
+		}
+	}
+	if c.HandoffIssue != "" {
+		if _, _, err := parseIssueRef(c.HandoffIssue); err != nil {
+			errs = append(errs, fmt.Errorf("invalid handoff_issue: %w", err))
+		}
+	}
+	if c.EscalationConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("escalation_concurrency %d must not be negative", c.EscalationConcurrency))
+	}
+	if c.ScheduleCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("schedule_cache_ttl %s must not be negative", c.ScheduleCacheTTL))
+	}
+	if c.ScheduleCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("schedule_cache_size %d must not be negative", c.ScheduleCacheSize))
+	}
+	if c.SnoozeDuration < 0 {
+		errs = append(errs, fmt.Errorf("snooze_duration %s must not be negative", c.SnoozeDuration))
+	}
+	if c.EscalationJitter < 0 {
+		errs = append(errs, fmt.Errorf("escalation_jitter %s must not be negative", c.EscalationJitter))
+	}
+	seeded := make(map[string]bool, len(c.Seed))
+	for _, seed := range c.Seed {
+		if err := seed.validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if name := strings.ToLower(seed.Name); seeded[name] {
+			errs = append(errs, fmt.Errorf("seed schedule %q is declared more than once", seed.Name))
+		} else {
+			seeded[name] = true
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IsRepositoryEnabled reports whether the module acts on the repository, given
+// by its full name. Matching is case-insensitive, like GitHub names.
+func (c OnCallConfig) IsRepositoryEnabled(repo string) bool {
+	if len(c.Repositories) == 0 {
+		return true
+	}
+	repo = strings.ToLower(repo)
+	for _, pattern := range c.Repositories {
+		if ok, _ := path.Match(strings.ToLower(pattern), repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMaintainer reports whether the GitHub login is a configured maintainer.
+func (c OnCallConfig) IsMaintainer(login string) bool {
+	for _, m := range c.Maintainers {
+		if strings.EqualFold(strings.TrimPrefix(m, "@"), login) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultScheduleTemplate parses the DefaultSchedule template.
+func (c OnCallConfig) defaultScheduleTemplate() (*template.Template, error) {
+	return parseRepoTemplate("default_schedule", cmp.Or(c.DefaultSchedule, DefaultScheduleTemplate))
+}
+
+// defaultScheduleDescriptionTemplate parses the DefaultScheduleDescription
+// template.
+func (c OnCallConfig) defaultScheduleDescriptionTemplate() (*template.Template, error) {
+	return parseRepoTemplate(
+		"default_schedule_description",
+		cmp.Or(c.DefaultScheduleDescription, DefaultScheduleDescriptionTemplate),
+	)
+}
+
+// DefaultScheduleName returns the name of the default schedule for a repository.
+func (c OnCallConfig) DefaultScheduleName(repo string) (string, error) {
+	tmpl, err := c.defaultScheduleTemplate()
+	if err != nil {
+		return "", err
+	}
+	return renderRepoTemplate(tmpl, repo)
+}
+
+// DefaultScheduleDescriptionFor returns the description of the default
+// schedule for a repository.
+func (c OnCallConfig) DefaultScheduleDescriptionFor(repo string) (string, error) {
+	tmpl, err := c.defaultScheduleDescriptionTemplate()
+	if err != nil {
+		return "", err
+	}
+	return renderRepoTemplate(tmpl, repo)
+}
+
+// parseRepoTemplate parses the text/template of the setting called name.
+func parseRepoTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// renderRepoTemplate renders a template parsed by parseRepoTemplate with .Repo
+// set to repo.
+func renderRepoTemplate(tmpl *template.Template, repo string) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, struct{ Repo string }{Repo: repo}); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// CommandCooldownWindow returns the effective command cooldown.
+func (c OnCallConfig) CommandCooldownWindow() time.Duration {
+	if c.CommandCooldown == 0 {
+		return DefaultCommandCooldown
+	}
+	return c.CommandCooldown
+}
+
+// ScheduleCacheEntries returns the effective schedule cache size.
+func (c OnCallConfig) ScheduleCacheEntries() int {
+	if c.ScheduleCacheSize == 0 {
+		return DefaultScheduleCacheSize
+	}
+	return c.ScheduleCacheSize
+}
+
+// SnoozeWindow returns the effective default snooze duration.
+func (c OnCallConfig) SnoozeWindow() time.Duration {
+	if c.SnoozeDuration == 0 {
+		return DefaultSnoozeDuration
+	}
+	return c.SnoozeDuration
+}
+
+// MaintainerTeamCacheTTL returns the effective maintainer team cache TTL.
+func (c OnCallConfig) MaintainerTeamCacheTTL() time.Duration {
+	if c.MaintainerTeamTTL == 0 {
+		return DefaultMaintainerTeamTTL
+	}
+	return c.MaintainerTeamTTL
+}
+
+// EscalationWorkers returns the effective escalation concurrency.
+func (c OnCallConfig) EscalationWorkers() int {
+	if c.EscalationConcurrency == 0 {
+		return DefaultEscalationConcurrency
+	}
+	return c.EscalationConcurrency
+}
+
+// IsResolveLabel reports whether adding label resolves an issue's task.
+func (c OnCallConfig) IsResolveLabel(label string) bool {
+	return containsLabel(c.ResolveLabels, DefaultResolveLabels, label)
+}
+
+// IsDismissLabel reports whether adding label dismisses an issue's task.
+func (c OnCallConfig) IsDismissLabel(label string) bool {
+	return containsLabel(c.DismissLabels, DefaultDismissLabels, label)
+}
+
+// containsLabel reports whether labels, or defaults when labels is empty,
+// contain label, ignoring case.
+func containsLabel(labels, defaults []string, label string) bool {
+	if len(labels) == 0 {
+		labels = defaults
+	}
+	for _, l := range labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package modules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsRepositoryEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		repo     string
+		want     bool
+	}{
+		{"empty list enables all", nil, "org/repo", true},
+		{"exact match", []string{"org/repo"}, "org/repo", true},
+		{"exact match ignores case", []string{"Org/Repo"}, "org/repo", true},
+		{"exact non-match", []string{"org/repo"}, "org/other", false},
+		{"org wildcard", []string{"open-telemetry/*"}, "open-telemetry/otel-go", true},
+		{"org wildcard other org", []string{"open-telemetry/*"}, "other/otel-go", false},
+		{"repo wildcard", []string{"*/community"}, "open-telemetry/community", true},
+		{"partial wildcard", []string{"org/otel-*"}, "org/otel-go", true},
+		{"second pattern matches", []string{"a/b", "org/*"}, "org/repo", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := OnCallConfig{Repositories: tt.patterns}
+			if got := cfg.IsRepositoryEnabled(tt.repo); got != tt.want {
+				t.Errorf("IsRepositoryEnabled(%q) with %q: want %v, got %v", tt.repo, tt.patterns, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadOnCallConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		modules map[string]any
+		wantErr string
+	}{
+		{
+			name:    "no oncall section",
+			modules: map[string]any{},
+		},
+		{
+			name: "valid patterns",
+			modules: map[string]any{"oncall": map[string]any{
+				"repositories": []any{"org/*", "other/repo"},
+			}},
+		},
+		{
+			name: "malformed glob",
+			modules: map[string]any{"oncall": map[string]any{
+				"repositories": []any{"org/[repo"},
+			}},
+			wantErr: `invalid repository pattern "org/[repo"`,
+		},
+		{
+			name: "malformed default schedule template",
+			modules: map[string]any{"oncall": map[string]any{
+				"default_schedule": "{{.Repo",
+			}},
+			wantErr: "invalid default_schedule template",
+		},
+		{
+			name: "malformed default schedule description template",
+			modules: map[string]any{"oncall": map[string]any{
+				"default_schedule_description": "{{.Repo",
+			}},
+			wantErr: "invalid default_schedule_description template",
+		},
+		{
+			name: "missing owner",
+			modules: map[string]any{"oncall": map[string]any{
+				"repositories": []any{"open-telemetry"},
+			}},
+			wantErr: "must be owner/name",
+		},
+		{
+			name: "empty resolve label",
+			modules: map[string]any{"oncall": map[string]any{
+				"resolve_labels": []any{"resolved", " "},
+			}},
+			wantErr: "resolve_labels must not contain empty labels",
+		},
+		{
+			name: "empty dismiss label",
+			modules: map[string]any{"oncall": map[string]any{
+				"dismiss_labels": []any{""},
+			}},
+			wantErr: "dismiss_labels must not contain empty labels",
+		},
+		{
+			name: "valid handoff issue",
+			modules: map[string]any{"oncall": map[string]any{
+				"handoff_issue": "org/repo#12",
+			}},
+		},
+		{
+			name: "handoff issue without a number",
+			modules: map[string]any{"oncall": map[string]any{
+				"handoff_issue": "org/repo",
+			}},
+			wantErr: "invalid handoff_issue",
+		},
+		{
+			name: "handoff issue with an invalid repository",
+			modules: map[string]any{"oncall": map[string]any{
+				"handoff_issue": "repo#12",
+			}},
+			wantErr: "invalid handoff_issue",
+		},
+		{
+			name: "maintainer team without org",
+			modules: map[string]any{"oncall": map[string]any{
+				"maintainer_team": "maintainers",
+			}},
+			wantErr: `invalid maintainer_team "maintainers"`,
+		},
+		{
+			name: "negative maintainer team ttl",
+			modules: map[string]any{"oncall": map[string]any{
+				"maintainer_team":     "org/maintainers",
+				"maintainer_team_ttl": "-1m",
+			}},
+			wantErr: "maintainer_team_ttl -1m0s must not be negative",
+		},
+		{
+			name:    "negative schedule cache size",
+			modules: map[string]any{"oncall": map[string]any{"schedule_cache_ttl": "1m", "schedule_cache_size": -1}},
+			wantErr: "schedule_cache_size -1 must not be negative",
+		},
+		{
+			name:    "negative snooze duration",
+			modules: map[string]any{"oncall": map[string]any{"snooze_duration": "-1h"}},
+			wantErr: "snooze_duration -1h0m0s must not be negative",
+		},
+		{
+			name: "valid seed",
+			modules: map[string]any{"oncall": map[string]any{
+				"seed": []any{map[string]any{
+					"name":       "primary",
+					"policy":     "random",
+					"recurrence": "FREQ=DAILY",
+					"members":    []any{"@alice", "bob"},
+				}},
+			}},
+		},
+		{
+			name: "seed with unknown policy",
+			modules: map[string]any{"oncall": map[string]any{
+				"seed": []any{map[string]any{"name": "primary", "policy": "fifo"}},
+			}},
+			wantErr: `seed schedule "primary": unknown policy "fifo"`,
+		},
+		{
+			name: "seed with invalid member",
+			modules: map[string]any{"oncall": map[string]any{
+				"seed": []any{map[string]any{"name": "primary", "members": []any{"not a login"}}},
+			}},
+			wantErr: `"not a login" is not a valid GitHub login`,
+		},
+		{
+			name: "duplicate seed",
+			modules: map[string]any{"oncall": map[string]any{
+				"seed": []any{map[string]any{"name": "primary"}, map[string]any{"name": "Primary"}},
+			}},
+			wantErr: `seed schedule "Primary" is declared more than once`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadOnCallConfig(tt.modules)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("want error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDefaultScheduleName(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{"default", "", "primary", false},
+		{"fixed name", "infra", "infra", false},
+		{"repository template", "{{.Repo}} on-call", "org/repo on-call", false},
+		{"unknown field", "{{.Team}}", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := OnCallConfig{DefaultSchedule: tt.template}
+			got, err := cfg.DefaultScheduleName("org/repo")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DefaultScheduleName: wantErr %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("DefaultScheduleName: want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDefaultScheduleDescription(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{"default", "", "Default on-call schedule for org/repo", false},
+		{"repository template", "Triage rotation of {{.Repo}}", "Triage rotation of org/repo", false},
+		{"unknown field", "{{.Team}}", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := OnCallConfig{DefaultScheduleDescription: tt.template}
+			got, err := cfg.DefaultScheduleDescriptionFor("org/repo")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DefaultScheduleDescriptionFor: wantErr %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("DefaultScheduleDescriptionFor: want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
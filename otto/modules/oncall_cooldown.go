@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_cooldown.go suppresses repeated commands so redelivered webhooks and
+// rapid repeats don't post duplicate comments.
+
+package modules
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// deliveryRetention is how long a handled webhook delivery is remembered so
+// that redeliveries of it are ignored.
+const deliveryRetention = 24 * time.Hour
+
+// DefaultCommandCooldown is how long a repeated command is suppressed when no
+// cooldown is configured.
+const DefaultCommandCooldown = 30 * time.Second
+
+// commandKey identifies a command invocation for cooldown purposes. It leaves
+// out who ran the command, so that several people repeating it on an issue
+// don't each get a reply.
+type commandKey struct {
+	repo     string
+	issueNum int
+	command  string
+}
+
+// commandCooldown remembers when each command last ran. The zero value is ready
+// to use.
+type commandCooldown struct {
+	mu      sync.Mutex
+	lastRun map[commandKey]time.Time
+}
+
+// allow reports whether the command may run at now, recording the run if so.
+// A command is refused when the same one ran less than window ago.
+func (c *commandCooldown) allow(key commandKey, now time.Time, window time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lastRun == nil {
+		c.lastRun = make(map[commandKey]time.Time)
+	}
+	if last, ok := c.lastRun[key]; ok && now.Sub(last) < window {
+		return false
+	}
+
+	// Forget expired entries so the map stays bounded by recent activity
+	for k, last := range c.lastRun {
+		if now.Sub(last) >= window {
+			delete(c.lastRun, k)
+		}
+	}
+	c.lastRun[key] = now
+	return true
+}
+
+// deliveryLog remembers the webhook deliveries being or recently handled. The
+// zero value is ready to use.
+type deliveryLog struct {
+	mu sync.Mutex
+	// order holds the claims oldest first, so that expired ones are evicted
+	// from its front without scanning them all.
+	order list.List
+	seen  map[string]*list.Element
+}
+
+// claimedDelivery is a delivery ID and when it was claimed.
+type claimedDelivery struct {
+	id string
+	at time.Time
+}
+
+// claim reports whether the delivery with the given ID should be handled at
+// now, recording it if so. A delivery claimed less than deliveryRetention ago
+// is refused.
+func (d *deliveryLog) claim(id string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.seen == nil {
+		d.seen = make(map[string]*list.Element)
+	}
+	for e := d.order.Front(); e != nil && now.Sub(e.Value.(claimedDelivery).at) >= deliveryRetention; {
+		next := e.Next()
+		delete(d.seen, e.Value.(claimedDelivery).id)
+		d.order.Remove(e)
+		e = next
+	}
+	if _, ok := d.seen[id]; ok {
+		return false
+	}
+	d.seen[id] = d.order.PushBack(claimedDelivery{id: id, at: now})
+	return true
+}
+
+// release forgets a claimed delivery, such as one that failed, so that a
+// redelivery is handled again.
+func (d *deliveryLog) release(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if e, ok := d.seen[id]; ok {
+		d.order.Remove(e)
+		delete(d.seen, id)
+	}
+}
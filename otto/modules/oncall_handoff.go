@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_handoff.go advances the rotation of schedules with a recurrence rule
+// at each of their handoff times.
+
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseIssueRef splits an issue reference such as "owner/repo#123" into the
+// repository's full name and the issue number.
+func parseIssueRef(ref string) (string, int, error) {
+	repo, number, ok := strings.Cut(ref, "#")
+	if !ok {
+		return "", 0, fmt.Errorf("%q must be owner/repo#number", ref)
+	}
+	if _, _, err := splitRepo(repo); err != nil {
+		return "", 0, err
+	}
+	issueNum, err := strconv.Atoi(number)
+	if err != nil || issueNum <= 0 {
+		return "", 0, fmt.Errorf("%q has an invalid issue number", ref)
+	}
+	return repo, issueNum, nil
+}
+
+// AdvanceRotations hands off every round-robin schedule whose recurrence rule
+// had a handoff time since its last handoff, moving it to the next active
+// member for each such time. A schedule checked for the first time only
+// starts counting from now. Problems with one schedule are logged and don't
+// stop the others.
+func (o *OnCallModule) AdvanceRotations() error {
+	db := o.database.DB()
+	schedules, err := ListRecurringSchedules(db)
+	if err != nil {
+		return fmt.Errorf("failed to list recurring schedules: %w", err)
+	}
+
+	now := o.now()
+	for _, schedule := range schedules {
+		if schedule.Policy != RoundRobinPolicy {
+			continue
+		}
+		if err := o.advanceRotation(&schedule, now); err != nil {
+			o.log().Error("Failed to advance rotation", "schedule", schedule.Name, "error", err)
+		}
+	}
+	return nil
+}
+
+// advanceRotation hands off a schedule for the handoff times of its rule
+// since its last handoff, up to now.
+func (o *OnCallModule) advanceRotation(schedule *OnCallSchedule, now time.Time) error {
+	db := o.database.DB()
+	logger := o.log().With("schedule", schedule.Name)
+	if schedule.LastHandoffAt == nil {
+		if _, err := HandOffSchedule(db, schedule.ID, nil, now, schedule.CurrentRotationIdx); err != nil {
+			return err
+		}
+		o.scheduleCache().Invalidate(schedule.Name)
+		return nil
+	}
+
+	rule, err := ParseRecurrence(schedule.Recurrence)
+	if err != nil {
+		return err
+	}
+	var due []time.Time
+	for _, at := range rule.Occurrences(schedule.CreatedAt, *schedule.LastHandoffAt, now) {
+		if at.After(*schedule.LastHandoffAt) {
+			due = append(due, at)
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	members, err := ListScheduleMembers(db, schedule.ID)
+	if err != nil {
+		return err
+	}
+	idx := schedule.CurrentRotationIdx
+	var outgoing, incoming *OnCallUser
+	if len(members) > 0 {
+		outgoing = &members[idx%len(members)]
+		incoming = outgoing
+	}
+	for range due {
+		next, ok := nextActiveMember(members, idx)
+		if !ok {
+			// Keep the rotation. The due handoff times are still recorded
+			// below, so later checks don't warn again until the next one.
+			logger.Warn("No active members to hand off to",
+				"members", len(members),
+				"handoff_at", due[len(due)-1])
+			break
+		}
+		idx, incoming = next, &members[next]
+	}
+
+	handedOff, err := HandOffSchedule(db, schedule.ID, schedule.LastHandoffAt, due[len(due)-1], idx)
+	if err != nil {
+		return err
+	}
+	if !handedOff {
+		logger.Debug("Schedule was handed off by another instance")
+		return nil
+	}
+	o.scheduleCache().Invalidate(schedule.Name)
+	if incoming == nil || incoming.ID == outgoing.ID {
+		return nil
+	}
+
+	logger.Info("Rotation handed off", "from", outgoing.GitHub, "to", incoming.GitHub)
+	if o.config.HandoffIssue == "" {
+		return nil
+	}
+	repo, issueNum, err := parseIssueRef(o.config.HandoffIssue)
+	if err != nil {
+		return err
+	}
+	return o.PostGitHubComment(repo, issueNum, fmt.Sprintf(
+		"Handoff for `%s`: @%s is now on call, taking over from @%s.",
+		schedule.Name, incoming.GitHub, outgoing.GitHub))
+}
+
+// nextActiveMember returns the index of the first active member after idx in
+// rotation order, wrapping around, and false if no member is active.
+func nextActiveMember(members []OnCallUser, idx int) (int, bool) {
+	for i := 1; i <= len(members); i++ {
+		next := (idx + i) % len(members)
+		if members[next].Active {
+			return next, true
+		}
+	}
+	return 0, false
+}
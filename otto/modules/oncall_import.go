@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_import.go implements /oncall import, which adds many users at once.
+
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	importPattern = regexp.MustCompile(`(?m)^\s*/oncall\s+import\s*$`)
+	// fencedBlockPattern captures the contents of a fenced code block.
+	fencedBlockPattern = regexp.MustCompile("(?s)```[^\\n]*\\n(.*?)```")
+	// githubLoginPattern matches letters, digits and single hyphens, not
+	// starting or ending with a hyphen, as GitHub logins are made of.
+	githubLoginPattern = regexp.MustCompile(`^[A-Za-z0-9](?:-?[A-Za-z0-9])*$`)
+)
+
+// maxGitHubLoginLength is the longest login GitHub allows.
+const maxGitHubLoginLength = 39
+
+// importHeaders are the first column names that mark a header row.
+var importHeaders = []string{"github_username", "github", "login", "username"}
+
+// importUsage is the reply to /oncall import without a code block.
+const importUsage = "Put the users to import in a code block after `/oncall import`, one per line as " +
+	"`github_username,name` or as a markdown table with those columns."
+
+// parseUserImport reads users from CSV lines or markdown table rows with the
+// columns github_username, name and optionally email, which is not stored. It
+// returns a problem for each row that can't be imported.
+func parseUserImport(block string) ([]OnCallUser, []string) {
+	var users []OnCallUser
+	var problems []string
+	for i, line := range strings.Split(block, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var fields []string
+		if strings.HasPrefix(line, "|") {
+			fields = strings.Split(strings.Trim(line, "|"), "|")
+		} else {
+			fields = strings.Split(line, ",")
+		}
+		for j := range fields {
+			fields[j] = strings.TrimSpace(fields[j])
+		}
+
+		login := strings.TrimPrefix(fields[0], "@")
+		if slices.Contains(importHeaders, strings.ToLower(login)) || strings.Trim(login, "-: ") == "" {
+			// Header and markdown separator rows
+			continue
+		}
+		if len(login) > maxGitHubLoginLength || !githubLoginPattern.MatchString(login) {
+			problems = append(problems, fmt.Sprintf("line %d: `%s` is not a valid GitHub login", i+1, login))
+			continue
+		}
+		name := login
+		if len(fields) > 1 && fields[1] != "" {
+			name = fields[1]
+		}
+		users = append(users, OnCallUser{GitHub: login, DisplayName: name})
+	}
+	return users, problems
+}
+
+// handleImportUsersCommand adds the users listed in a comment's code block.
+// Only maintainers may import users, and nothing is imported if any row is
+// invalid.
+func (o *OnCallModule) handleImportUsersCommand(
+	db *sql.DB,
+	logger *slog.Logger,
+	repo string,
+	issueNum int,
+	user, body string,
+) error {
+	if !o.isMaintainer(user) {
+		return o.PostGitHubComment(repo, issueNum,
+			fmt.Sprintf("@%s only maintainers can import users.", user))
+	}
+
+	block := fencedBlockPattern.FindStringSubmatch(body[importPattern.FindStringIndex(body)[1]:])
+	if block == nil {
+		return o.PostGitHubComment(repo, issueNum, importUsage)
+	}
+	users, problems := parseUserImport(block[1])
+	if len(problems) > 0 {
+		return o.PostGitHubComment(repo, issueNum,
+			"No users were imported:\n- "+strings.Join(problems, "\n- "))
+	}
+	if len(users) == 0 {
+		return o.PostGitHubComment(repo, issueNum, importUsage)
+	}
+
+	created, skipped, err := ImportUsers(db, users, o.now())
+	if err != nil {
+		return LogAndWrapError(err, ErrorTypeCommand, "import_users", map[string]any{
+			"users": len(users),
+		})
+	}
+	logger.Info("Users imported", "created", created, "skipped", skipped, "imported_by", user)
+	return o.PostGitHubComment(repo, issueNum,
+		fmt.Sprintf("Imported users: %d created, %d skipped because they already exist.", created, skipped))
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_maintainers.go decides who may run administrative commands, from the
+// configured maintainers or the members of a GitHub team.
+
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/cache"
+)
+
+// DefaultMaintainerTeamTTL is how long the maintainer team's members are
+// cached when no TTL is configured.
+const DefaultMaintainerTeamTTL = 10 * time.Minute
+
+// maintainerTeamCache returns the cache of the maintainer team's members, as
+// lower-cased logins, keyed by team.
+func (o *OnCallModule) maintainerTeamCache() *cache.TTL[string, map[string]bool] {
+	o.initCaches()
+	return o.maintainerTeam
+}
+
+// isMaintainer reports whether login may run administrative commands. With a
+// maintainer team configured, its members are the maintainers; if the team
+// can't be listed, the configured Maintainers are used instead.
+func (o *OnCallModule) isMaintainer(login string) bool {
+	if o.config.MaintainerTeam == "" {
+		return o.config.IsMaintainer(login)
+	}
+	members, err := o.maintainerTeamMembers()
+	if err != nil {
+		o.log().Warn("Failed to list maintainer team, using configured maintainers",
+			"team", o.config.MaintainerTeam,
+			"error", err)
+		return o.config.IsMaintainer(login)
+	}
+	return members[strings.ToLower(login)]
+}
+
+// maintainerMentions mentions the maintainer team if one is configured, or
+// else each configured maintainer. It is empty when neither is configured.
+func (o *OnCallModule) maintainerMentions() string {
+	if o.config.MaintainerTeam != "" {
+		return "@" + o.config.MaintainerTeam
+	}
+	mentions := make([]string, 0, len(o.config.Maintainers))
+	for _, m := range o.config.Maintainers {
+		mentions = append(mentions, "@"+strings.TrimPrefix(m, "@"))
+	}
+	return strings.Join(mentions, ", ")
+}
+
+// maintainerTeamMembers returns the maintainer team's members, listing them
+// again once the cached list is older than the configured TTL. Concurrent
+// callers wait for a single listing.
+func (o *OnCallModule) maintainerTeamMembers() (map[string]bool, error) {
+	team := o.config.MaintainerTeam
+	if members, ok := o.maintainerTeamCache().Get(team); ok {
+		return members, nil
+	}
+
+	o.maintainerTeamMu.Lock()
+	defer o.maintainerTeamMu.Unlock()
+	// Another caller may have listed the team while this one waited
+	if members, ok := o.maintainerTeamCache().Get(team); ok {
+		return members, nil
+	}
+	members, err := o.listTeamMembers(team)
+	if err != nil {
+		return nil, err
+	}
+	o.maintainerTeamCache().Set(team, members)
+	return members, nil
+}
+
+// listTeamMembers returns the lower-cased logins of the members of a team,
+// given as "org/team-slug".
+func (o *OnCallModule) listTeamMembers(team string) (map[string]bool, error) {
+	if o.app == nil || o.app.GitHubClient == nil {
+		return nil, errors.New("no GitHub client available")
+	}
+	org, slug, ok := strings.Cut(team, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid team %q: must be org/team", team)
+	}
+
+	var perPage int
+	if o.app.Config != nil {
+		perPage = o.app.Config.GitHubPerPage
+	}
+	users, err := internal.Paginate(perPage, func(opts github.ListOptions) ([]*github.User, *github.Response, error) {
+		return o.app.GitHubClient.Teams.ListTeamMembersBySlug(context.Background(), org, slug,
+			&github.TeamListTeamMembersOptions{ListOptions: opts})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of %s: %w", team, err)
+	}
+	members := make(map[string]bool, len(users))
+	for _, user := range users {
+		members[strings.ToLower(user.GetLogin())] = true
+	}
+	return members, nil
+}
@@ -2,14 +2,19 @@
 
 package modules
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"time"
+)
 
 type OnCallUser struct {
-	ID          int64
-	GitHub      string
-	DisplayName string
-	Active      bool
-	CreatedAt   time.Time
+	ID           int64
+	GitHub       string
+	DisplayName  string
+	Active       bool
+	CreatedAt    time.Time
+	LastActiveAt time.Time // zero if the user never acknowledged a task
 }
 
 type OnCallScheduleRotationPolicy string
@@ -30,8 +35,32 @@ type OnCallSchedule struct {
 	Policy             OnCallScheduleRotationPolicy
 	Enabled            bool
 	CurrentRotationIdx int
-	CreatedAt          time.Time
-	UpdatedAt          time.Time
+	// Recurrence is the schedule's handoff rule, empty if handoffs are manual.
+	Recurrence string
+	// LastHandoffAt is when the rotation last advanced by its recurrence rule,
+	// or nil until the schedule is first checked for handoffs.
+	LastHandoffAt *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// CurrentOnCallView is who is on call for an enabled schedule, for status
+// views across all schedules.
+type CurrentOnCallView struct {
+	ScheduleID int64                        `json:"schedule_id"`
+	Schedule   string                       `json:"schedule"`
+	Policy     OnCallScheduleRotationPolicy `json:"policy"`
+	// GitHub and DisplayName are empty when no one is on call, because the
+	// schedule has no members or its policy has no current user.
+	GitHub      string `json:"github"`
+	DisplayName string `json:"display_name"`
+}
+
+// OnCallHandoff is a period during which a user is on call for a schedule.
+type OnCallHandoff struct {
+	UserID int64
+	Start  time.Time
+	End    time.Time
 }
 
 type OnCallScheduleUser struct {
@@ -40,6 +69,81 @@ type OnCallScheduleUser struct {
 	Position   int
 }
 
+// Task status constants describe the lifecycle of an on-call task.
+const (
+	// TaskStatusOpen marks a task that has not been acknowledged yet.
+	TaskStatusOpen = "open"
+	// TaskStatusAck marks a task acknowledged by the on-call user.
+	TaskStatusAck = "ack"
+	// TaskStatusDone marks a completed task.
+	TaskStatusDone = "done"
+	// TaskStatusDismissed marks a task closed without acting on it, such as
+	// a report that won't be fixed. Unlike done tasks, dismissed tasks don't
+	// count as resolved.
+	TaskStatusDismissed = "dismissed"
+)
+
+// TaskStatuses lists every known task status.
+var TaskStatuses = []string{TaskStatusOpen, TaskStatusAck, TaskStatusDone, TaskStatusDismissed}
+
+// taskTransitions lists the statuses each task status may move to. A task is
+// acknowledged before it is done unless it is resolved straight away, it may
+// be dismissed until it is done, and only a finished task can be reopened.
+var taskTransitions = map[string][]string{
+	TaskStatusOpen:      {TaskStatusAck, TaskStatusDone, TaskStatusDismissed},
+	TaskStatusAck:       {TaskStatusDone, TaskStatusDismissed},
+	TaskStatusDone:      {TaskStatusOpen},
+	TaskStatusDismissed: {TaskStatusOpen},
+}
+
+// IsTaskFinished reports whether a task status ends the task, that is whether
+// the task is done or dismissed.
+func IsTaskFinished(status string) bool {
+	return status == TaskStatusDone || status == TaskStatusDismissed
+}
+
+// CanTransitionTask reports whether a task may move from one status to another.
+func CanTransitionTask(from, to string) bool {
+	return slices.Contains(taskTransitions[from], to)
+}
+
+// transitionSources returns the statuses a task may move to status from.
+func transitionSources(status string) []string {
+	var sources []string
+	for _, from := range TaskStatuses {
+		if CanTransitionTask(from, status) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+// TaskTransitionError reports a task status change that the task lifecycle
+// doesn't allow, such as acknowledging a task that is already done.
+type TaskTransitionError struct {
+	TaskID int64
+	From   string
+	To     string
+}
+
+func (e *TaskTransitionError) Error() string {
+	return fmt.Sprintf("task %d cannot move from %s to %s", e.TaskID, e.From, e.To)
+}
+
+// maxRepositoryLength is the longest "owner/name" GitHub allows: a 39
+// character owner and a 100 character name.
+const maxRepositoryLength = 39 + 1 + 100
+
+// InvalidRepositoryError reports a repository full name that isn't
+// "owner/name", such as one with a missing part, more parts or too long.
+type InvalidRepositoryError struct {
+	Repo string
+}
+
+func (e *InvalidRepositoryError) Error() string {
+	return fmt.Sprintf("invalid repository format: %.*s, expected owner/repo", maxRepositoryLength, e.Repo)
+}
+
 type OnCallTask struct {
 	ID          int64
 	ScheduleID  int64
@@ -51,5 +155,45 @@ type OnCallTask struct {
 	AssignedTo  int64
 	CreatedAt   time.Time
 	AckedAt     *time.Time
+	// AckedBy is the GitHub login that acknowledged the task, empty if none.
+	AckedBy string
+	// CompletedAt is when the task was last done or dismissed.
 	CompletedAt *time.Time
+	// EscalationTier is the last escalation tier notified, 0 if none.
+	EscalationTier int
+	// StatusCommentID is the comment edited on each status change when
+	// sticky status comments are enabled, 0 if none was posted yet.
+	StatusCommentID int64
+	// StatusCommentMention is who the status comment mentions, which a new
+	// status must match to edit it.
+	StatusCommentMention string
+	// SnoozedUntil pauses escalation of the task until then, nil if never
+	// snoozed.
+	SnoozedUntil *time.Time
+}
+
+// Snoozed reports whether escalation of the task is paused at now.
+func (t *OnCallTask) Snoozed(now time.Time) bool {
+	return t.SnoozedUntil != nil && now.Before(*t.SnoozedUntil)
+}
+
+// OnCallEscalationTier is one step of a schedule's escalation chain. A task that
+// is still unacknowledged After its creation is escalated to whoever is on call
+// for TargetScheduleID, or to Target for tiers without a schedule or when no
+// one is on call for it.
+type OnCallEscalationTier struct {
+	ScheduleID       int64
+	Level            int
+	Target           string
+	After            time.Duration
+	TargetScheduleID int64
+}
+
+// OnCallRepoSetting overrides the configured repository list for one
+// repository, as set with /oncall enable or /oncall disable.
+type OnCallRepoSetting struct {
+	Repo      string
+	Enabled   bool
+	UpdatedBy string
+	UpdatedAt time.Time
 }
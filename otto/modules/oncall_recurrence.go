@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_recurrence.go evaluates the recurrence rules that define when a
+// schedule hands off to its next user.
+
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies supported in rules.
+const (
+	RecurrenceDaily  = "DAILY"
+	RecurrenceWeekly = "WEEKLY"
+)
+
+// recurrenceWeekdays maps RFC 5545 day codes to weekdays.
+var recurrenceWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Recurrence is the subset of RFC 5545 recurrence rules used for handoffs, such
+// as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO;BYHOUR=9". Times are in UTC.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	// ByDay is the handoff weekday for weekly rules.
+	ByDay  time.Weekday
+	Hour   int
+	Minute int
+}
+
+// ParseRecurrence parses and validates a recurrence rule. INTERVAL defaults to
+// 1, BYDAY to MO and BYHOUR and BYMINUTE to 0.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	r := Recurrence{Interval: 1, ByDay: time.Monday}
+	hasByDay := false
+
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return Recurrence{}, fmt.Errorf("invalid recurrence rule %q: %q is not KEY=VALUE", rule, part)
+		}
+		key, value = strings.ToUpper(key), strings.ToUpper(value)
+
+		var err error
+		switch key {
+		case "FREQ":
+			if value != RecurrenceDaily && value != RecurrenceWeekly {
+				return Recurrence{}, fmt.Errorf("invalid recurrence rule %q: unsupported FREQ %q", rule, value)
+			}
+			r.Freq = value
+		case "INTERVAL":
+			r.Interval, err = parseRecurrenceInt(value, 1, 52)
+		case "BYDAY":
+			day, ok := recurrenceWeekdays[value]
+			if !ok {
+				return Recurrence{}, fmt.Errorf("invalid recurrence rule %q: unknown BYDAY %q", rule, value)
+			}
+			r.ByDay, hasByDay = day, true
+		case "BYHOUR":
+			r.Hour, err = parseRecurrenceInt(value, 0, 23)
+		case "BYMINUTE":
+			r.Minute, err = parseRecurrenceInt(value, 0, 59)
+		default:
+			return Recurrence{}, fmt.Errorf("invalid recurrence rule %q: unsupported part %q", rule, key)
+		}
+		if err != nil {
+			return Recurrence{}, fmt.Errorf("invalid recurrence rule %q: %s: %w", rule, key, err)
+		}
+	}
+
+	if r.Freq == "" {
+		return Recurrence{}, fmt.Errorf("invalid recurrence rule %q: FREQ is required", rule)
+	}
+	if hasByDay && r.Freq != RecurrenceWeekly {
+		return Recurrence{}, fmt.Errorf("invalid recurrence rule %q: BYDAY requires FREQ=WEEKLY", rule)
+	}
+	return r, nil
+}
+
+// parseRecurrenceInt parses an integer rule value within [minValue, maxValue].
+func parseRecurrenceInt(value string, minValue, maxValue int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < minValue || n > maxValue {
+		return 0, fmt.Errorf("%d is outside [%d, %d]", n, minValue, maxValue)
+	}
+	return n, nil
+}
+
+// Occurrences returns the handoff times in [from, until). The cadence starts
+// at the first matching time at or after anchor, so an every-other-week rule
+// keeps the same phase regardless of from.
+func (r Recurrence) Occurrences(anchor, from, until time.Time) []time.Time {
+	anchor = anchor.UTC()
+	first := time.Date(anchor.Year(), anchor.Month(), anchor.Day(), r.Hour, r.Minute, 0, 0, time.UTC)
+	days := r.Interval
+	if r.Freq == RecurrenceWeekly {
+		first = first.AddDate(0, 0, (int(r.ByDay)-int(first.Weekday())+7)%7)
+		days *= 7
+	}
+	if first.Before(anchor) {
+		first = first.AddDate(0, 0, days)
+	}
+
+	// Skip whole periods before from
+	next := first
+	if next.Before(from) {
+		period := time.Duration(days) * 24 * time.Hour
+		skip := int(from.Sub(next) / period)
+		next = next.AddDate(0, 0, skip*days)
+		if next.Before(from) {
+			next = next.AddDate(0, 0, days)
+		}
+	}
+
+	var occurrences []time.Time
+	for ; next.Before(until); next = next.AddDate(0, 0, days) {
+		occurrences = append(occurrences, next)
+	}
+	return occurrences
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package modules
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    Recurrence
+		wantErr string
+	}{
+		{
+			rule: "FREQ=WEEKLY;BYDAY=MO;BYHOUR=9",
+			want: Recurrence{Freq: RecurrenceWeekly, Interval: 1, ByDay: time.Monday, Hour: 9},
+		},
+		{
+			rule: "freq=weekly;interval=2;byday=fr;byhour=17;byminute=30",
+			want: Recurrence{Freq: RecurrenceWeekly, Interval: 2, ByDay: time.Friday, Hour: 17, Minute: 30},
+		},
+		{
+			rule: "FREQ=DAILY",
+			want: Recurrence{Freq: RecurrenceDaily, Interval: 1, ByDay: time.Monday},
+		},
+		{rule: "BYDAY=MO", wantErr: "FREQ is required"},
+		{rule: "FREQ=MONTHLY", wantErr: "unsupported FREQ"},
+		{rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: "unknown BYDAY"},
+		{rule: "FREQ=WEEKLY;BYHOUR=24", wantErr: "BYHOUR"},
+		{rule: "FREQ=WEEKLY;INTERVAL=0", wantErr: "INTERVAL"},
+		{rule: "FREQ=DAILY;BYDAY=MO", wantErr: "BYDAY requires FREQ=WEEKLY"},
+		{rule: "FREQ=WEEKLY;COUNT=3", wantErr: "unsupported part"},
+		{rule: "weekly", wantErr: "not KEY=VALUE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := ParseRecurrence(tt.rule)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("want error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrence failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRecurrence: want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	// Wednesday
+	anchor := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2025, month, d, 9, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		from  time.Time
+		until time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "weekly",
+			rule:  "FREQ=WEEKLY;BYDAY=MO;BYHOUR=9",
+			from:  anchor,
+			until: day(1, 27),
+			want:  []time.Time{day(1, 6), day(1, 13), day(1, 20)},
+		},
+		{
+			name:  "bi-weekly",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO;BYHOUR=9",
+			from:  anchor,
+			until: day(2, 10),
+			want:  []time.Time{day(1, 6), day(1, 20), day(2, 3)},
+		},
+		{
+			name:  "bi-weekly keeps its phase after the anchor",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO;BYHOUR=9",
+			from:  day(1, 8),
+			until: day(2, 4),
+			want:  []time.Time{day(1, 20), day(2, 3)},
+		},
+		{
+			name:  "from on an occurrence includes it",
+			rule:  "FREQ=WEEKLY;BYDAY=MO;BYHOUR=9",
+			from:  day(1, 13),
+			until: day(1, 14),
+			want:  []time.Time{day(1, 13)},
+		},
+		{
+			name:  "daily",
+			rule:  "FREQ=DAILY;BYHOUR=9",
+			from:  anchor,
+			until: day(1, 4),
+			want:  []time.Time{day(1, 2), day(1, 3)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRecurrence failed: %v", err)
+			}
+			got := r.Occurrences(anchor, tt.from, tt.until)
+			if len(got) != len(tt.want) {
+				t.Fatalf("want %d occurrences %v, got %v", len(tt.want), tt.want, got)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d: want %v, got %v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_repos.go decides which repositories the module acts on, combining the
+// configured list with settings made by /oncall enable and /oncall disable.
+
+package modules
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal/cache"
+)
+
+// repoSettingsCache returns the cache of stored repository settings, which
+// spares events a query each. It is keyed by lower-cased repository name, and
+// a nil entry means the repository has no stored setting. Settings only change
+// through this module, which updates the cache as it writes them, so entries
+// don't expire.
+func (o *OnCallModule) repoSettingsCache() *cache.TTL[string, *OnCallRepoSetting] {
+	o.initCaches()
+	return o.repoSettings
+}
+
+// repositoryScheduleName returns the name of the schedule that handles a
+// repository's tasks: its default schedule, or the fallback schedule if the
+// default one doesn't exist.
+func (o *OnCallModule) repositoryScheduleName(db *sql.DB, repo string) (string, error) {
+	name, err := o.config.DefaultScheduleName(repo)
+	if err != nil {
+		return "", err
+	}
+	if o.config.FallbackSchedule == "" {
+		return name, nil
+	}
+	schedule, err := o.scheduleByName(db, name)
+	if err != nil {
+		return "", err
+	}
+	if schedule == nil {
+		return o.config.FallbackSchedule, nil
+	}
+	return name, nil
+}
+
+// isRepositoryEnabled reports whether the module acts on repo. A setting made
+// with /oncall enable or /oncall disable wins over the configured list.
+func (o *OnCallModule) isRepositoryEnabled(db *sql.DB, repo string) bool {
+	setting, ok := o.repoSettingsCache().Get(strings.ToLower(repo))
+	if !ok {
+		var err error
+		setting, err = GetRepoSetting(db, repo)
+		if err != nil {
+			o.log().Error("Failed to load repository setting, using configuration",
+				"repo", repo,
+				"error", err)
+			return o.config.IsRepositoryEnabled(repo)
+		}
+		o.repoSettingsCache().Set(strings.ToLower(repo), setting)
+	}
+	if setting != nil {
+		return setting.Enabled
+	}
+	return o.config.IsRepositoryEnabled(repo)
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_schedule_cache.go optionally caches schedule lookups by name, which
+// otherwise hit the database on every command in a repository.
+
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal/cache"
+)
+
+// DefaultScheduleCacheSize is how many schedule lookups are cached when the
+// cache is enabled without a size.
+const DefaultScheduleCacheSize = 256
+
+// scheduleCache returns the cache of schedule lookups by name, which also
+// remembers names with no schedule as nil entries until they expire. Every
+// schedule write made by the module invalidates the schedule right away;
+// changes made elsewhere show once entries expire.
+func (o *OnCallModule) scheduleCache() *cache.TTL[string, *OnCallSchedule] {
+	o.initCaches()
+	return o.schedules
+}
+
+// scheduleByName returns the schedule named name, or nil if none exists,
+// through the schedule cache when it is enabled. The returned schedule may be
+// shared and must not be modified.
+func (o *OnCallModule) scheduleByName(db *sql.DB, name string) (*OnCallSchedule, error) {
+	if o.config.ScheduleCacheTTL <= 0 {
+		return GetScheduleByName(db, name)
+	}
+	schedules := o.scheduleCache()
+	if schedule, ok := schedules.Get(name); ok {
+		return schedule, nil
+	}
+	schedule, err := GetScheduleByName(db, name)
+	if err != nil {
+		return nil, err
+	}
+	schedules.Set(name, schedule)
+	return schedule, nil
+}
+
+// currentOnCallUser returns the user on call for the schedule named name,
+// reading the schedule through the schedule cache like scheduleByName.
+func (o *OnCallModule) currentOnCallUser(db *sql.DB, name string) (*OnCallUser, error) {
+	schedule, err := o.scheduleByName(db, name)
+	if err != nil || schedule == nil {
+		return nil, fmt.Errorf("schedule not found: %s", name)
+	}
+	return CurrentOnCallUser(db, schedule)
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// oncall_seed.go creates the schedules declared in the oncall configuration,
+// so that they can be managed declaratively instead of by hand.
+
+package modules
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ScheduleSeed declares a schedule and its members in OnCallConfig.Seed.
+type ScheduleSeed struct {
+	// Name is the schedule's name.
+	Name string `yaml:"name"`
+
+	// Policy is the rotation policy: round-robin, sequential or random.
+	// Defaults to round-robin.
+	Policy string `yaml:"policy"`
+
+	// Recurrence is the handoff rule of a newly created schedule, such as
+	// "FREQ=WEEKLY;BYDAY=MO;BYHOUR=9". Empty means manual handoffs.
+	Recurrence string `yaml:"recurrence"`
+
+	// Members lists GitHub logins in rotation order. Users that don't exist
+	// yet are created.
+	Members []string `yaml:"members"`
+}
+
+// validate reports a missing name, an unknown policy, a malformed recurrence
+// rule or an invalid member login.
+func (s ScheduleSeed) validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("seed schedules must have a name")
+	}
+	switch OnCallScheduleRotationPolicy(s.Policy) {
+	case "", RoundRobinPolicy, SequentialPolicy, RandomPolicy:
+	default:
+		return fmt.Errorf("seed schedule %q: unknown policy %q", s.Name, s.Policy)
+	}
+	if s.Recurrence != "" {
+		if _, err := ParseRecurrence(s.Recurrence); err != nil {
+			return fmt.Errorf("seed schedule %q: %w", s.Name, err)
+		}
+	}
+	for _, member := range s.Members {
+		login := strings.TrimPrefix(member, "@")
+		if len(login) > maxGitHubLoginLength || !githubLoginPattern.MatchString(login) {
+			return fmt.Errorf("seed schedule %q: %q is not a valid GitHub login", s.Name, member)
+		}
+	}
+	return nil
+}
+
+// SeedSchedules creates the seeded schedules that don't exist yet, matching
+// names ignoring case, and adds seeded members missing from each schedule to
+// the end of its rotation. Existing schedules keep their policy, recurrence
+// and other members, so seeding again changes nothing.
+func SeedSchedules(db *sql.DB, seeds []ScheduleSeed) error {
+	for _, seed := range seeds {
+		schedule, err := FindScheduleByName(db, seed.Name)
+		if err != nil {
+			return fmt.Errorf("failed to find schedule %s: %w", seed.Name, err)
+		}
+		if schedule == nil {
+			if schedule, err = AddSchedule(db, seed.Name, seed.Policy); err != nil {
+				return fmt.Errorf("failed to create schedule %s: %w", seed.Name, err)
+			}
+			if seed.Recurrence != "" {
+				if err := SetScheduleRecurrence(db, schedule.ID, seed.Recurrence); err != nil {
+					return fmt.Errorf("failed to set recurrence of schedule %s: %w", seed.Name, err)
+				}
+			}
+		}
+		if err := seedMembers(db, schedule.ID, seed.Members); err != nil {
+			return fmt.Errorf("failed to add members to schedule %s: %w", seed.Name, err)
+		}
+	}
+	return nil
+}
+
+// seedMembers adds the users with the given logins to a schedule unless they
+// are already assigned, creating users that don't exist.
+func seedMembers(db *sql.DB, scheduleID int64, logins []string) error {
+	assigned, err := ListUsersForSchedule(db, scheduleID)
+	if err != nil {
+		return err
+	}
+	members := make(map[int64]bool, len(assigned))
+	position := 0
+	for _, rel := range assigned {
+		members[rel.UserID] = true
+		position = max(position, rel.Position+1)
+	}
+
+	for _, login := range logins {
+		login = strings.TrimPrefix(login, "@")
+		user, err := GetUserByGitHub(db, login)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			if user, err = AddUser(db, login, login); err != nil {
+				return err
+			}
+		}
+		if members[user.ID] {
+			continue
+		}
+		if err := AssignUserToSchedule(db, scheduleID, user.ID, position); err != nil {
+			return err
+		}
+		members[user.ID] = true
+		position++
+	}
+	return nil
+}
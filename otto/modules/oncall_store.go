@@ -3,23 +3,121 @@
 package modules
 
 import (
+	"cmp"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Migration, AddUser, AddSchedule, AssignUserToSchedule, etc.
 
+// dbTimeLayout is the format timestamps are written in. Values are stored in UTC
+// with a fixed width so that they sort and compare correctly as text, and the
+// layout is one the SQLite drivers parse back into time.Time when scanning.
+const dbTimeLayout = "2006-01-02 15:04:05.000000000-07:00"
+
+// formatDBTime formats t for storage in a TIMESTAMP column.
+func formatDBTime(t time.Time) string {
+	return t.UTC().Format(dbTimeLayout)
+}
+
+// dbTimeLayouts are the layouts parseDBTime accepts, in order. The last one is
+// time.Time's String format, which older versions wrote through the driver.
+var dbTimeLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05-07:00",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+}
+
+// parseDBTime parses a timestamp read from a TIMESTAMP column.
+func parseDBTime(s string) (time.Time, error) {
+	// Drop the monotonic clock reading included by time.Time's String format
+	if i := strings.Index(s, " m="); i >= 0 {
+		s = s[:i]
+	}
+	for _, layout := range dbTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp format: %q", s)
+}
+
+// scanDBTime converts a TIMESTAMP column value, which the driver may return as
+// time.Time or as text. ok is false for NULL.
+func scanDBTime(src any) (t time.Time, ok bool, err error) {
+	switch v := src.(type) {
+	case nil:
+		return time.Time{}, false, nil
+	case time.Time:
+		return v, true, nil
+	case string:
+		t, err = parseDBTime(v)
+	case []byte:
+		t, err = parseDBTime(string(v))
+	default:
+		return time.Time{}, false, fmt.Errorf("unsupported timestamp type %T", src)
+	}
+	return t, err == nil, err
+}
+
+// dbTime scans a TIMESTAMP column into a time.Time, leaving it zero for NULL.
+type dbTime struct{ t *time.Time }
+
+// Scan implements sql.Scanner.
+func (d dbTime) Scan(src any) error {
+	t, _, err := scanDBTime(src)
+	if err != nil {
+		return err
+	}
+	*d.t = t
+	return nil
+}
+
+// dbTimePtr scans a nullable TIMESTAMP column into a *time.Time.
+type dbTimePtr struct{ t **time.Time }
+
+// Scan implements sql.Scanner.
+func (d dbTimePtr) Scan(src any) error {
+	t, ok, err := scanDBTime(src)
+	if err != nil {
+		return err
+	}
+	*d.t = nil
+	if ok {
+		*d.t = &t
+	}
+	return nil
+}
+
 func AutoMigrateOnCall(db *sql.DB) error {
+	// Command runs were once recorded per user. They only matter for a
+	// cooldown window, so the old table is dropped rather than converted.
+	perUser, err := hasColumn(db, "oncall_command_cooldowns", "user")
+	if err != nil {
+		return err
+	}
+	if perUser {
+		if _, err := db.Exec(`DROP TABLE oncall_command_cooldowns`); err != nil {
+			return fmt.Errorf("failed migration: %w", err)
+		}
+	}
+
 	stmts := []string{
 		`CREATE TABLE IF NOT EXISTS oncall_users (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			github TEXT UNIQUE NOT NULL,
 			display_name TEXT,
 			active BOOLEAN NOT NULL DEFAULT 1,
-			created_at TIMESTAMP NOT NULL
+			created_at TIMESTAMP NOT NULL,
+			last_active_at TIMESTAMP
 		);`,
 		`CREATE TABLE IF NOT EXISTS oncall_schedules (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -27,6 +125,7 @@ func AutoMigrateOnCall(db *sql.DB) error {
 			policy TEXT NOT NULL,
 			enabled BOOLEAN NOT NULL DEFAULT 1,
 			current_rotation_idx INTEGER NOT NULL DEFAULT 0,
+			recurrence TEXT NOT NULL DEFAULT '',
 			created_at TIMESTAMP NOT NULL,
 			updated_at TIMESTAMP NOT NULL
 		);`,
@@ -50,25 +149,140 @@ func AutoMigrateOnCall(db *sql.DB) error {
 			created_at TIMESTAMP NOT NULL,
 			acked_at TIMESTAMP,
 			completed_at TIMESTAMP,
+			escalation_tier INTEGER NOT NULL DEFAULT 0,
+			acked_by TEXT NOT NULL DEFAULT '',
+			status_comment_id INTEGER NOT NULL DEFAULT 0,
+			status_comment_mention TEXT NOT NULL DEFAULT '',
+			snoozed_until TIMESTAMP,
 			FOREIGN KEY(schedule_id) REFERENCES oncall_schedules(id),
 			FOREIGN KEY(assigned_to) REFERENCES oncall_users(id)
 		);`,
+		`CREATE TABLE IF NOT EXISTS oncall_escalation_tiers (
+			schedule_id INTEGER NOT NULL,
+			level INTEGER NOT NULL,
+			target TEXT NOT NULL,
+			after_minutes INTEGER NOT NULL,
+			target_schedule_id INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (schedule_id, level),
+			FOREIGN KEY(schedule_id) REFERENCES oncall_schedules(id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS oncall_repo_settings (
+			repo TEXT PRIMARY KEY,
+			enabled BOOLEAN NOT NULL,
+			updated_by TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS oncall_deliveries (
+			delivery_id TEXT PRIMARY KEY,
+			received_at TIMESTAMP NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS oncall_deliveries_received_at ON oncall_deliveries (received_at);`,
+		`CREATE TABLE IF NOT EXISTS oncall_command_cooldowns (
+			repo TEXT NOT NULL,
+			issue_num INTEGER NOT NULL,
+			command TEXT NOT NULL,
+			last_run_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (repo, issue_num, command)
+		);`,
+		`CREATE INDEX IF NOT EXISTS oncall_command_cooldowns_last_run_at
+			ON oncall_command_cooldowns (last_run_at);`,
 	}
 	for _, s := range stmts {
 		if _, err := db.Exec(s); err != nil {
 			return fmt.Errorf("failed migration: %w (SQL: %s)", err, s)
 		}
 	}
+
+	// Columns added after the initial schema
+	if err := addColumnIfMissing(db, "oncall_users", "last_active_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(
+		db, "oncall_tasks", "escalation_tier", "INTEGER NOT NULL DEFAULT 0",
+	); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "oncall_schedules", "recurrence", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "oncall_schedules", "last_handoff_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "oncall_tasks", "acked_by", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(
+		db, "oncall_tasks", "status_comment_id", "INTEGER NOT NULL DEFAULT 0",
+	); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "oncall_tasks", "snoozed_until", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(
+		db, "oncall_tasks", "status_comment_mention", "TEXT NOT NULL DEFAULT ''",
+	); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(
+		db, "oncall_escalation_tiers", "target_schedule_id", "INTEGER NOT NULL DEFAULT 0",
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already present.
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	exists, err := hasColumn(db, table, column)
+	if err != nil || exists {
+		return err
+	}
+
+	stmt := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("failed migration: %w (SQL: %s)", err, stmt)
+	}
 	return nil
 }
 
+// hasColumn reports whether a table has a column. A missing table has none.
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	return false, nil
+}
+
 func AddUser(db *sql.DB, gh, name string) (*OnCallUser, error) {
 	now := time.Now()
 	res, err := db.Exec(
 		`INSERT INTO oncall_users (github, display_name, active, created_at) VALUES (?, ?, 1, ?)`,
 		gh,
 		name,
-		now,
+		formatDBTime(now),
 	)
 	if err != nil {
 		return nil, err
@@ -77,6 +291,108 @@ func AddUser(db *sql.DB, gh, name string) (*OnCallUser, error) {
 	return &OnCallUser{ID: id, GitHub: gh, DisplayName: name, Active: true, CreatedAt: now}, nil
 }
 
+// GetUserByGitHub returns the user with the given GitHub login, ignoring case,
+// or nil if there is none.
+func GetUserByGitHub(db *sql.DB, gh string) (*OnCallUser, error) {
+	var u OnCallUser
+	err := db.QueryRow(
+		`SELECT id, github, display_name, active, created_at, last_active_at FROM oncall_users
+		 WHERE github = ? COLLATE NOCASE`,
+		gh,
+	).Scan(&u.ID, &u.GitHub, &u.DisplayName, &u.Active, dbTime{&u.CreatedAt}, dbTime{&u.LastActiveAt})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ImportUsers adds users in one transaction, skipping those whose GitHub login
+// already exists ignoring case, and reports how many were created and skipped.
+// Nothing is added if any insert fails.
+func ImportUsers(db *sql.DB, users []OnCallUser, now time.Time) (created, skipped int, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.Error("Failed to rollback transaction", "error", err)
+		}
+	}()
+
+	for _, u := range users {
+		var exists bool
+		err := tx.QueryRow(
+			`SELECT EXISTS (SELECT 1 FROM oncall_users WHERE github = ? COLLATE NOCASE)`,
+			u.GitHub,
+		).Scan(&exists)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to look up user %s: %w", u.GitHub, err)
+		}
+		if exists {
+			skipped++
+			continue
+		}
+		_, err = tx.Exec(
+			`INSERT INTO oncall_users (github, display_name, active, created_at) VALUES (?, ?, 1, ?)`,
+			u.GitHub,
+			u.DisplayName,
+			formatDBTime(now),
+		)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to add user %s: %w", u.GitHub, err)
+		}
+		created++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, skipped, nil
+}
+
+// MarkUserActive records that the user with the given GitHub login was active at t.
+func MarkUserActive(db *sql.DB, gh string, t time.Time) error {
+	_, err := db.Exec(`UPDATE oncall_users SET last_active_at = ? WHERE github = ?`, formatDBTime(t), gh)
+	return err
+}
+
+// FindInactiveUsersSince returns active users who have not acknowledged anything
+// since t, including users who were never active.
+func FindInactiveUsersSince(db *sql.DB, t time.Time) ([]OnCallUser, error) {
+	rows, err := db.Query(
+		`SELECT id, github, display_name, active, created_at, last_active_at FROM oncall_users
+		 WHERE active = 1 AND (last_active_at IS NULL OR last_active_at < ?)
+		 ORDER BY github ASC`,
+		formatDBTime(t),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []OnCallUser
+	for rows.Next() {
+		var u OnCallUser
+		err := rows.Scan(
+			&u.ID,
+			&u.GitHub,
+			&u.DisplayName,
+			&u.Active,
+			dbTime{&u.CreatedAt},
+			dbTime{&u.LastActiveAt},
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
 func AddSchedule(db *sql.DB, name, policyStr string) (*OnCallSchedule, error) {
 	now := time.Now()
 
@@ -97,8 +413,8 @@ func AddSchedule(db *sql.DB, name, policyStr string) (*OnCallSchedule, error) {
 		`INSERT INTO oncall_schedules (name, policy, enabled, current_rotation_idx, created_at, updated_at) VALUES (?, ?, 1, 0, ?, ?)`,
 		name,
 		string(policy),
-		now,
-		now,
+		formatDBTime(now),
+		formatDBTime(now),
 	)
 	if err != nil {
 		return nil, err
@@ -123,11 +439,17 @@ func AssignUserToSchedule(db *sql.DB, scheduleID, userID int64, position int) er
 	return err
 }
 
-func GetScheduleByName(db *sql.DB, name string) (*OnCallSchedule, error) {
-	row := db.QueryRow(
-		`SELECT id, name, policy, enabled, current_rotation_idx, created_at, updated_at FROM oncall_schedules WHERE name = ?`,
-		name,
-	)
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scheduleColumns lists the oncall_schedules columns read by scanSchedule, in order.
+const scheduleColumns = `id, name, policy, enabled, current_rotation_idx, recurrence, last_handoff_at,
+	created_at, updated_at`
+
+// scanSchedule reads a schedule selected with scheduleColumns.
+func scanSchedule(row rowScanner) (*OnCallSchedule, error) {
 	var s OnCallSchedule
 	err := row.Scan(
 		&s.ID,
@@ -135,13 +457,167 @@ func GetScheduleByName(db *sql.DB, name string) (*OnCallSchedule, error) {
 		&s.Policy,
 		&s.Enabled,
 		&s.CurrentRotationIdx,
-		&s.CreatedAt,
-		&s.UpdatedAt,
+		&s.Recurrence,
+		dbTimePtr{&s.LastHandoffAt},
+		dbTime{&s.CreatedAt},
+		dbTime{&s.UpdatedAt},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func GetScheduleByName(db *sql.DB, name string) (*OnCallSchedule, error) {
+	row := db.QueryRow(`SELECT `+scheduleColumns+` FROM oncall_schedules WHERE name = ?`, name)
+	s, err := scanSchedule(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// GetSchedule returns the schedule with the given ID, or nil if there is none.
+func GetSchedule(db *sql.DB, id int64) (*OnCallSchedule, error) {
+	row := db.QueryRow(`SELECT `+scheduleColumns+` FROM oncall_schedules WHERE id = ?`, id)
+	s, err := scanSchedule(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// SetScheduleRecurrence sets the handoff rule of a schedule. An empty rule
+// makes handoffs manual again.
+func SetScheduleRecurrence(db *sql.DB, scheduleID int64, rule string) error {
+	if rule != "" {
+		if _, err := ParseRecurrence(rule); err != nil {
+			return err
+		}
+	}
+	_, err := db.Exec(
+		`UPDATE oncall_schedules SET recurrence = ?, updated_at = ? WHERE id = ?`,
+		rule,
+		formatDBTime(time.Now()),
+		scheduleID,
+	)
+	return err
+}
+
+// ListRecurringSchedules returns the enabled schedules that have a handoff
+// rule, ordered by name.
+func ListRecurringSchedules(db *sql.DB) ([]OnCallSchedule, error) {
+	rows, err := db.Query(
+		`SELECT ` + scheduleColumns + ` FROM oncall_schedules WHERE enabled = 1 AND recurrence != '' ORDER BY name ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var schedules []OnCallSchedule
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *s)
+	}
+	return schedules, rows.Err()
+}
+
+// HandOffSchedule sets a schedule's rotation index to idx and its last
+// handoff to at, unless its last handoff is no longer prev, such as when
+// another instance sharing the database handed it off first. A nil prev
+// matches a schedule never handed off. It reports whether it did.
+func HandOffSchedule(db *sql.DB, scheduleID int64, prev *time.Time, at time.Time, idx int) (bool, error) {
+	var prevValue string
+	if prev != nil {
+		prevValue = formatDBTime(*prev)
+	}
+	res, err := db.Exec(
+		`UPDATE oncall_schedules SET current_rotation_idx = ?, last_handoff_at = ?, updated_at = ?
+		 WHERE id = ? AND COALESCE(last_handoff_at, '') = ?`,
+		idx,
+		formatDBTime(at),
+		formatDBTime(time.Now()),
+		scheduleID,
+		prevValue,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// GenerateUpcomingHandoffs returns who is on call for a schedule from from
+// until horizon later, following the schedule's recurrence rule and user order
+// starting with the current on-call user. Like the handoffs themselves, it
+// skips inactive members.
+func GenerateUpcomingHandoffs(
+	db *sql.DB,
+	scheduleID int64,
+	from time.Time,
+	horizon time.Duration,
+) ([]OnCallHandoff, error) {
+	schedule, err := GetSchedule(db, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, fmt.Errorf("no schedule found with id %d", scheduleID)
+	}
+	if schedule.Recurrence == "" {
+		return nil, fmt.Errorf("schedule %s has no recurrence rule", schedule.Name)
+	}
+	rule, err := ParseRecurrence(schedule.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	members, err := ListScheduleMembers(db, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no users found in schedule: %s", schedule.Name)
+	}
+
+	// Hand off like advanceRotation: to the next active member, or to no one
+	// else when none is active
+	until := from.Add(horizon)
+	idx := schedule.CurrentRotationIdx % len(members)
+	start := from
+	var handoffs []OnCallHandoff
+	for _, at := range rule.Occurrences(schedule.CreatedAt, from, until) {
+		if !at.After(start) {
+			continue
+		}
+		handoffs = append(handoffs, OnCallHandoff{UserID: members[idx].ID, Start: start, End: at})
+		if next, ok := nextActiveMember(members, idx); ok {
+			idx = next
+		}
+		start = at
+	}
+	if until.After(start) {
+		handoffs = append(handoffs, OnCallHandoff{UserID: members[idx].ID, Start: start, End: until})
+	}
+	return handoffs, nil
+}
+
+// FindScheduleByName looks a schedule up by name ignoring case, for names typed
+// in commands. An exact match wins over other case variants.
+func FindScheduleByName(db *sql.DB, name string) (*OnCallSchedule, error) {
+	row := db.QueryRow(
+		`SELECT `+scheduleColumns+` FROM oncall_schedules WHERE LOWER(name) = LOWER(?)
+		 ORDER BY name = ? DESC LIMIT 1`,
+		name,
+		name,
 	)
+	s, err := scanSchedule(row)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	return &s, err
+	return s, err
 }
 
 func GetCurrentOnCallUser(db *sql.DB, scheduleName string) (*OnCallUser, error) {
@@ -150,11 +626,15 @@ func GetCurrentOnCallUser(db *sql.DB, scheduleName string) (*OnCallUser, error)
 	if err != nil || schedule == nil {
 		return nil, fmt.Errorf("schedule not found: %s", scheduleName)
 	}
+	return CurrentOnCallUser(db, schedule)
+}
 
+// CurrentOnCallUser returns the user on call for an already loaded schedule.
+func CurrentOnCallUser(db *sql.DB, schedule *OnCallSchedule) (*OnCallUser, error) {
 	// Get users in the schedule
 	users, err := ListUsersForSchedule(db, schedule.ID)
 	if err != nil || len(users) == 0 {
-		return nil, fmt.Errorf("no users found in schedule: %s", scheduleName)
+		return nil, fmt.Errorf("no users found in schedule: %s", schedule.Name)
 	}
 
 	// For round-robin, use current rotation index
@@ -164,7 +644,7 @@ func GetCurrentOnCallUser(db *sql.DB, scheduleName string) (*OnCallUser, error)
 		idx := schedule.CurrentRotationIdx % len(users)
 		currentUserSchedule := users[idx]
 		row := db.QueryRow(
-			`SELECT id, github, display_name, active, created_at FROM oncall_users WHERE id = ?`,
+			`SELECT id, github, display_name, active, created_at, last_active_at FROM oncall_users WHERE id = ?`,
 			currentUserSchedule.UserID,
 		)
 		err = row.Scan(
@@ -172,7 +652,8 @@ func GetCurrentOnCallUser(db *sql.DB, scheduleName string) (*OnCallUser, error)
 			&currentUser.GitHub,
 			&currentUser.DisplayName,
 			&currentUser.Active,
-			&currentUser.CreatedAt,
+			dbTime{&currentUser.CreatedAt},
+			dbTime{&currentUser.LastActiveAt},
 		)
 		if err != nil {
 			return nil, err
@@ -204,13 +685,62 @@ func AdvanceOnCallSchedule(db *sql.DB, scheduleName string) error {
 	_, err = db.Exec(
 		`UPDATE oncall_schedules SET current_rotation_idx = ?, updated_at = ? WHERE id = ?`,
 		newRotationIdx,
-		time.Now(),
+		formatDBTime(time.Now()),
 		schedule.ID,
 	)
 
 	return err
 }
 
+// DeleteSchedule removes a schedule, its user assignments and escalation tiers.
+func DeleteSchedule(db *sql.DB, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.Error("Failed to rollback transaction", "error", err)
+		}
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM oncall_schedules_users WHERE schedule_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete schedule users: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM oncall_escalation_tiers WHERE schedule_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete escalation tiers: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM oncall_schedules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("no schedule found with id %d", id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CountUnfinishedTasksForSchedule returns the number of tasks on a schedule
+// that are neither done nor dismissed.
+func CountUnfinishedTasksForSchedule(db *sql.DB, scheduleID int64) (int, error) {
+	var count int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM oncall_tasks WHERE schedule_id = ? AND status NOT IN (?, ?)`,
+		scheduleID,
+		TaskStatusDone,
+		TaskStatusDismissed,
+	).Scan(&count)
+	return count, err
+}
+
 func ListUsersForSchedule(db *sql.DB, scheduleID int64) ([]OnCallScheduleUser, error) {
 	rows, err := db.Query(
 		`SELECT schedule_id, user_id, position FROM oncall_schedules_users WHERE schedule_id = ? ORDER BY position ASC`,
@@ -231,49 +761,182 @@ func ListUsersForSchedule(db *sql.DB, scheduleID int64) ([]OnCallScheduleUser, e
 	return rels, nil
 }
 
-func AddTask(
-	db *sql.DB,
-	scheduleID int64,
-	repo string,
-	issueNum int,
-	title, description string,
-	assignedTo int64,
-) (*OnCallTask, error) {
-	now := time.Now()
-	res, err := db.Exec(
-		`INSERT INTO oncall_tasks (schedule_id, repo, issue_num, title, description, status, assigned_to, created_at) VALUES (?, ?, ?, ?, ?, 'open', ?, ?)`,
+// ListScheduleMembers returns the users of a schedule in rotation order.
+func ListScheduleMembers(db *sql.DB, scheduleID int64) ([]OnCallUser, error) {
+	rows, err := db.Query(
+		`SELECT u.id, u.github, u.display_name, u.active, u.created_at, u.last_active_at
+		 FROM oncall_schedules_users su
+		 JOIN oncall_users u ON u.id = su.user_id
+		 WHERE su.schedule_id = ? ORDER BY su.position ASC`,
 		scheduleID,
-		repo,
-		issueNum,
-		title,
-		description,
-		assignedTo,
-		now,
 	)
 	if err != nil {
 		return nil, err
 	}
-	id, _ := res.LastInsertId()
-	return &OnCallTask{
-		ID:          id,
-		ScheduleID:  scheduleID,
-		Repo:        repo,
-		IssueNum:    issueNum,
-		Title:       title,
-		Description: description,
-		Status:      "open",
-		AssignedTo:  assignedTo,
-		CreatedAt:   now,
-	}, nil
-}
+	defer rows.Close()
 
-func GetTaskByIssueNumber(db *sql.DB, repo string, issueNum int) (*OnCallTask, error) {
-	row := db.QueryRow(
-		`SELECT id, schedule_id, repo, issue_num, title, description, status, assigned_to, created_at, acked_at, completed_at
-		 FROM oncall_tasks WHERE repo = ? AND issue_num = ?`,
-		repo,
-		issueNum,
+	var users []OnCallUser
+	for rows.Next() {
+		var u OnCallUser
+		err := rows.Scan(&u.ID, &u.GitHub, &u.DisplayName, &u.Active, dbTime{&u.CreatedAt}, dbTime{&u.LastActiveAt})
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// SetScheduleMembers replaces the members of a schedule with the given users,
+// in rotation order, in one transaction. The rotation index is kept, so the
+// member at that position of the new order is on call.
+func SetScheduleMembers(db *sql.DB, scheduleID int64, userIDs []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.Error("Failed to rollback transaction", "error", err)
+		}
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM oncall_schedules_users WHERE schedule_id = ?`, scheduleID); err != nil {
+		return fmt.Errorf("failed to delete schedule users: %w", err)
+	}
+	for position, userID := range userIDs {
+		_, err := tx.Exec(
+			`INSERT INTO oncall_schedules_users (schedule_id, user_id, position) VALUES (?, ?, ?)`,
+			scheduleID, userID, position,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add user %d: %w", userID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// FindCurrentOnCallForAllSchedules returns the current on-call user of every
+// enabled schedule, by schedule name, in one query. It agrees with
+// GetCurrentOnCallUser, which only round-robin schedules have a current user
+// for; other schedules and those without members have no user.
+func FindCurrentOnCallForAllSchedules(db *sql.DB) ([]CurrentOnCallView, error) {
+	rows, err := db.Query(
+		`WITH members AS (
+			SELECT schedule_id, user_id,
+				ROW_NUMBER() OVER (PARTITION BY schedule_id ORDER BY position, user_id) - 1 AS idx,
+				COUNT(*) OVER (PARTITION BY schedule_id) AS n
+			FROM oncall_schedules_users
+		)
+		SELECT s.id, s.name, s.policy, COALESCE(u.github, ''), COALESCE(u.display_name, '')
+		FROM oncall_schedules s
+		LEFT JOIN members m ON m.schedule_id = s.id AND s.policy = ? AND m.idx = s.current_rotation_idx % m.n
+		LEFT JOIN oncall_users u ON u.id = m.user_id
+		WHERE s.enabled
+		ORDER BY s.name ASC`,
+		RoundRobinPolicy,
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []CurrentOnCallView
+	for rows.Next() {
+		var v CurrentOnCallView
+		if err := rows.Scan(&v.ScheduleID, &v.Schedule, &v.Policy, &v.GitHub, &v.DisplayName); err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+// ListSchedulesForUser returns the schedules a user is assigned to, by name.
+func ListSchedulesForUser(db *sql.DB, userID int64) ([]OnCallSchedule, error) {
+	rows, err := db.Query(
+		`SELECT `+prefixColumns("s", scheduleColumns)+` FROM oncall_schedules s
+		 JOIN oncall_schedules_users su ON su.schedule_id = s.id
+		 WHERE su.user_id = ? ORDER BY s.name ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []OnCallSchedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// prefixColumns qualifies a comma-separated column list with a table alias.
+func prefixColumns(alias, columns string) string {
+	fields := strings.Split(columns, ",")
+	for i, f := range fields {
+		fields[i] = alias + "." + strings.TrimSpace(f)
+	}
+	return strings.Join(fields, ", ")
+}
+
+func AddTask(
+	db *sql.DB,
+	scheduleID int64,
+	repo string,
+	issueNum int,
+	title, description string,
+	assignedTo int64,
+) (*OnCallTask, error) {
+	// Reject names GitHub can't have before writing a task that could never
+	// be commented on
+	if _, _, err := splitRepo(repo); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	res, err := db.Exec(
+		`INSERT INTO oncall_tasks (schedule_id, repo, issue_num, title, description, status, assigned_to, created_at) VALUES (?, ?, ?, ?, ?, 'open', ?, ?)`,
+		scheduleID,
+		repo,
+		issueNum,
+		title,
+		description,
+		assignedTo,
+		formatDBTime(now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	return &OnCallTask{
+		ID:          id,
+		ScheduleID:  scheduleID,
+		Repo:        repo,
+		IssueNum:    issueNum,
+		Title:       title,
+		Description: description,
+		Status:      "open",
+		AssignedTo:  assignedTo,
+		CreatedAt:   now,
+	}, nil
+}
+
+// taskColumns lists the oncall_tasks columns read by scanTask, in order.
+const taskColumns = `id, schedule_id, repo, issue_num, title, description, status, assigned_to,
+	created_at, acked_at, completed_at, escalation_tier, acked_by, status_comment_id, snoozed_until,
+	status_comment_mention`
+
+// scanTask reads a task selected with taskColumns.
+func scanTask(row rowScanner) (*OnCallTask, error) {
 	var t OnCallTask
 	err := row.Scan(
 		&t.ID,
@@ -284,23 +947,45 @@ func GetTaskByIssueNumber(db *sql.DB, repo string, issueNum int) (*OnCallTask, e
 		&t.Description,
 		&t.Status,
 		&t.AssignedTo,
-		&t.CreatedAt,
-		&t.AckedAt,
-		&t.CompletedAt,
+		dbTime{&t.CreatedAt},
+		dbTimePtr{&t.AckedAt},
+		dbTimePtr{&t.CompletedAt},
+		&t.EscalationTier,
+		&t.AckedBy,
+		&t.StatusCommentID,
+		dbTimePtr{&t.SnoozedUntil},
+		&t.StatusCommentMention,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func GetTaskByIssueNumber(db *sql.DB, repo string, issueNum int) (*OnCallTask, error) {
+	row := db.QueryRow(
+		`SELECT `+taskColumns+` FROM oncall_tasks WHERE repo = ? AND issue_num = ?`,
+		repo,
+		issueNum,
+	)
+	t, err := scanTask(row)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	return &t, err
+	return t, err
 }
 
+// UpdateTaskStatus moves a task to the ack, done or dismissed status and
+// records when.
+// It returns a *TaskTransitionError if the task's current status can't move
+// to status.
 func UpdateTaskStatus(db *sql.DB, id int64, status string) error {
 	now := time.Now()
 	var tsField string
 	switch status {
 	case "ack":
 		tsField = "acked_at"
-	case "done":
+	case TaskStatusDone, TaskStatusDismissed:
 		tsField = "completed_at"
 	default:
 		return fmt.Errorf("invalid status: %s", status)
@@ -318,21 +1003,8 @@ func UpdateTaskStatus(db *sql.DB, id int64, status string) error {
 	}() // Rollback in case of error, won't do anything if commit succeeds
 
 	// Execute the update
-	result, err := tx.Exec(
-		fmt.Sprintf(`UPDATE oncall_tasks SET status = ?, %s = ? WHERE id = ?`, tsField),
-		status, now, id,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update task status: %w", err)
-	}
-
-	// Check that a row was actually updated
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to check rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("no task found with id %d", id)
+	if err := transitionTask(tx, id, status, tsField+" = ?", formatDBTime(now)); err != nil {
+		return err
 	}
 
 	// Verify the update
@@ -358,26 +1030,472 @@ func UpdateTaskStatus(db *sql.DB, id int64, status string) error {
 }
 
 func GetTask(db *sql.DB, id int64) (*OnCallTask, error) {
-	row := db.QueryRow(
-		`SELECT id, schedule_id, repo, issue_num, title, description, status, assigned_to, created_at, acked_at, completed_at FROM oncall_tasks WHERE id = ?`,
-		id,
+	row := db.QueryRow(`SELECT `+taskColumns+` FROM oncall_tasks WHERE id = ?`, id)
+	t, err := scanTask(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// FindUnacknowledgedTasksOlderThan returns open tasks created before cutoff,
+// oldest first.
+func FindUnacknowledgedTasksOlderThan(db *sql.DB, cutoff time.Time) ([]OnCallTask, error) {
+	rows, err := db.Query(
+		`SELECT `+taskColumns+` FROM oncall_tasks
+		 WHERE status = ? AND created_at < ?
+		 ORDER BY created_at ASC, id ASC`,
+		TaskStatusOpen,
+		formatDBTime(cutoff),
 	)
-	var t OnCallTask
-	err := row.Scan(
-		&t.ID,
-		&t.ScheduleID,
-		&t.Repo,
-		&t.IssueNum,
-		&t.Title,
-		&t.Description,
-		&t.Status,
-		&t.AssignedTo,
-		&t.CreatedAt,
-		&t.AckedAt,
-		&t.CompletedAt,
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []OnCallTask
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *t)
+	}
+	return tasks, rows.Err()
+}
+
+// ErrInvalidCursor is returned for a page cursor that FindTasksAfter didn't
+// produce.
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// encodeTaskCursor returns the opaque cursor of the page after task.
+func encodeTaskCursor(task OnCallTask) string {
+	raw := formatDBTime(task.CreatedAt) + "," + strconv.FormatInt(task.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeTaskCursor returns the creation time, as stored, and ID of the last
+// task of the previous page.
+func decodeTaskCursor(cursor string) (createdAt string, id int64, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	createdAt, idStr, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return "", 0, ErrInvalidCursor
+	}
+	if _, err := time.Parse(dbTimeLayout, createdAt); err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	if id, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
+
+// FindTasksAfter returns up to limit tasks, oldest first, following the page
+// that ended at cursor; an empty cursor starts from the first task. next is
+// the cursor of the following page, empty after the last one. Tasks are
+// ordered by creation time and then ID, so pages neither skip nor repeat
+// tasks as new ones are added.
+func FindTasksAfter(db *sql.DB, cursor string, limit int) (tasks []OnCallTask, next string, err error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("page limit must be positive, got %d", limit)
+	}
+	query := `SELECT ` + taskColumns + ` FROM oncall_tasks`
+	var args []any
+	if cursor != "" {
+		createdAt, id, err := decodeTaskCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += ` WHERE created_at > ? OR (created_at = ? AND id > ?)`
+		args = append(args, createdAt, createdAt, id)
+	}
+	// Read one more task than requested to know whether another page follows
+	query += ` ORDER BY created_at ASC, id ASC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		tasks = append(tasks, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		next = encodeTaskCursor(tasks[limit-1])
+	}
+	return tasks, next, nil
+}
+
+// AcknowledgeTask marks an open task acknowledged by user at the given time.
+func AcknowledgeTask(db *sql.DB, taskID int64, user string, at time.Time) error {
+	return transitionTask(db, taskID, TaskStatusAck, "acked_at = ?, acked_by = ?", formatDBTime(at), user)
+}
+
+// execQuerier is the subset of *sql.DB and *sql.Tx used by transitionTask.
+type execQuerier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// transitionTask moves a task to status and applies the assignments in set,
+// with args bound to their placeholders. It returns a *TaskTransitionError if
+// the task's current status can't move to status.
+func transitionTask(db execQuerier, taskID int64, status, set string, args ...any) error {
+	sources := transitionSources(status)
+	query := `UPDATE oncall_tasks SET status = ?, ` + set +
+		` WHERE id = ? AND status IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(sources)), ", ") + `)`
+	queryArgs := append([]any{status}, args...)
+	queryArgs = append(queryArgs, taskID)
+	for _, from := range sources {
+		queryArgs = append(queryArgs, from)
+	}
+
+	result, err := db.Exec(query, queryArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to update task status: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	// Nothing matched: either the task is missing or its status is wrong
+	var current string
+	err = db.QueryRow(`SELECT status FROM oncall_tasks WHERE id = ?`, taskID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no task found with id %d", taskID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check task status: %w", err)
+	}
+	return &TaskTransitionError{TaskID: taskID, From: current, To: status}
+}
+
+// maxQueryIDs caps the ids bound in a single IN clause. SQLite builds before
+// 3.32 limit a statement to 999 bound variables (SQLITE_MAX_VARIABLE_NUMBER).
+const maxQueryIDs = 999
+
+// inPlaceholders returns a "?, ?, ..." list for an IN clause over ids, with the
+// ids as query arguments.
+func inPlaceholders(ids []int64) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+// GetTasksByIDs returns the tasks with the given ids, ordered by id. Unknown
+// ids are skipped.
+func GetTasksByIDs(db *sql.DB, ids []int64) ([]OnCallTask, error) {
+	var tasks []OnCallTask
+	for chunk := range slices.Chunk(ids, maxQueryIDs) {
+		placeholders, args := inPlaceholders(chunk)
+		rows, err := db.Query(`SELECT `+taskColumns+` FROM oncall_tasks WHERE id IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tasks: %w", err)
+		}
+		for rows.Next() {
+			t, err := scanTask(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			tasks = append(tasks, *t)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	slices.SortFunc(tasks, func(a, b OnCallTask) int { return cmp.Compare(a.ID, b.ID) })
+	return tasks, nil
+}
+
+// DeleteTasksByIDs deletes the tasks with the given ids and returns how many
+// were removed. Large batches are split across statements in one transaction.
+func DeleteTasksByIDs(db *sql.DB, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.Error("Failed to rollback transaction", "error", err)
+		}
+	}()
+
+	var deleted int64
+	for chunk := range slices.Chunk(ids, maxQueryIDs) {
+		placeholders, args := inPlaceholders(chunk)
+		result, err := tx.Exec(`DELETE FROM oncall_tasks WHERE id IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete tasks: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to check rows affected: %w", err)
+		}
+		deleted += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deleted, nil
+}
+
+// ReopenTask returns a done or dismissed task to the open state, clearing its completion time.
+func ReopenTask(db *sql.DB, taskID int64) error {
+	return transitionTask(db, taskID, TaskStatusOpen, "completed_at = NULL")
+}
+
+// ReassignTask moves a task to another schedule and assignee. The escalation
+// chain restarts because tiers belong to the schedule.
+func ReassignTask(db *sql.DB, taskID, scheduleID, userID int64) error {
+	result, err := db.Exec(
+		`UPDATE oncall_tasks SET schedule_id = ?, assigned_to = ?, escalation_tier = 0 WHERE id = ?`,
+		scheduleID,
+		userID,
+		taskID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("no task found with id %d", taskID)
+	}
+	return nil
+}
+
+// SetTaskEscalationTier records the last escalation tier notified for a task.
+func SetTaskEscalationTier(db *sql.DB, taskID int64, tier int) error {
+	_, err := db.Exec(`UPDATE oncall_tasks SET escalation_tier = ? WHERE id = ?`, tier, taskID)
+	return err
+}
+
+// SnoozeTask pauses escalation of a task until the given time.
+func SnoozeTask(db *sql.DB, taskID int64, until time.Time) error {
+	_, err := db.Exec(`UPDATE oncall_tasks SET snoozed_until = ? WHERE id = ?`, formatDBTime(until), taskID)
+	return err
+}
+
+// SetTaskStatusComment records the GitHub comment that shows a task's status
+// and who it mentions.
+func SetTaskStatusComment(db *sql.DB, taskID, commentID int64, mention string) error {
+	_, err := db.Exec(
+		`UPDATE oncall_tasks SET status_comment_id = ?, status_comment_mention = ? WHERE id = ?`,
+		commentID, mention, taskID,
 	)
+	return err
+}
+
+// AddEscalationTier adds or replaces an escalation tier for a schedule.
+func AddEscalationTier(db *sql.DB, tier OnCallEscalationTier) error {
+	_, err := db.Exec(
+		`INSERT OR REPLACE INTO oncall_escalation_tiers (schedule_id, level, target, after_minutes, target_schedule_id)
+		 VALUES (?, ?, ?, ?, ?)`,
+		tier.ScheduleID,
+		tier.Level,
+		tier.Target,
+		int64(tier.After/time.Minute),
+		tier.TargetScheduleID,
+	)
+	return err
+}
+
+// ShortestEscalationDelay returns the smallest delay of any configured
+// escalation tier, and false if no tiers are configured.
+func ShortestEscalationDelay(db *sql.DB) (time.Duration, bool, error) {
+	var minutes sql.NullInt64
+	if err := db.QueryRow(`SELECT MIN(after_minutes) FROM oncall_escalation_tiers`).Scan(&minutes); err != nil {
+		return 0, false, err
+	}
+	if !minutes.Valid {
+		return 0, false, nil
+	}
+	return time.Duration(minutes.Int64) * time.Minute, true, nil
+}
+
+// ListEscalationTiers returns the escalation tiers of a schedule ordered by level.
+func ListEscalationTiers(db *sql.DB, scheduleID int64) ([]OnCallEscalationTier, error) {
+	rows, err := db.Query(
+		`SELECT schedule_id, level, target, after_minutes, target_schedule_id FROM oncall_escalation_tiers
+		 WHERE schedule_id = ? ORDER BY level ASC`,
+		scheduleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []OnCallEscalationTier
+	for rows.Next() {
+		var tier OnCallEscalationTier
+		var afterMinutes int64
+		if err := rows.Scan(
+			&tier.ScheduleID, &tier.Level, &tier.Target, &afterMinutes, &tier.TargetScheduleID,
+		); err != nil {
+			return nil, err
+		}
+		tier.After = time.Duration(afterMinutes) * time.Minute
+		tiers = append(tiers, tier)
+	}
+	return tiers, rows.Err()
+}
+
+// CountTasksByStatus returns the number of tasks per status. Every known status
+// is present in the result, with a zero count when no rows match.
+func CountTasksByStatus(db *sql.DB) (map[string]int, error) {
+	rows, err := db.Query(`SELECT status, COUNT(*) FROM oncall_tasks GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(TaskStatuses))
+	for _, status := range TaskStatuses {
+		counts[status] = 0
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// SetRepositoryEnabled records whether the module acts on repo, overriding the
+// configured repository list.
+func SetRepositoryEnabled(db *sql.DB, repo string, enabled bool, user string, at time.Time) error {
+	_, err := db.Exec(
+		`INSERT INTO oncall_repo_settings (repo, enabled, updated_by, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(repo) DO UPDATE SET
+		   enabled = excluded.enabled, updated_by = excluded.updated_by, updated_at = excluded.updated_at`,
+		strings.ToLower(repo),
+		enabled,
+		user,
+		formatDBTime(at),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save repository setting: %w", err)
+	}
+	return nil
+}
+
+// GetRepoSetting returns the stored setting for repo, or nil if there is none.
+func GetRepoSetting(db *sql.DB, repo string) (*OnCallRepoSetting, error) {
+	var setting OnCallRepoSetting
+	err := db.QueryRow(
+		`SELECT repo, enabled, updated_by, updated_at FROM oncall_repo_settings WHERE repo = ?`,
+		strings.ToLower(repo),
+	).Scan(&setting.Repo, &setting.Enabled, &setting.UpdatedBy, dbTime{&setting.UpdatedAt})
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	return &t, err
+	if err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// RecordDelivery durably records that the webhook delivery with the given ID
+// is being handled. It reports false, without error, if the delivery was
+// already recorded, such as by another instance or before a restart.
+func RecordDelivery(db *sql.DB, deliveryID string, receivedAt time.Time) (bool, error) {
+	res, err := db.Exec(
+		`INSERT INTO oncall_deliveries (delivery_id, received_at) VALUES (?, ?)
+		 ON CONFLICT (delivery_id) DO NOTHING`,
+		deliveryID,
+		formatDBTime(receivedAt),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ForgetDelivery removes a recorded delivery, so that a redelivery of it is
+// handled again.
+func ForgetDelivery(db *sql.DB, deliveryID string) error {
+	_, err := db.Exec(`DELETE FROM oncall_deliveries WHERE delivery_id = ?`, deliveryID)
+	return err
+}
+
+// PruneDeliveries removes the deliveries received before cutoff and returns
+// how many were removed.
+func PruneDeliveries(db *sql.DB, cutoff time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM oncall_deliveries WHERE received_at < ?`, formatDBTime(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ClaimCommandRun durably records that command ran on an issue at now, unless
+// the same command ran less than window ago, such as on another instance or
+// before a restart. It reports whether the run was recorded.
+func ClaimCommandRun(
+	db *sql.DB,
+	repo string,
+	issueNum int,
+	command string,
+	now time.Time,
+	window time.Duration,
+) (bool, error) {
+	res, err := db.Exec(
+		`INSERT INTO oncall_command_cooldowns (repo, issue_num, command, last_run_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (repo, issue_num, command) DO UPDATE SET last_run_at = excluded.last_run_at
+		 WHERE oncall_command_cooldowns.last_run_at <= ?`,
+		repo,
+		issueNum,
+		command,
+		formatDBTime(now),
+		formatDBTime(now.Add(-window)),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// PruneCommandRuns removes the command runs recorded before cutoff and
+// returns how many were removed.
+func PruneCommandRuns(db *sql.DB, cutoff time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM oncall_command_cooldowns WHERE last_run_at < ?`, formatDBTime(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
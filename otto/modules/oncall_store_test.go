@@ -4,14 +4,22 @@ package modules
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
+// openTestDB returns a migrated shared-cache SQLite database.
 func openTestDB(t *testing.T) *sql.DB {
-	db, err := sql.Open("sqlite", ":memory:")
-	if err != nil {
-		t.Fatalf("failed to open test db: %v", err)
-	}
+	t.Helper()
+	db := internal.TestDatabase(t).DB()
 	if err := AutoMigrateOnCall(db); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
@@ -36,12 +44,32 @@ func TestAddTaskAndGetTaskByIssueNumber(t *testing.T) {
 	}
 }
 
+func TestLargeIssueNumberRoundTrip(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "testuser", "Test User")
+
+	// Issue numbers are stored as 64-bit integers and never clamped
+	const issueNum = math.MaxInt32 + 10
+	task, err := AddTask(db, sch.ID, "org/repo", issueNum, "big", "desc", user.ID)
+	if err != nil {
+		t.Fatalf("AddTask failed: %v", err)
+	}
+	got, err := GetTaskByIssueNumber(db, "org/repo", issueNum)
+	if err != nil || got == nil {
+		t.Fatalf("GetTaskByIssueNumber failed: %v", err)
+	}
+	if got.ID != task.ID || got.IssueNum != issueNum {
+		t.Errorf("want task %d for issue %d, got task %d for issue %d", task.ID, issueNum, got.ID, got.IssueNum)
+	}
+}
+
 func TestTaskAcknowledge(t *testing.T) {
 	db := openTestDB(t)
 	sch, _ := AddSchedule(db, "primary", "round-robin")
 	user, _ := AddUser(db, "a", "A")
 	_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
-	task, err := AddTask(db, sch.ID, "repo", 1, "t", "desc", user.ID)
+	task, err := AddTask(db, sch.ID, "org/repo", 1, "t", "desc", user.ID)
 	if err != nil {
 		t.Fatalf("AddTask failed: %v", err)
 	}
@@ -59,3 +87,980 @@ func TestTaskAcknowledge(t *testing.T) {
 		t.Errorf("expected status 'ack', got %q", updated.Status)
 	}
 }
+
+func TestAddTaskRejectsInvalidRepositories(t *testing.T) {
+	tests := []struct {
+		name string
+		repo string
+	}{
+		{"empty", ""},
+		{"no owner", "repo"},
+		{"empty owner", "/repo"},
+		{"empty name", "org/"},
+		{"too many parts", "org/repo/extra"},
+		{"too long", "org/" + strings.Repeat("r", 137)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, _ := newTestModule(t, OnCallConfig{})
+			reader := sdkmetric.NewManualReader()
+			module.telemetry = &internal.TelemetryManager{
+				TracerProvider: sdktrace.NewTracerProvider(),
+				MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+			}
+			if err := module.telemetry.InitMetrics(); err != nil {
+				t.Fatalf("InitMetrics failed: %v", err)
+			}
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "a", "A")
+
+			task, err := module.AddTask(sch.ID, tt.repo, 1, "t", "desc", user.ID)
+			var repoErr *InvalidRepositoryError
+			if !errors.As(err, &repoErr) || repoErr.Repo != tt.repo {
+				t.Fatalf("want *InvalidRepositoryError for %q, got task %v, error %v", tt.repo, task, err)
+			}
+			var n int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM oncall_tasks`).Scan(&n); err != nil {
+				t.Fatalf("counting tasks failed: %v", err)
+			}
+			if n != 0 {
+				t.Errorf("want no task written, got %d", n)
+			}
+			if got := moduleCounter(t, reader, "otto.module.invalid_repositories_total"); got != 1 {
+				t.Errorf("invalid repositories: want 1, got %d", got)
+			}
+		})
+	}
+}
+
+func TestTaskStatusTransitions(t *testing.T) {
+	// transition moves a task to a status with the store function for it
+	transition := func(db *sql.DB, id int64, status string) error {
+		switch status {
+		case TaskStatusOpen:
+			return ReopenTask(db, id)
+		case TaskStatusAck:
+			return AcknowledgeTask(db, id, "a", time.Now())
+		default:
+			return UpdateTaskStatus(db, id, status)
+		}
+	}
+	// reach is the path from a new task to each status
+	reach := map[string][]string{
+		TaskStatusOpen:      nil,
+		TaskStatusAck:       {TaskStatusAck},
+		TaskStatusDone:      {TaskStatusAck, TaskStatusDone},
+		TaskStatusDismissed: {TaskStatusDismissed},
+	}
+
+	tests := []struct {
+		from, to string
+		legal    bool
+	}{
+		{TaskStatusOpen, TaskStatusOpen, false},
+		{TaskStatusOpen, TaskStatusAck, true},
+		{TaskStatusOpen, TaskStatusDone, true},
+		{TaskStatusAck, TaskStatusOpen, false},
+		{TaskStatusAck, TaskStatusAck, false},
+		{TaskStatusAck, TaskStatusDone, true},
+		{TaskStatusDone, TaskStatusOpen, true},
+		{TaskStatusDone, TaskStatusAck, false},
+		{TaskStatusDone, TaskStatusDone, false},
+		{TaskStatusOpen, TaskStatusDismissed, true},
+		{TaskStatusAck, TaskStatusDismissed, true},
+		{TaskStatusDone, TaskStatusDismissed, false},
+		{TaskStatusDismissed, TaskStatusOpen, true},
+		{TaskStatusDismissed, TaskStatusAck, false},
+		{TaskStatusDismissed, TaskStatusDone, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			db := openTestDB(t)
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "a", "A")
+			task, _ := AddTask(db, sch.ID, "org/repo", 1, "t", "desc", user.ID)
+			for _, status := range reach[tt.from] {
+				if err := transition(db, task.ID, status); err != nil {
+					t.Fatalf("moving task to %s failed: %v", status, err)
+				}
+			}
+
+			if got := CanTransitionTask(tt.from, tt.to); got != tt.legal {
+				t.Errorf("CanTransitionTask: want %v, got %v", tt.legal, got)
+			}
+			err := transition(db, task.ID, tt.to)
+			got, _ := GetTask(db, task.ID)
+			if tt.legal {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if got.Status != tt.to {
+					t.Errorf("status: want %s, got %s", tt.to, got.Status)
+				}
+				return
+			}
+
+			var transitionErr *TaskTransitionError
+			if !errors.As(err, &transitionErr) {
+				t.Fatalf("want *TaskTransitionError, got %v", err)
+			}
+			if transitionErr.From != tt.from || transitionErr.To != tt.to {
+				t.Errorf("error: want %s to %s, got %s to %s", tt.from, tt.to, transitionErr.From, transitionErr.To)
+			}
+			if got.Status != tt.from {
+				t.Errorf("status changed on illegal transition: want %s, got %s", tt.from, got.Status)
+			}
+		})
+	}
+}
+
+func TestTaskTransitionMissingTask(t *testing.T) {
+	db := openTestDB(t)
+	err := ReopenTask(db, 42)
+	var transitionErr *TaskTransitionError
+	if err == nil || errors.As(err, &transitionErr) {
+		t.Errorf("want a missing task error, got %v", err)
+	}
+}
+
+func TestCountTasksByStatus(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	for i := 1; i <= 3; i++ {
+		if _, err := AddTask(db, sch.ID, "org/repo", i, "t", "desc", user.ID); err != nil {
+			t.Fatalf("AddTask failed: %v", err)
+		}
+	}
+	task, _ := GetTaskByIssueNumber(db, "org/repo", 1)
+	if err := UpdateTaskStatus(db, task.ID, TaskStatusAck); err != nil {
+		t.Fatalf("UpdateTaskStatus failed: %v", err)
+	}
+	task, _ = GetTaskByIssueNumber(db, "org/repo", 2)
+	if err := UpdateTaskStatus(db, task.ID, TaskStatusDismissed); err != nil {
+		t.Fatalf("UpdateTaskStatus failed: %v", err)
+	}
+
+	counts, err := CountTasksByStatus(db)
+	if err != nil {
+		t.Fatalf("CountTasksByStatus failed: %v", err)
+	}
+
+	want := map[string]int{TaskStatusOpen: 1, TaskStatusAck: 1, TaskStatusDone: 0, TaskStatusDismissed: 1}
+	for status, n := range want {
+		got, ok := counts[status]
+		if !ok {
+			t.Errorf("missing count for status %q", status)
+			continue
+		}
+		if got != n {
+			t.Errorf("count for status %q: want %d, got %d", status, n, got)
+		}
+	}
+}
+
+func TestFindInactiveUsersSince(t *testing.T) {
+	db := openTestDB(t)
+	_, _ = AddUser(db, "recent", "Recent")
+	_, _ = AddUser(db, "stale", "Stale")
+	_, _ = AddUser(db, "never", "Never")
+
+	now := time.Now()
+	if err := MarkUserActive(db, "recent", now.Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkUserActive failed: %v", err)
+	}
+	if err := MarkUserActive(db, "stale", now.Add(-72*time.Hour)); err != nil {
+		t.Fatalf("MarkUserActive failed: %v", err)
+	}
+
+	users, err := FindInactiveUsersSince(db, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("FindInactiveUsersSince failed: %v", err)
+	}
+
+	want := []string{"never", "stale"}
+	if len(users) != len(want) {
+		t.Fatalf("want %d inactive users, got %d", len(want), len(users))
+	}
+	for i, u := range users {
+		if u.GitHub != want[i] {
+			t.Errorf("user %d: want %q, got %q", i, want[i], u.GitHub)
+		}
+	}
+	if !users[0].LastActiveAt.IsZero() {
+		t.Errorf("user %q should have no activity, got %v", users[0].GitHub, users[0].LastActiveAt)
+	}
+	if users[1].LastActiveAt.IsZero() {
+		t.Errorf("user %q should have recorded activity", users[1].GitHub)
+	}
+}
+
+func TestFindUnacknowledgedTasksOlderThan(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "oncaller", "On Caller")
+
+	now := time.Now()
+	ages := map[int]time.Duration{1: 48 * time.Hour, 2: 30 * time.Hour, 3: time.Hour, 4: 72 * time.Hour}
+	for issueNum, age := range ages {
+		task, _ := AddTask(db, sch.ID, "org/repo", issueNum, "t", "desc", user.ID)
+		_, err := db.Exec(`UPDATE oncall_tasks SET created_at = ? WHERE id = ?`, formatDBTime(now.Add(-age)), task.ID)
+		if err != nil {
+			t.Fatalf("failed to age task: %v", err)
+		}
+		if issueNum == 4 {
+			_ = UpdateTaskStatus(db, task.ID, TaskStatusAck)
+		}
+	}
+
+	tasks, err := FindUnacknowledgedTasksOlderThan(db, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("FindUnacknowledgedTasksOlderThan failed: %v", err)
+	}
+
+	want := []int{1, 2}
+	if len(tasks) != len(want) {
+		t.Fatalf("want %d tasks, got %d", len(want), len(tasks))
+	}
+	for i, task := range tasks {
+		if task.IssueNum != want[i] {
+			t.Errorf("task %d: want issue %d, got %d", i, want[i], task.IssueNum)
+		}
+	}
+}
+
+func TestFindTasksAfter(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "oncaller", "On Caller")
+
+	// Groups of tasks share a creation time so that pages break within ties
+	now := time.Now()
+	const total = 23
+	for i := range total {
+		task, _ := AddTask(db, sch.ID, "org/repo", i+1, "t", "desc", user.ID)
+		created := formatDBTime(now.Add(-time.Duration(i/4) * time.Hour))
+		if _, err := db.Exec(`UPDATE oncall_tasks SET created_at = ? WHERE id = ?`, created, task.ID); err != nil {
+			t.Fatalf("failed to age task: %v", err)
+		}
+	}
+
+	for _, limit := range []int{1, 3, 4, 5, total, total + 1} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			seen := make(map[int64]bool)
+			var last *OnCallTask
+			cursor, pages := "", 0
+			for {
+				tasks, next, err := FindTasksAfter(db, cursor, limit)
+				if err != nil {
+					t.Fatalf("FindTasksAfter failed: %v", err)
+				}
+				pages++
+				if len(tasks) > limit {
+					t.Fatalf("page %d has %d tasks, limit %d", pages, len(tasks), limit)
+				}
+				for _, task := range tasks {
+					if seen[task.ID] {
+						t.Errorf("task %d returned twice", task.ID)
+					}
+					seen[task.ID] = true
+					if last != nil && (task.CreatedAt.Before(last.CreatedAt) ||
+						task.CreatedAt.Equal(last.CreatedAt) && task.ID < last.ID) {
+						t.Errorf("task %d listed after task %d", task.ID, last.ID)
+					}
+					last = &task
+				}
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if len(seen) != total {
+				t.Errorf("want %d tasks, got %d", total, len(seen))
+			}
+			if wantPages := max(1, (total+limit-1)/limit); pages != wantPages {
+				t.Errorf("want %d pages, got %d", wantPages, pages)
+			}
+		})
+	}
+
+	for _, cursor := range []string{"not base64!", "bm9jb21tYQ", "eCwx"} {
+		if _, _, err := FindTasksAfter(db, cursor, 10); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: want ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+}
+
+func TestFindCurrentOnCallForAllSchedules(t *testing.T) {
+	db := openTestDB(t)
+	users := make(map[string]*OnCallUser)
+	for _, login := range []string{"a", "b", "c"} {
+		users[login], _ = AddUser(db, login, strings.ToUpper(login))
+	}
+	schedules := []struct {
+		name     string
+		policy   string
+		members  []string
+		rotation int
+		disabled bool
+	}{
+		{name: "org/a on-call", policy: "round-robin", members: []string{"a", "b"}},
+		{name: "org/b on-call", policy: "round-robin", members: []string{"a", "b", "c"}, rotation: 4},
+		{name: "empty", policy: "round-robin"},
+		{name: "sequential", policy: "sequential", members: []string{"c"}},
+		{name: "disabled", policy: "round-robin", members: []string{"a"}, disabled: true},
+	}
+	for _, tt := range schedules {
+		sch, err := AddSchedule(db, tt.name, tt.policy)
+		if err != nil {
+			t.Fatalf("AddSchedule failed: %v", err)
+		}
+		for i, login := range tt.members {
+			_ = AssignUserToSchedule(db, sch.ID, users[login].ID, i)
+		}
+		_, err = db.Exec(`UPDATE oncall_schedules SET current_rotation_idx = ?, enabled = ? WHERE id = ?`,
+			tt.rotation, !tt.disabled, sch.ID)
+		if err != nil {
+			t.Fatalf("failed to update schedule: %v", err)
+		}
+	}
+
+	views, err := FindCurrentOnCallForAllSchedules(db)
+	if err != nil {
+		t.Fatalf("FindCurrentOnCallForAllSchedules failed: %v", err)
+	}
+	want := map[string]string{"empty": "", "org/a on-call": "a", "org/b on-call": "b", "sequential": ""}
+	if len(views) != len(want) {
+		t.Fatalf("want %d schedules, got %+v", len(want), views)
+	}
+	for i, view := range views {
+		if i > 0 && views[i-1].Schedule > view.Schedule {
+			t.Errorf("schedules not sorted by name: %+v", views)
+		}
+		wantLogin, ok := want[view.Schedule]
+		if !ok || view.GitHub != wantLogin {
+			t.Errorf("%s: want on-call %q, got %q", view.Schedule, wantLogin, view.GitHub)
+		}
+		// The joined result agrees with looking each schedule up on its own
+		current, err := GetCurrentOnCallUser(db, view.Schedule)
+		switch {
+		case err == nil && (current.GitHub != view.GitHub || current.DisplayName != view.DisplayName):
+			t.Errorf("%s: GetCurrentOnCallUser returns %+v, view %+v", view.Schedule, current, view)
+		case err != nil && view.GitHub != "":
+			t.Errorf("%s: GetCurrentOnCallUser failed (%v) but view has %q", view.Schedule, err, view.GitHub)
+		}
+	}
+}
+
+func TestDeleteTasksByIDs(t *testing.T) {
+	tests := []struct {
+		name        string
+		tasks       int
+		deleteIdx   []int
+		extraIDs    []int64
+		wantDeleted int64
+	}{
+		{name: "empty slice", tasks: 3},
+		{name: "subset", tasks: 5, deleteIdx: []int{0, 2, 4}, wantDeleted: 3},
+		{name: "unknown ids are ignored", tasks: 2, deleteIdx: []int{1}, extraIDs: []int64{9999}, wantDeleted: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "oncaller", "On Caller")
+			var ids []int64
+			for i := range tt.tasks {
+				task, err := AddTask(db, sch.ID, "org/repo", i+1, "t", "desc", user.ID)
+				if err != nil {
+					t.Fatalf("AddTask failed: %v", err)
+				}
+				ids = append(ids, task.ID)
+			}
+
+			toDelete := append([]int64{}, tt.extraIDs...)
+			for _, i := range tt.deleteIdx {
+				toDelete = append(toDelete, ids[i])
+			}
+			deleted, err := DeleteTasksByIDs(db, toDelete)
+			if err != nil {
+				t.Fatalf("DeleteTasksByIDs failed: %v", err)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("deleted: want %d, got %d", tt.wantDeleted, deleted)
+			}
+
+			counts, _ := CountTasksByStatus(db)
+			if want := int64(tt.tasks) - tt.wantDeleted; int64(counts[TaskStatusOpen]) != want {
+				t.Errorf("remaining tasks: want %d, got %d", want, counts[TaskStatusOpen])
+			}
+		})
+	}
+}
+
+func TestBatchTaskQueriesSpanChunks(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "oncaller", "On Caller")
+
+	// Insert more tasks than fit in one IN clause
+	const total = 2*maxQueryIDs + 50
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	ids := make([]int64, 0, total)
+	for i := range total {
+		result, err := tx.Exec(
+			`INSERT INTO oncall_tasks (schedule_id, repo, issue_num, title, description, status, assigned_to, created_at)
+			 VALUES (?, 'org/repo', ?, 't', '', 'open', ?, ?)`,
+			sch.ID, i+1, user.ID, formatDBTime(time.Now()),
+		)
+		if err != nil {
+			t.Fatalf("failed to insert task: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		ids = append(ids, id)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	tasks, err := GetTasksByIDs(db, ids)
+	if err != nil {
+		t.Fatalf("GetTasksByIDs failed: %v", err)
+	}
+	if len(tasks) != total {
+		t.Fatalf("GetTasksByIDs: want %d tasks, got %d", total, len(tasks))
+	}
+	for i, task := range tasks {
+		if task.ID != ids[i] {
+			t.Fatalf("task %d: want id %d, got %d", i, ids[i], task.ID)
+		}
+	}
+
+	deleted, err := DeleteTasksByIDs(db, ids[:total-1])
+	if err != nil {
+		t.Fatalf("DeleteTasksByIDs failed: %v", err)
+	}
+	if deleted != total-1 {
+		t.Errorf("DeleteTasksByIDs: want %d deleted, got %d", total-1, deleted)
+	}
+	counts, _ := CountTasksByStatus(db)
+	if counts[TaskStatusOpen] != 1 {
+		t.Errorf("want 1 remaining task, got %d", counts[TaskStatusOpen])
+	}
+}
+
+func TestAutoMigrateAddsLastActiveColumn(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	// Simulate a database created before last_active_at existed
+	if _, err := db.Exec(`CREATE TABLE oncall_users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		github TEXT UNIQUE NOT NULL,
+		display_name TEXT,
+		active BOOLEAN NOT NULL DEFAULT 1,
+		created_at TIMESTAMP NOT NULL
+	);`); err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+
+	// Running the migration twice must be safe
+	for i := 0; i < 2; i++ {
+		if err := AutoMigrateOnCall(db); err != nil {
+			t.Fatalf("migration %d failed: %v", i, err)
+		}
+	}
+
+	if _, err := AddUser(db, "a", "A"); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if err := MarkUserActive(db, "a", time.Now()); err != nil {
+		t.Errorf("MarkUserActive failed after migration: %v", err)
+	}
+}
+
+func TestAutoMigrateDropsPerUserCommandRuns(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	// Simulate a database created while command runs were recorded per user
+	if _, err := db.Exec(`CREATE TABLE oncall_command_cooldowns (
+		repo TEXT NOT NULL,
+		issue_num INTEGER NOT NULL,
+		user TEXT NOT NULL,
+		command TEXT NOT NULL,
+		last_run_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (repo, issue_num, user, command)
+	);`); err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := AutoMigrateOnCall(db); err != nil {
+			t.Fatalf("migration %d failed: %v", i, err)
+		}
+	}
+
+	if _, err := ClaimCommandRun(db, "org/repo", 1, "reassign x", time.Now(), time.Minute); err != nil {
+		t.Errorf("ClaimCommandRun failed after migration: %v", err)
+	}
+}
+
+func TestOnCallStoreCRUD(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, db *sql.DB)
+	}{
+		{
+			name: "user round trip",
+			run: func(t *testing.T, db *sql.DB) {
+				user, err := AddUser(db, "alice", "Alice")
+				if err != nil {
+					t.Fatalf("AddUser failed: %v", err)
+				}
+				if _, err := AddUser(db, "alice", "Alice again"); err == nil {
+					t.Errorf("expected duplicate github login to be rejected")
+				}
+				sch, _ := AddSchedule(db, "primary", "round-robin")
+				_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+
+				got, err := GetCurrentOnCallUser(db, "primary")
+				if err != nil {
+					t.Fatalf("GetCurrentOnCallUser failed: %v", err)
+				}
+				if got.ID != user.ID || got.GitHub != "alice" || got.DisplayName != "Alice" || !got.Active {
+					t.Errorf("unexpected user: %+v", got)
+				}
+				if got.CreatedAt.Sub(user.CreatedAt).Abs() > time.Second {
+					t.Errorf("created_at: want %v, got %v", user.CreatedAt, got.CreatedAt)
+				}
+			},
+		},
+		{
+			name: "schedule round trip",
+			run: func(t *testing.T, db *sql.DB) {
+				sch, err := AddSchedule(db, "primary", "sequential")
+				if err != nil {
+					t.Fatalf("AddSchedule failed: %v", err)
+				}
+				got, err := GetScheduleByName(db, "primary")
+				if err != nil || got == nil {
+					t.Fatalf("GetScheduleByName failed: %v", err)
+				}
+				if got.ID != sch.ID || got.Policy != SequentialPolicy || !got.Enabled {
+					t.Errorf("unexpected schedule: %+v", got)
+				}
+				if got.UpdatedAt.Sub(sch.UpdatedAt).Abs() > time.Second {
+					t.Errorf("updated_at: want %v, got %v", sch.UpdatedAt, got.UpdatedAt)
+				}
+			},
+		},
+		{
+			name: "schedule rotation",
+			run: func(t *testing.T, db *sql.DB) {
+				sch, _ := AddSchedule(db, "primary", "round-robin")
+				for i, gh := range []string{"a", "b"} {
+					user, _ := AddUser(db, gh, gh)
+					_ = AssignUserToSchedule(db, sch.ID, user.ID, i)
+				}
+				for _, want := range []string{"a", "b", "a"} {
+					got, err := GetCurrentOnCallUser(db, "primary")
+					if err != nil {
+						t.Fatalf("GetCurrentOnCallUser failed: %v", err)
+					}
+					if got.GitHub != want {
+						t.Errorf("on-call user: want %q, got %q", want, got.GitHub)
+					}
+					if err := AdvanceOnCallSchedule(db, "primary"); err != nil {
+						t.Fatalf("AdvanceOnCallSchedule failed: %v", err)
+					}
+				}
+			},
+		},
+		{
+			name: "schedule delete",
+			run: func(t *testing.T, db *sql.DB) {
+				sch, _ := AddSchedule(db, "primary", "round-robin")
+				user, _ := AddUser(db, "a", "A")
+				_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+				_ = AddEscalationTier(db, OnCallEscalationTier{
+					ScheduleID: sch.ID, Level: 1, Target: "@x", After: time.Hour,
+				})
+
+				if err := DeleteSchedule(db, sch.ID); err != nil {
+					t.Fatalf("DeleteSchedule failed: %v", err)
+				}
+				if got, _ := GetScheduleByName(db, "primary"); got != nil {
+					t.Errorf("schedule still present after delete")
+				}
+				if users, _ := ListUsersForSchedule(db, sch.ID); len(users) != 0 {
+					t.Errorf("schedule users still present after delete: %v", users)
+				}
+				if tiers, _ := ListEscalationTiers(db, sch.ID); len(tiers) != 0 {
+					t.Errorf("escalation tiers still present after delete: %v", tiers)
+				}
+				if err := DeleteSchedule(db, sch.ID); err == nil {
+					t.Errorf("expected deleting a missing schedule to fail")
+				}
+			},
+		},
+		{
+			name: "task lifecycle",
+			run: func(t *testing.T, db *sql.DB) {
+				sch, _ := AddSchedule(db, "primary", "round-robin")
+				user, _ := AddUser(db, "a", "A")
+				task, err := AddTask(db, sch.ID, "org/repo", 9, "title", "desc", user.ID)
+				if err != nil {
+					t.Fatalf("AddTask failed: %v", err)
+				}
+
+				got, err := GetTask(db, task.ID)
+				if err != nil || got == nil {
+					t.Fatalf("GetTask failed: %v", err)
+				}
+				if got.Status != TaskStatusOpen || got.AckedAt != nil || got.CompletedAt != nil {
+					t.Errorf("unexpected new task: %+v", got)
+				}
+				if got.CreatedAt.Sub(task.CreatedAt).Abs() > time.Second {
+					t.Errorf("created_at: want %v, got %v", task.CreatedAt, got.CreatedAt)
+				}
+
+				for _, status := range []string{TaskStatusAck, TaskStatusDone} {
+					if err := UpdateTaskStatus(db, task.ID, status); err != nil {
+						t.Fatalf("UpdateTaskStatus(%q) failed: %v", status, err)
+					}
+				}
+				got, _ = GetTask(db, task.ID)
+				if got.Status != TaskStatusDone || got.AckedAt == nil || got.CompletedAt == nil {
+					t.Errorf("unexpected finished task: %+v", got)
+				}
+			},
+		},
+		{
+			name: "not found",
+			run: func(t *testing.T, db *sql.DB) {
+				if got, err := GetTask(db, 42); got != nil || err != nil {
+					t.Errorf("GetTask: want nil, nil; got %v, %v", got, err)
+				}
+				if got, err := GetTaskByIssueNumber(db, "org/repo", 42); got != nil || err != nil {
+					t.Errorf("GetTaskByIssueNumber: want nil, nil; got %v, %v", got, err)
+				}
+				if got, err := GetScheduleByName(db, "missing"); got != nil || err != nil {
+					t.Errorf("GetScheduleByName: want nil, nil; got %v, %v", got, err)
+				}
+				if _, err := GetCurrentOnCallUser(db, "missing"); err == nil {
+					t.Errorf("GetCurrentOnCallUser: expected error for missing schedule")
+				}
+				if err := UpdateTaskStatus(db, 42, TaskStatusAck); err == nil {
+					t.Errorf("UpdateTaskStatus: expected error for missing task")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, openTestDB(t))
+		})
+	}
+}
+
+func TestTimestampRoundTrip(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	task, err := AddTask(db, sch.ID, "org/repo", 1, "t", "desc", user.ID)
+	if err != nil {
+		t.Fatalf("AddTask failed: %v", err)
+	}
+
+	var raw string
+	if err := db.QueryRow(`SELECT CAST(created_at AS TEXT) FROM oncall_tasks WHERE id = ?`, task.ID).
+		Scan(&raw); err != nil {
+		t.Fatalf("failed to read raw created_at: %v", err)
+	}
+	if _, err := time.Parse(dbTimeLayout, raw); err != nil {
+		t.Errorf("created_at stored as %q, not in %q: %v", raw, dbTimeLayout, err)
+	}
+
+	got, err := GetTask(db, task.ID)
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	if !got.CreatedAt.Equal(task.CreatedAt) {
+		t.Errorf("created_at: want %v, got %v", task.CreatedAt, got.CreatedAt)
+	}
+}
+
+func TestParseDBTime(t *testing.T) {
+	want := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"RFC3339", "2025-03-04T05:06:07Z", false},
+		{"RFC3339 with offset", "2025-03-04T07:06:07+02:00", false},
+		{"RFC3339Nano", "2025-03-04T05:06:07.000000000Z", false},
+		{"sqlite default", "2025-03-04 05:06:07+00:00", false},
+		{"stored layout", "2025-03-04 05:06:07.000000000+00:00", false},
+		{"time.Time String", "2025-03-04 05:06:07 +0000 UTC", false},
+		{"String with monotonic clock", "2025-03-04 05:06:07 +0000 UTC m=+0.012345", false},
+		{"date only", "2025-03-04", true},
+		{"garbage", "yesterday", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDBTime(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for %q, got %v", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDBTime(%q) failed: %v", tt.input, err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("parseDBTime(%q): want %v, got %v", tt.input, want, got)
+			}
+		})
+	}
+}
+
+func TestReadLegacyTimestamps(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	task, _ := AddTask(db, sch.ID, "org/repo", 1, "t", "desc", user.ID)
+
+	// Rows written before timestamps were normalized hold time.Time's String format
+	legacy := "2025-03-04 05:06:07.5 +0000 UTC m=+0.012345"
+	if _, err := db.Exec(`UPDATE oncall_tasks SET created_at = ?, acked_at = ? WHERE id = ?`,
+		legacy, legacy, task.ID); err != nil {
+		t.Fatalf("failed to write legacy timestamps: %v", err)
+	}
+
+	got, err := GetTask(db, task.ID)
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	want := time.Date(2025, 3, 4, 5, 6, 7, 500000000, time.UTC)
+	if !got.CreatedAt.Equal(want) {
+		t.Errorf("created_at: want %v, got %v", want, got.CreatedAt)
+	}
+	if got.AckedAt == nil || !got.AckedAt.Equal(want) {
+		t.Errorf("acked_at: want %v, got %v", want, got.AckedAt)
+	}
+	if got.CompletedAt != nil {
+		t.Errorf("completed_at: want nil, got %v", got.CompletedAt)
+	}
+}
+
+func TestFindScheduleByName(t *testing.T) {
+	db := openTestDB(t)
+	infra, _ := AddSchedule(db, "Infra", "round-robin")
+	lower, _ := AddSchedule(db, "docs", "round-robin")
+	upper, _ := AddSchedule(db, "DOCS", "round-robin")
+
+	tests := []struct {
+		name   string
+		lookup string
+		wantID int64
+	}{
+		{"exact", "Infra", infra.ID},
+		{"different case", "INFRA", infra.ID},
+		{"exact match wins over case variant", "DOCS", upper.ID},
+		{"exact lowercase match", "docs", lower.ID},
+		{"missing", "security", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindScheduleByName(db, tt.lookup)
+			if err != nil {
+				t.Fatalf("FindScheduleByName failed: %v", err)
+			}
+			var gotID int64
+			if got != nil {
+				gotID = got.ID
+			}
+			if gotID != tt.wantID {
+				t.Errorf("FindScheduleByName(%q): want schedule %d, got %d", tt.lookup, tt.wantID, gotID)
+			}
+		})
+	}
+}
+
+func TestGenerateUpcomingHandoffs(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	var userIDs []int64
+	for i, gh := range []string{"a", "b"} {
+		user, _ := AddUser(db, gh, gh)
+		_ = AssignUserToSchedule(db, sch.ID, user.ID, i)
+		userIDs = append(userIDs, user.ID)
+	}
+
+	if _, err := GenerateUpcomingHandoffs(db, sch.ID, time.Now(), 24*time.Hour); err == nil {
+		t.Errorf("expected an error for a schedule without a recurrence rule")
+	}
+	if err := SetScheduleRecurrence(db, sch.ID, "FREQ=YEARLY"); err == nil {
+		t.Errorf("expected an invalid rule to be rejected")
+	}
+	if err := SetScheduleRecurrence(db, sch.ID, "FREQ=WEEKLY;BYDAY=MO;BYHOUR=9"); err != nil {
+		t.Fatalf("SetScheduleRecurrence failed: %v", err)
+	}
+
+	// Next Monday 09:00 after the schedule was created
+	next := time.Now().UTC().Truncate(24 * time.Hour).Add(9 * time.Hour)
+	for next.Weekday() != time.Monday || !next.After(time.Now()) {
+		next = next.Add(24 * time.Hour)
+	}
+	from := next.Add(-time.Hour)
+	handoffs, err := GenerateUpcomingHandoffs(db, sch.ID, from, 15*24*time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateUpcomingHandoffs failed: %v", err)
+	}
+
+	week := 7 * 24 * time.Hour
+	want := []OnCallHandoff{
+		{UserID: userIDs[0], Start: from, End: next},
+		{UserID: userIDs[1], Start: next, End: next.Add(week)},
+		{UserID: userIDs[0], Start: next.Add(week), End: next.Add(2 * week)},
+		{UserID: userIDs[1], Start: next.Add(2 * week), End: from.Add(15 * 24 * time.Hour)},
+	}
+	if len(handoffs) != len(want) {
+		t.Fatalf("want %d handoffs, got %d: %+v", len(want), len(handoffs), handoffs)
+	}
+	for i, h := range handoffs {
+		if h.UserID != want[i].UserID || !h.Start.Equal(want[i].Start) || !h.End.Equal(want[i].End) {
+			t.Errorf("handoff %d: want %+v, got %+v", i, want[i], h)
+		}
+	}
+}
+
+func TestGenerateUpcomingHandoffsSkipsInactiveMembers(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	if err := SetScheduleRecurrence(db, sch.ID, "FREQ=DAILY;BYHOUR=9"); err != nil {
+		t.Fatalf("SetScheduleRecurrence failed: %v", err)
+	}
+	users := make(map[string]int64)
+	for i, gh := range []string{"a", "b", "c"} {
+		user, _ := AddUser(db, gh, gh)
+		_ = AssignUserToSchedule(db, sch.ID, user.ID, i)
+		users[gh] = user.ID
+	}
+	if _, err := db.Exec(`UPDATE oncall_users SET active = 0 WHERE github = 'b'`); err != nil {
+		t.Fatalf("failed to deactivate b: %v", err)
+	}
+
+	// Start exactly at tomorrow's handoff, which has already happened
+	day := 24 * time.Hour
+	from := time.Now().UTC().Truncate(day).Add(day + 9*time.Hour)
+	handoffs, err := GenerateUpcomingHandoffs(db, sch.ID, from, 3*day)
+	if err != nil {
+		t.Fatalf("GenerateUpcomingHandoffs failed: %v", err)
+	}
+
+	want := []OnCallHandoff{
+		{UserID: users["a"], Start: from, End: from.Add(day)},
+		{UserID: users["c"], Start: from.Add(day), End: from.Add(2 * day)},
+		{UserID: users["a"], Start: from.Add(2 * day), End: from.Add(3 * day)},
+	}
+	if len(handoffs) != len(want) {
+		t.Fatalf("want %d handoffs, got %d: %+v", len(want), len(handoffs), handoffs)
+	}
+	for i, h := range handoffs {
+		if h.UserID != want[i].UserID || !h.Start.Equal(want[i].Start) || !h.End.Equal(want[i].End) {
+			t.Errorf("handoff %d: want %+v, got %+v", i, want[i], h)
+		}
+	}
+}
+
+func TestClaimCommandRun(t *testing.T) {
+	db := openTestDB(t)
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	const window = 30 * time.Second
+
+	tests := []struct {
+		name    string
+		command string
+		at      time.Duration
+		wantRun bool
+	}{
+		{"first run", "reassign x", 0, true},
+		{"repeat within window", "reassign x", 10 * time.Second, false},
+		{"other command", "reassign y", 10 * time.Second, true},
+		{"repeat after window", "reassign x", window, true},
+		{"repeat within renewed window", "reassign x", window + time.Second, false},
+	}
+	for _, tt := range tests {
+		claimed, err := ClaimCommandRun(db, "org/repo", 1, tt.command, start.Add(tt.at), window)
+		if err != nil {
+			t.Fatalf("%s: ClaimCommandRun failed: %v", tt.name, err)
+		}
+		if claimed != tt.wantRun {
+			t.Errorf("%s: claimed = %v, want %v", tt.name, claimed, tt.wantRun)
+		}
+	}
+
+	// Only the other command's run is older than the cutoff
+	pruned, err := PruneCommandRuns(db, start.Add(window))
+	if err != nil {
+		t.Fatalf("PruneCommandRuns failed: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("pruned %d runs, want 1", pruned)
+	}
+}
+
+func TestHandOffSchedule(t *testing.T) {
+	db := openTestDB(t)
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	first := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		prev    *time.Time
+		at      time.Time
+		idx     int
+		wantRan bool
+	}{
+		{"first handoff", nil, first, 0, true},
+		{"first handoff again", nil, first, 5, false},
+		{"next handoff", &first, second, 1, true},
+		{"stale handoff", &first, second, 2, false},
+	}
+	for _, tt := range tests {
+		ran, err := HandOffSchedule(db, sch.ID, tt.prev, tt.at, tt.idx)
+		if err != nil {
+			t.Fatalf("%s: HandOffSchedule failed: %v", tt.name, err)
+		}
+		if ran != tt.wantRan {
+			t.Errorf("%s: handed off = %v, want %v", tt.name, ran, tt.wantRan)
+		}
+	}
+
+	got, _ := GetSchedule(db, sch.ID)
+	if got.CurrentRotationIdx != 1 || got.LastHandoffAt == nil || !got.LastHandoffAt.Equal(second) {
+		t.Errorf("schedule: got index %d and last handoff %v, want 1 and %v",
+			got.CurrentRotationIdx, got.LastHandoffAt, second)
+	}
+}
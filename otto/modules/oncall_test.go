@@ -0,0 +1,2735 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package modules
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+	"github.com/open-telemetry/sig-project-infra/otto/internal"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/config"
+	"github.com/open-telemetry/sig-project-infra/otto/internal/testutil"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestModule creates an oncall module backed by an in-memory database, a
+// fake clock and a fake GitHub API that records comments.
+func newTestModule(t *testing.T, cfg OnCallConfig) (*OnCallModule, *sql.DB, *testutil.GitHubRecorder) {
+	t.Helper()
+	module, h := newHarnessModule(t, cfg)
+	return module, h.App.Database.DB(), h.GitHub
+}
+
+// newHarnessModule creates an oncall module like newTestModule, registered
+// with the harness app so that webhooks can be delivered to it.
+func newHarnessModule(t *testing.T, cfg OnCallConfig) (*OnCallModule, *testutil.Harness) {
+	t.Helper()
+
+	h := testutil.NewHarness(t)
+	if err := AutoMigrateOnCall(h.App.Database.DB()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	module := &OnCallModule{
+		app:      h.App,
+		database: h.App.Database,
+		config:   cfg,
+		clock:    internal.NewFakeClock(time.Now()),
+	}
+	h.App.RegisterModule(module)
+	return module, h
+}
+
+func TestAckCommandFromIssueComment(t *testing.T) {
+	// Several users /ack the same issue, which the cooldown would suppress
+	module, db, _ := newTestModule(t, OnCallConfig{CommandCooldown: -1})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "oncaller", "On Caller")
+	_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+	task, _ := AddTask(db, sch.ID, "org/repo", 7, "t", "desc", user.ID)
+
+	tests := []struct {
+		name        string
+		user        string
+		wantStatus  string
+		wantAckedBy string
+	}{
+		{"other user cannot ack", "someone", TaskStatusOpen, ""},
+		{"on-call user acks", "oncaller", TaskStatusAck, "oncaller"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := testutil.NewIssueCommentEvent("org/repo", 7, "/ack", tt.user)
+			handled, err := module.HandleEvent("issue_comment", event, nil)
+			if err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			if !handled {
+				t.Errorf("expected /ack comment to be handled")
+			}
+			got, _ := GetTask(db, task.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("task status: want %q, got %q", tt.wantStatus, got.Status)
+			}
+			if got.AckedBy != tt.wantAckedBy {
+				t.Errorf("acked by: want %q, got %q", tt.wantAckedBy, got.AckedBy)
+			}
+			if tt.wantAckedBy == "" {
+				return
+			}
+			if want := module.now(); got.AckedAt == nil || !got.AckedAt.Equal(want) {
+				t.Errorf("acked at: want %v, got %v", want, got.AckedAt)
+			}
+		})
+	}
+}
+
+func TestRequireAssignedAck(t *testing.T) {
+	tests := []struct {
+		name        string
+		require     bool
+		user        string
+		wantStatus  string
+		wantAckedBy string
+		wantComment string
+	}{
+		{"on-call user acks", true, "oncaller", TaskStatusAck, "oncaller", ""},
+		{"on-call login is case-insensitive", true, "OnCaller", TaskStatusAck, "OnCaller", ""},
+		{"maintainer acks", true, "lead", TaskStatusAck, "lead", ""},
+		{"maintainer acks without the option", false, "lead", TaskStatusAck, "lead", ""},
+		{
+			"other user is rejected", true, "someone", TaskStatusOpen, "",
+			"@someone only the on-call engineer can acknowledge",
+		},
+		{"other user is ignored without the option", false, "someone", TaskStatusOpen, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{
+				Maintainers:        []string{"lead"},
+				RequireAssignedAck: tt.require,
+			})
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "oncaller", "On Caller")
+			_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+			task, _ := AddTask(db, sch.ID, "org/repo", 7, "t", "desc", user.ID)
+
+			event := testutil.NewIssueCommentEvent("org/repo", 7, "/ack", tt.user)
+			if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			got, _ := GetTask(db, task.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("task status: want %q, got %q", tt.wantStatus, got.Status)
+			}
+			if got.AckedBy != tt.wantAckedBy {
+				t.Errorf("acked by: want %q, got %q", tt.wantAckedBy, got.AckedBy)
+			}
+			comments := recorder.Comments()
+			if tt.wantComment == "" {
+				if len(comments) != 0 {
+					t.Errorf("expected no comments, got %q", comments)
+				}
+				return
+			}
+			if len(comments) != 1 || !strings.Contains(comments[0], tt.wantComment) {
+				t.Errorf("comments: want one containing %q, got %q", tt.wantComment, comments)
+			}
+		})
+	}
+}
+
+func TestRemoveScheduleCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		user         string
+		schedule     string
+		openTask     bool
+		wantRemoved  bool
+		wantComment  string
+		wantSchedule bool
+	}{
+		{
+			name:         "non-maintainer is refused",
+			user:         "someone",
+			schedule:     "primary",
+			wantComment:  "only maintainers can remove schedules",
+			wantSchedule: true,
+		},
+		{
+			name:        "unknown schedule",
+			user:        "maintainer",
+			schedule:    "missing",
+			wantComment: "Schedule `missing` does not exist.",
+			// The existing schedule is untouched
+			wantSchedule: true,
+		},
+		{
+			name:         "schedule with unfinished tasks is kept",
+			user:         "maintainer",
+			schedule:     "primary",
+			openTask:     true,
+			wantComment:  "still has 1 unfinished task(s)",
+			wantSchedule: true,
+		},
+		{
+			name:        "schedule is removed",
+			user:        "Maintainer",
+			schedule:    "primary",
+			wantRemoved: true,
+			wantComment: "Schedule `primary` has been removed.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{Maintainers: []string{"@maintainer"}})
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "a", "A")
+			_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+			if tt.openTask {
+				_, _ = AddTask(db, sch.ID, "org/repo", 1, "t", "desc", user.ID)
+			}
+
+			event := testutil.NewIssueCommentEvent("org/repo", 1, "/oncall remove schedule "+tt.schedule, tt.user)
+			handled, err := module.HandleEvent("issue_comment", event, nil)
+			if err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			if !handled {
+				t.Errorf("expected command to be handled")
+			}
+
+			comments := recorder.Comments()
+			if len(comments) != 1 || !strings.Contains(comments[0], tt.wantComment) {
+				t.Errorf("want one comment containing %q, got %q", tt.wantComment, comments)
+			}
+
+			got, err := GetScheduleByName(db, "primary")
+			if err != nil {
+				t.Fatalf("GetScheduleByName failed: %v", err)
+			}
+			if (got != nil) != tt.wantSchedule {
+				t.Errorf("schedule present: want %v, got %v", tt.wantSchedule, got != nil)
+			}
+			if tt.wantRemoved {
+				users, _ := ListUsersForSchedule(db, sch.ID)
+				if len(users) != 0 {
+					t.Errorf("schedule users should be removed, got %d", len(users))
+				}
+			}
+		})
+	}
+}
+
+func TestOrderCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		user        string
+		body        string
+		wantComment string
+		wantOrder   []string
+	}{
+		{
+			name:        "non-maintainer is refused",
+			user:        "a",
+			body:        "/oncall order org/repo on-call @c @b @a",
+			wantComment: "only maintainers can change the order",
+			wantOrder:   []string{"a", "b"},
+		},
+		{
+			name:        "unknown schedule",
+			user:        "lead",
+			body:        "/oncall order missing @a",
+			wantComment: "Schedule `missing` does not exist.",
+			wantOrder:   []string{"a", "b"},
+		},
+		{
+			name:        "unknown users",
+			user:        "lead",
+			body:        "/oncall order org/repo on-call @a @ghost @nobody",
+			wantComment: "Unknown users: @ghost, @nobody.",
+			wantOrder:   []string{"a", "b"},
+		},
+		{
+			name:        "duplicate user",
+			user:        "lead",
+			body:        "/oncall order org/repo on-call @a @b @A",
+			wantComment: "@a is listed more than once",
+			wantOrder:   []string{"a", "b"},
+		},
+		{
+			name:        "members are ordered",
+			user:        "lead",
+			body:        "/oncall order org/repo on-call @c @a @b",
+			wantComment: "Rotation order of `org/repo on-call`:\n1. @c\n2. @a\n3. @b",
+			wantOrder:   []string{"c", "a", "b"},
+		},
+		{
+			name:        "members are replaced",
+			user:        "lead",
+			body:        "/oncall order ORG/REPO ON-CALL @b",
+			wantComment: "Rotation order of `org/repo on-call`:\n1. @b",
+			wantOrder:   []string{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{Maintainers: []string{"lead"}})
+			sch, _ := AddSchedule(db, "org/repo on-call", "round-robin")
+			for i, login := range []string{"a", "b", "c"} {
+				user, _ := AddUser(db, login, login)
+				if i < 2 {
+					_ = AssignUserToSchedule(db, sch.ID, user.ID, i)
+				}
+			}
+
+			event := testutil.NewIssueCommentEvent("org/repo", 1, tt.body, tt.user)
+			if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			comments := recorder.Comments()
+			if len(comments) != 1 || !strings.Contains(comments[0], tt.wantComment) {
+				t.Errorf("want one comment containing %q, got %q", tt.wantComment, comments)
+			}
+
+			members, err := ListScheduleMembers(db, sch.ID)
+			if err != nil {
+				t.Fatalf("ListScheduleMembers failed: %v", err)
+			}
+			var got []string
+			for _, member := range members {
+				got = append(got, member.GitHub)
+			}
+			if !slices.Equal(got, tt.wantOrder) {
+				t.Errorf("members: want %v, got %v", tt.wantOrder, got)
+			}
+		})
+	}
+}
+
+func TestReorderingChangesWhoIsOnCall(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{Maintainers: []string{"lead"}})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	for _, login := range []string{"a", "b"} {
+		_, _ = AddUser(db, login, login)
+	}
+
+	steps := []struct {
+		body        string
+		wantComment string
+		wantOnCall  string
+	}{
+		{"/oncall members primary", "Schedule `primary` has no members.", ""},
+		{"/oncall order primary @a @b", "1. @a\n2. @b", "a"},
+		{"/oncall members primary", "Rotation order of `primary`:\n1. @a\n2. @b", "a"},
+		{"/oncall order primary @b @a", "1. @b\n2. @a", "b"},
+		{"/oncall members Primary", "Rotation order of `primary`:\n1. @b\n2. @a", "b"},
+		{"/oncall members missing", "Schedule `missing` does not exist.", "b"},
+	}
+	for i, step := range steps {
+		event := testutil.NewIssueCommentEvent("org/repo", i+1, step.body, "lead")
+		if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+			t.Fatalf("step %d: HandleEvent failed: %v", i, err)
+		}
+		comments := recorder.Comments()
+		if len(comments) != i+1 || !strings.Contains(comments[i], step.wantComment) {
+			t.Fatalf("step %d: want comment containing %q, got %q", i, step.wantComment, comments)
+		}
+		if step.wantOnCall == "" {
+			continue
+		}
+		onCall, err := GetCurrentOnCallUser(db, sch.Name)
+		if err != nil {
+			t.Fatalf("step %d: GetCurrentOnCallUser failed: %v", i, err)
+		}
+		if onCall.GitHub != step.wantOnCall {
+			t.Errorf("step %d: on call: want %s, got %s", i, step.wantOnCall, onCall.GitHub)
+		}
+	}
+}
+
+func TestCheckUnacknowledgedTasksEscalationChain(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+	secondary, _ := AddSchedule(db, "secondary", "round-robin")
+	for i, login := range []string{"b", "c"} {
+		u, _ := AddUser(db, login, login)
+		_ = AssignUserToSchedule(db, secondary.ID, u.ID, i)
+	}
+	_ = AdvanceOnCallSchedule(db, secondary.Name)
+	tiers := []OnCallEscalationTier{
+		{ScheduleID: sch.ID, Level: 1, Target: "@org/secondary", After: time.Hour, TargetScheduleID: secondary.ID},
+		{ScheduleID: sch.ID, Level: 2, Target: "@org/leadership", After: 4 * time.Hour},
+	}
+	for _, tier := range tiers {
+		if err := AddEscalationTier(db, tier); err != nil {
+			t.Fatalf("AddEscalationTier failed: %v", err)
+		}
+	}
+	task, _ := AddTask(db, sch.ID, "org/repo", 3, "t", "desc", user.ID)
+	clock := module.clock.(*internal.FakeClock)
+
+	tests := []struct {
+		name         string
+		age          time.Duration
+		wantTier     int
+		wantComments int
+		wantTarget   string
+	}{
+		{"fresh task is not escalated", 10 * time.Minute, 0, 0, ""},
+		{"first tier mentions the secondary on call", 2 * time.Hour, 1, 1, "Escalating to: @c"},
+		{"first tier is not repeated", 3 * time.Hour, 1, 1, ""},
+		{"second tier", 5 * time.Hour, 2, 2, "@org/leadership"},
+		{"chain exhausted", 48 * time.Hour, 2, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Set(task.CreatedAt.Add(tt.age))
+			if err := module.CheckUnacknowledgedTasks(); err != nil {
+				t.Fatalf("CheckUnacknowledgedTasks failed: %v", err)
+			}
+
+			got, _ := GetTask(db, task.ID)
+			if got.EscalationTier != tt.wantTier {
+				t.Errorf("escalation tier: want %d, got %d", tt.wantTier, got.EscalationTier)
+			}
+			comments := recorder.Comments()
+			if len(comments) != tt.wantComments {
+				t.Fatalf("want %d comments, got %d: %q", tt.wantComments, len(comments), comments)
+			}
+			if tt.wantTarget != "" && !strings.Contains(comments[len(comments)-1], tt.wantTarget) {
+				t.Errorf("want escalation to %q, got %q", tt.wantTarget, comments[len(comments)-1])
+			}
+		})
+	}
+}
+
+func TestEscalationTierTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		users      []string
+		wantErr    bool
+		wantTarget string
+	}{
+		{"on-call user of the schedule", "@org/secondary", []string{"b"}, false, "@b"},
+		{"target when no one is on call", "@org/secondary", nil, false, "@org/secondary"},
+		{"error without a target", "", nil, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{})
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "a", "A")
+			_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+			secondary, _ := AddSchedule(db, "secondary", "round-robin")
+			for i, login := range tt.users {
+				u, _ := AddUser(db, login, login)
+				_ = AssignUserToSchedule(db, secondary.ID, u.ID, i)
+			}
+			task, _ := AddTask(db, sch.ID, "org/repo", 3, "t", "desc", user.ID)
+
+			err := module.EscalateTask(task, OnCallEscalationTier{
+				ScheduleID: sch.ID, Level: 1, Target: tt.target, After: time.Hour, TargetScheduleID: secondary.ID,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EscalateTask: want error %v, got %v", tt.wantErr, err)
+			}
+			comments := recorder.Comments()
+			if tt.wantErr {
+				if len(comments) != 0 {
+					t.Errorf("want no comments, got %q", comments)
+				}
+				return
+			}
+			if len(comments) != 1 || !strings.HasSuffix(comments[0], "Escalating to: "+tt.wantTarget) {
+				t.Errorf("want one escalation to %s, got %q", tt.wantTarget, comments)
+			}
+		})
+	}
+}
+
+func TestEscalationWithoutAssignee(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        OnCallConfig
+		assignee   bool
+		want       string
+		wantAbsent string
+		wantCount  int64
+	}{
+		{
+			"assigned task",
+			OnCallConfig{EscalateToMaintainersWhenUnassigned: true, Maintainers: []string{"lead"}},
+			true, "Escalating to: @org/secondary", "@lead", 0,
+		},
+		{
+			"unassigned without fallback",
+			OnCallConfig{Maintainers: []string{"lead"}},
+			false, "Assigned to: no one\nEscalating to: @org/secondary", "@lead", 1,
+		},
+		{
+			"unassigned with fallback to maintainers",
+			OnCallConfig{EscalateToMaintainersWhenUnassigned: true, Maintainers: []string{"lead", "@other"}},
+			false, "Escalating to: @org/secondary, @lead, @other", "", 1,
+		},
+		{
+			"unassigned with fallback to maintainer team",
+			OnCallConfig{
+				EscalateToMaintainersWhenUnassigned: true,
+				Maintainers:                         []string{"lead"},
+				MaintainerTeam:                      "org/maintainers",
+			},
+			false, "Escalating to: @org/secondary, @org/maintainers", "@lead", 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, tt.cfg)
+			reader := sdkmetric.NewManualReader()
+			module.telemetry = &internal.TelemetryManager{
+				TracerProvider: sdktrace.NewTracerProvider(),
+				MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+			}
+			if err := module.telemetry.InitMetrics(); err != nil {
+				t.Fatalf("InitMetrics failed: %v", err)
+			}
+
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			var assignee int64
+			if tt.assignee {
+				user, _ := AddUser(db, "a", "A")
+				_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+				assignee = user.ID
+			}
+			if err := AddEscalationTier(db, OnCallEscalationTier{
+				ScheduleID: sch.ID, Level: 1, Target: "@org/secondary", After: time.Hour,
+			}); err != nil {
+				t.Fatalf("AddEscalationTier failed: %v", err)
+			}
+			task, _ := AddTask(db, sch.ID, "org/repo", 3, "t", "desc", assignee)
+			module.clock.(*internal.FakeClock).Set(task.CreatedAt.Add(2 * time.Hour))
+
+			if err := module.CheckUnacknowledgedTasks(); err != nil {
+				t.Fatalf("CheckUnacknowledgedTasks failed: %v", err)
+			}
+
+			comments := recorder.Comments()
+			if len(comments) != 1 {
+				t.Fatalf("want 1 comment, got %d: %q", len(comments), comments)
+			}
+			if !strings.Contains(comments[0], tt.want) {
+				t.Errorf("want comment containing %q, got %q", tt.want, comments[0])
+			}
+			if tt.wantAbsent != "" && strings.Contains(comments[0], tt.wantAbsent) {
+				t.Errorf("comment should not mention %q, got %q", tt.wantAbsent, comments[0])
+			}
+			if got := moduleCounter(t, reader, "otto.module.unassigned_escalations_total"); got != tt.wantCount {
+				t.Errorf("unassigned escalations: want %d, got %d", tt.wantCount, got)
+			}
+		})
+	}
+}
+
+// moduleCounter sums the data points of the oncall module in a counter.
+func moduleCounter(t *testing.T, reader *sdkmetric.ManualReader, name string) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == name {
+				for _, dp := range sum.DataPoints {
+					if module, _ := dp.Attributes.Value("module"); module.AsString() == "oncall" {
+						total += dp.Value
+					}
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestEscalationOfInvalidRepository(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{})
+	reader := sdkmetric.NewManualReader()
+	module.telemetry = &internal.TelemetryManager{
+		TracerProvider: sdktrace.NewTracerProvider(),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	}
+	if err := module.telemetry.InitMetrics(); err != nil {
+		t.Fatalf("InitMetrics failed: %v", err)
+	}
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+	if err := AddEscalationTier(db, OnCallEscalationTier{
+		ScheduleID: sch.ID, Level: 1, Target: "@org/secondary", After: time.Hour,
+	}); err != nil {
+		t.Fatalf("AddEscalationTier failed: %v", err)
+	}
+	// A task stored before repository names were validated
+	created := module.now().Add(-2 * time.Hour)
+	if _, err := db.Exec(
+		`INSERT INTO oncall_tasks (schedule_id, repo, issue_num, title, description, status, assigned_to, created_at)
+		 VALUES (?, 'not-a-repo', 3, 't', '', 'open', ?, ?)`,
+		sch.ID, user.ID, formatDBTime(created),
+	); err != nil {
+		t.Fatalf("inserting task failed: %v", err)
+	}
+
+	// Escalation errors are logged per task, so the check itself succeeds
+	if err := module.CheckUnacknowledgedTasks(); err != nil {
+		t.Fatalf("CheckUnacknowledgedTasks failed: %v", err)
+	}
+	if got := recorder.Comments(); len(got) != 0 {
+		t.Errorf("want no comments, got %q", got)
+	}
+	if got := moduleCounter(t, reader, "otto.module.invalid_repositories_total"); got != 1 {
+		t.Errorf("invalid repositories: want 1, got %d", got)
+	}
+}
+
+func TestSnoozeCommandPausesEscalation(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{SnoozeDuration: 3 * time.Hour, CommandCooldown: -1})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+	if err := AddEscalationTier(db, OnCallEscalationTier{
+		ScheduleID: sch.ID, Level: 1, Target: "@org/secondary", After: time.Hour,
+	}); err != nil {
+		t.Fatalf("AddEscalationTier failed: %v", err)
+	}
+	task, _ := AddTask(db, sch.ID, "org/repo", 3, "t", "desc", user.ID)
+	clock := module.clock.(*internal.FakeClock)
+	clock.Set(task.CreatedAt.Add(30 * time.Minute))
+
+	snooze := func(user, body string) string {
+		t.Helper()
+		before := len(recorder.Comments())
+		event := testutil.NewIssueCommentEvent("org/repo", 3, body, user)
+		if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+		comments := recorder.Comments()
+		if len(comments) != before+1 {
+			t.Fatalf("want a reply to %q, got %q", body, comments[before:])
+		}
+		return comments[before]
+	}
+
+	if got := snooze("someone", "/oncall snooze"); !strings.Contains(got, "only the assignee or maintainers") {
+		t.Errorf("third party snooze: got reply %q", got)
+	}
+	if got := snooze("a", "/oncall snooze soon"); !strings.Contains(got, "Invalid snooze duration") {
+		t.Errorf("invalid duration: got reply %q", got)
+	}
+	if got, _ := GetTask(db, task.ID); got.SnoozedUntil != nil {
+		t.Fatalf("rejected snoozes set snoozed_until to %v", got.SnoozedUntil)
+	}
+
+	// The configured default of 3h applies without a duration
+	until := clock.Now().Add(3 * time.Hour)
+	want := "snoozed until " + until.UTC().Format("Mon 2006-01-02 15:04 UTC")
+	if got := snooze("a", "/oncall snooze"); !strings.Contains(got, want) {
+		t.Errorf("snooze confirmation: want %q, got %q", want, got)
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		wantTier int
+	}{
+		{"snoozed past the tier threshold", until.Add(-time.Minute), 0},
+		{"escalated once the snooze ends", until, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Set(tt.at)
+			if err := module.CheckUnacknowledgedTasks(); err != nil {
+				t.Fatalf("CheckUnacknowledgedTasks failed: %v", err)
+			}
+			if got, _ := GetTask(db, task.ID); got.EscalationTier != tt.wantTier {
+				t.Errorf("escalation tier: want %d, got %d", tt.wantTier, got.EscalationTier)
+			}
+		})
+	}
+}
+
+func TestEscalationConcurrencyIsBounded(t *testing.T) {
+	const repos = 6
+	tests := []struct {
+		name        string
+		concurrency int
+	}{
+		{"sequential", 1},
+		{"two at a time", 2},
+		{"default", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{EscalationConcurrency: tt.concurrency})
+			recorder.Delay = 20 * time.Millisecond
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "a", "A")
+			tier := OnCallEscalationTier{ScheduleID: sch.ID, Level: 1, Target: "@t", After: time.Hour}
+			_ = AddEscalationTier(db, tier)
+			for i := range repos {
+				_, _ = AddTask(db, sch.ID, fmt.Sprintf("org/repo%d", i), 1, "t", "desc", user.ID)
+			}
+			module.clock.(*internal.FakeClock).Advance(2 * time.Hour)
+
+			if err := module.CheckUnacknowledgedTasks(); err != nil {
+				t.Fatalf("CheckUnacknowledgedTasks failed: %v", err)
+			}
+
+			if got := len(recorder.Comments()); got != repos {
+				t.Errorf("want %d escalations, got %d", repos, got)
+			}
+			limit := module.config.EscalationWorkers()
+			got := recorder.MaxInFlight()
+			if got > limit {
+				t.Errorf("want at most %d escalations at once, got %d", limit, got)
+			}
+			if limit > 1 && got < 2 {
+				t.Errorf("want escalations to run concurrently, got at most %d at once", got)
+			}
+		})
+	}
+}
+
+func TestWaitJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		jitter   time.Duration
+		canceled bool
+		want     bool
+	}{
+		{"no jitter", 0, false, true},
+		{"short jitter", time.Millisecond, false, true},
+		{"canceled during jitter", time.Hour, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &OnCallModule{config: OnCallConfig{EscalationJitter: tt.jitter}}
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.canceled {
+				cancel()
+			}
+			defer cancel()
+
+			if got := module.waitJitter(ctx); got != tt.want {
+				t.Errorf("waitJitter: want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStickyStatusComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+		// staleCommentID is a status comment that no longer exists.
+		staleCommentID int64
+		sticky         bool
+		wantComments   int
+		wantEdits      int
+		wantCommentID  int64
+	}{
+		{name: "new comment per escalation", targets: []string{"@t1", "@t1"}, wantComments: 2},
+		{
+			name:          "second escalation edits the first comment",
+			targets:       []string{"@t1", "@t1"},
+			sticky:        true,
+			wantComments:  1,
+			wantEdits:     1,
+			wantCommentID: 1,
+		},
+		{
+			name:          "new target gets a new comment",
+			targets:       []string{"@t1", "@t2"},
+			sticky:        true,
+			wantComments:  2,
+			wantCommentID: 2,
+		},
+		{
+			name:           "deleted comment is replaced",
+			targets:        []string{"@t1", "@t1"},
+			sticky:         true,
+			staleCommentID: 99,
+			wantComments:   1,
+			wantEdits:      1,
+			wantCommentID:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{StickyStatusComment: tt.sticky})
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "a", "A")
+			_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+			for level, target := range tt.targets {
+				_ = AddEscalationTier(db, OnCallEscalationTier{
+					ScheduleID: sch.ID,
+					Level:      level + 1,
+					Target:     target,
+					After:      time.Duration(level+1) * time.Hour,
+				})
+			}
+			task, _ := AddTask(db, sch.ID, "org/repo", 3, "t", "desc", user.ID)
+			if tt.staleCommentID != 0 {
+				_ = SetTaskStatusComment(db, task.ID, tt.staleCommentID, tt.targets[0])
+			}
+
+			clock := module.clock.(*internal.FakeClock)
+			for _, age := range []time.Duration{90 * time.Minute, 3 * time.Hour} {
+				clock.Set(task.CreatedAt.Add(age))
+				if err := module.CheckUnacknowledgedTasks(); err != nil {
+					t.Fatalf("CheckUnacknowledgedTasks failed: %v", err)
+				}
+			}
+
+			if got := len(recorder.Comments()); got != tt.wantComments {
+				t.Errorf("want %d comments, got %d", tt.wantComments, got)
+			}
+			edits := recorder.Edits()
+			if len(edits) != tt.wantEdits {
+				t.Fatalf("want %d edits, got %d", tt.wantEdits, len(edits))
+			}
+			if tt.wantEdits > 0 && !strings.Contains(edits[len(edits)-1], "(tier 2)") {
+				t.Errorf("want the last edit to show tier 2, got %q", edits[len(edits)-1])
+			}
+			if got, _ := GetTask(db, task.ID); got.StatusCommentID != tt.wantCommentID {
+				t.Errorf("status comment: want %d, got %d", tt.wantCommentID, got.StatusCommentID)
+			}
+		})
+	}
+}
+
+func TestCommentFooter(t *testing.T) {
+	const footer = "— otto • [docs](https://example.com/otto)"
+	module, db, recorder := newTestModule(t, OnCallConfig{CommentFooter: footer, StickyStatusComment: true})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+	for level := 1; level <= 2; level++ {
+		_ = AddEscalationTier(db, OnCallEscalationTier{
+			ScheduleID: sch.ID,
+			Level:      level,
+			Target:     "@t",
+			After:      time.Duration(level) * time.Hour,
+		})
+	}
+	task, _ := AddTask(db, sch.ID, "org/repo", 3, "t", "desc", user.ID)
+
+	// A command reply, then an escalation and its sticky edit
+	if _, err := module.HandleEvent("issue_comment",
+		testutil.NewIssueCommentEvent("org/repo", 3, "/oncall help", "a"), nil); err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	clock := module.clock.(*internal.FakeClock)
+	for _, age := range []time.Duration{90 * time.Minute, 3 * time.Hour} {
+		clock.Set(task.CreatedAt.Add(age))
+		if err := module.CheckUnacknowledgedTasks(); err != nil {
+			t.Fatalf("CheckUnacknowledgedTasks failed: %v", err)
+		}
+	}
+
+	bodies := append(recorder.Comments(), recorder.Edits()...)
+	if len(bodies) != 3 {
+		t.Fatalf("want 2 comments and 1 edit, got %q", bodies)
+	}
+	for _, body := range bodies {
+		if !strings.HasSuffix(body, "\n\n"+footer) {
+			t.Errorf("want the footer appended, got %q", body)
+		}
+	}
+	if bodies[0] != oncallHelp+"\n\n"+footer {
+		t.Errorf("help comment: got %q", bodies[0])
+	}
+}
+
+func TestCheckUnacknowledgedTasksSkipsAcknowledged(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+	for i, status := range []string{TaskStatusAck, TaskStatusDone} {
+		task, _ := AddTask(db, sch.ID, "org/repo", i+1, "t", "desc", user.ID)
+		_ = UpdateTaskStatus(db, task.ID, status)
+	}
+	module.clock.(*internal.FakeClock).Advance(48 * time.Hour)
+
+	if err := module.CheckUnacknowledgedTasks(); err != nil {
+		t.Fatalf("CheckUnacknowledgedTasks failed: %v", err)
+	}
+	if comments := recorder.Comments(); len(comments) != 0 {
+		t.Errorf("expected no escalations, got %q", comments)
+	}
+}
+
+func TestReassignCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		issueNum     int
+		target       string
+		user         string
+		wantComment  string
+		wantSchedule string
+		wantAssignee string
+	}{
+		{
+			name:        "no task for issue",
+			issueNum:    99,
+			target:      "infra",
+			wantComment: "There is no on-call task for this issue",
+		},
+		{
+			name:         "unknown schedule",
+			issueNum:     1,
+			target:       "missing",
+			wantComment:  "Schedule `missing` does not exist.",
+			wantSchedule: "primary",
+			wantAssignee: "a",
+		},
+		{
+			name:         "schedule without users",
+			issueNum:     1,
+			target:       "empty",
+			wantComment:  "Schedule `empty` has no one on call.",
+			wantSchedule: "primary",
+			wantAssignee: "a",
+		},
+		{
+			name:         "schedule name is case-insensitive",
+			issueNum:     1,
+			target:       "Infra",
+			wantComment:  "Reassigned to schedule `infra`",
+			wantSchedule: "infra",
+			wantAssignee: "b",
+		},
+		{
+			name:         "task is reassigned",
+			issueNum:     1,
+			target:       "infra",
+			wantComment:  "@b is now on call",
+			wantSchedule: "infra",
+			wantAssignee: "b",
+		},
+		{
+			name:         "maintainer reassigns",
+			issueNum:     1,
+			target:       "infra",
+			user:         "lead",
+			wantComment:  "@b is now on call",
+			wantSchedule: "infra",
+			wantAssignee: "b",
+		},
+		{
+			name:         "outsider is rejected",
+			issueNum:     1,
+			target:       "infra",
+			user:         "someone",
+			wantComment:  "@someone only the assignee or maintainers can reassign this task.",
+			wantSchedule: "primary",
+			wantAssignee: "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{Maintainers: []string{"lead"}})
+			primary, _ := AddSchedule(db, "primary", "round-robin")
+			infra, _ := AddSchedule(db, "infra", "round-robin")
+			_, _ = AddSchedule(db, "empty", "round-robin")
+			a, _ := AddUser(db, "a", "A")
+			b, _ := AddUser(db, "b", "B")
+			_ = AssignUserToSchedule(db, primary.ID, a.ID, 0)
+			_ = AssignUserToSchedule(db, infra.ID, b.ID, 0)
+			task, _ := AddTask(db, primary.ID, "org/repo", 1, "t", "desc", a.ID)
+			_ = SetTaskEscalationTier(db, task.ID, 1)
+
+			user := cmp.Or(tt.user, "a")
+			event := testutil.NewIssueCommentEvent("org/repo", tt.issueNum, "/oncall reassign "+tt.target, user)
+			handled, err := module.HandleEvent("issue_comment", event, nil)
+			if err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			if !handled {
+				t.Errorf("expected command to be handled")
+			}
+
+			comments := recorder.Comments()
+			if len(comments) != 1 || !strings.Contains(comments[0], tt.wantComment) {
+				t.Errorf("want one comment containing %q, got %q", tt.wantComment, comments)
+			}
+
+			if tt.wantSchedule == "" {
+				return
+			}
+			got, _ := GetTask(db, task.ID)
+			users := map[int64]string{a.ID: "a", b.ID: "b"}
+			schedules := map[int64]string{primary.ID: "primary", infra.ID: "infra"}
+			if schedules[got.ScheduleID] != tt.wantSchedule {
+				t.Errorf("schedule: want %q, got %q", tt.wantSchedule, schedules[got.ScheduleID])
+			}
+			if users[got.AssignedTo] != tt.wantAssignee {
+				t.Errorf("assignee: want %q, got %q", tt.wantAssignee, users[got.AssignedTo])
+			}
+			if tt.wantSchedule == "infra" && got.EscalationTier != 0 {
+				t.Errorf("escalation tier should restart, got %d", got.EscalationTier)
+			}
+		})
+	}
+}
+
+func TestRepeatedCommandIsSuppressed(t *testing.T) {
+	tests := []struct {
+		name         string
+		cooldown     time.Duration
+		advance      time.Duration
+		secondUser   string
+		wantComments int
+	}{
+		{name: "within default window", advance: 10 * time.Second, wantComments: 1},
+		{name: "other user within window", advance: 10 * time.Second, secondUser: "other", wantComments: 1},
+		{name: "other user after window", advance: DefaultCommandCooldown, secondUser: "other", wantComments: 2},
+		{name: "after default window", advance: DefaultCommandCooldown, wantComments: 2},
+		{name: "within configured window", cooldown: time.Minute, advance: 45 * time.Second, wantComments: 1},
+		{name: "cooldown disabled", cooldown: -1, wantComments: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{CommandCooldown: tt.cooldown})
+			primary, _ := AddSchedule(db, "primary", "round-robin")
+			a, _ := AddUser(db, "a", "A")
+			_ = AssignUserToSchedule(db, primary.ID, a.ID, 0)
+
+			for i, user := range []string{"someone", cmp.Or(tt.secondUser, "someone")} {
+				if i > 0 {
+					module.clock.(*internal.FakeClock).Advance(tt.advance)
+				}
+				event := testutil.NewIssueCommentEvent("org/repo", 1, "/oncall reassign missing", user)
+				handled, err := module.HandleEvent("issue_comment", event, nil)
+				if err != nil {
+					t.Fatalf("HandleEvent failed: %v", err)
+				}
+				if !handled {
+					t.Errorf("expected command to be handled")
+				}
+			}
+
+			if got := len(recorder.Comments()); got != tt.wantComments {
+				t.Errorf("want %d comments, got %d", tt.wantComments, got)
+			}
+		})
+	}
+}
+
+func TestPersistedCommandCooldownSurvivesRestart(t *testing.T) {
+	tests := []struct {
+		name         string
+		persist      bool
+		advance      time.Duration
+		wantComments int
+	}{
+		{name: "in-memory cooldown is lost", wantComments: 2},
+		{name: "persisted cooldown holds", persist: true, advance: 10 * time.Second, wantComments: 1},
+		{name: "persisted cooldown expires", persist: true, advance: DefaultCommandCooldown, wantComments: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, db, recorder := newTestModule(t, OnCallConfig{PersistCommandCooldown: tt.persist})
+			primary, _ := AddSchedule(db, "primary", "round-robin")
+			a, _ := AddUser(db, "a", "A")
+			_ = AssignUserToSchedule(db, primary.ID, a.ID, 0)
+			// The module after a restart, or another instance, shares only the database
+			second := &OnCallModule{
+				app:      first.app,
+				database: first.database,
+				config:   first.config,
+				clock:    first.clock,
+			}
+
+			event := testutil.NewIssueCommentEvent("org/repo", 1, "/oncall reassign missing", "someone")
+			if _, err := first.HandleEvent("issue_comment", event, nil); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			first.clock.(*internal.FakeClock).Advance(tt.advance)
+			if _, err := second.HandleEvent("issue_comment", event, nil); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+
+			if got := len(recorder.Comments()); got != tt.wantComments {
+				t.Errorf("want %d comments, got %d", tt.wantComments, got)
+			}
+		})
+	}
+}
+
+func TestLabelResolvesTask(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         OnCallConfig
+		startStatus string
+		action      string
+		label       string
+		sender      string
+		wantHandled bool
+		wantStatus  string
+	}{
+		{
+			name:        "default resolve label",
+			startStatus: TaskStatusOpen,
+			action:      "labeled",
+			label:       "resolved",
+			wantHandled: true,
+			wantStatus:  TaskStatusDone,
+		},
+		{
+			name:        "default dismiss label",
+			startStatus: TaskStatusOpen,
+			action:      "labeled",
+			label:       "wontfix",
+			wantHandled: true,
+			wantStatus:  TaskStatusDismissed,
+		},
+		{
+			name:        "configured dismiss label",
+			cfg:         OnCallConfig{DismissLabels: []string{"Invalid"}},
+			startStatus: TaskStatusAck,
+			action:      "labeled",
+			label:       "invalid",
+			wantHandled: true,
+			wantStatus:  TaskStatusDismissed,
+		},
+		{
+			name:        "dismiss label on a resolved task",
+			startStatus: TaskStatusDone,
+			action:      "labeled",
+			label:       "wontfix",
+			wantHandled: true,
+			wantStatus:  TaskStatusDone,
+		},
+		{
+			name:        "configured resolve label",
+			cfg:         OnCallConfig{ResolveLabels: []string{"Fixed"}},
+			startStatus: TaskStatusAck,
+			action:      "labeled",
+			label:       "fixed",
+			wantHandled: true,
+			wantStatus:  TaskStatusDone,
+		},
+		{
+			name:        "other label",
+			startStatus: TaskStatusOpen,
+			action:      "labeled",
+			label:       "bug",
+			wantStatus:  TaskStatusOpen,
+		},
+		{
+			name:        "resolve label from a non-maintainer",
+			startStatus: TaskStatusOpen,
+			action:      "labeled",
+			label:       "resolved",
+			sender:      "contributor",
+			wantStatus:  TaskStatusOpen,
+		},
+		{
+			name:        "dismiss label from a non-maintainer",
+			startStatus: TaskStatusOpen,
+			action:      "labeled",
+			label:       "wontfix",
+			sender:      "contributor",
+			wantStatus:  TaskStatusOpen,
+		},
+		{
+			name:        "unlabel without reopen",
+			startStatus: TaskStatusDone,
+			action:      "unlabeled",
+			label:       "resolved",
+			wantStatus:  TaskStatusDone,
+		},
+		{
+			name:        "unlabel reopens",
+			cfg:         OnCallConfig{ReopenOnUnlabel: true},
+			startStatus: TaskStatusDone,
+			action:      "unlabeled",
+			label:       "resolved",
+			wantHandled: true,
+			wantStatus:  TaskStatusOpen,
+		},
+		{
+			name:        "removing a dismiss label reopens",
+			cfg:         OnCallConfig{ReopenOnUnlabel: true},
+			startStatus: TaskStatusDismissed,
+			action:      "unlabeled",
+			label:       "wontfix",
+			wantHandled: true,
+			wantStatus:  TaskStatusOpen,
+		},
+		{
+			name:        "unlabel from a non-maintainer",
+			cfg:         OnCallConfig{ReopenOnUnlabel: true},
+			startStatus: TaskStatusDone,
+			action:      "unlabeled",
+			label:       "resolved",
+			sender:      "contributor",
+			wantStatus:  TaskStatusDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Maintainers = []string{"maintainer"}
+			module, db, _ := newTestModule(t, cfg)
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "oncaller", "On Caller")
+			task, _ := AddTask(db, sch.ID, "org/repo", 3, "t", "desc", user.ID)
+			if tt.startStatus != TaskStatusOpen {
+				_ = UpdateTaskStatus(db, task.ID, tt.startStatus)
+			}
+
+			event := &github.IssuesEvent{
+				Action: github.Ptr(tt.action),
+				Repo:   &github.Repository{FullName: github.Ptr("org/repo")},
+				Issue:  &github.Issue{Number: github.Ptr(3)},
+				Label:  &github.Label{Name: github.Ptr(tt.label)},
+				Sender: &github.User{Login: github.Ptr(cmp.Or(tt.sender, "maintainer"))},
+			}
+			handled, err := module.HandleEvent("issues", event, nil)
+			if err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			if handled != tt.wantHandled {
+				t.Errorf("handled: want %v, got %v", tt.wantHandled, handled)
+			}
+
+			got, _ := GetTask(db, task.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("task status: want %q, got %q", tt.wantStatus, got.Status)
+			}
+			if got.Status == TaskStatusOpen && got.CompletedAt != nil {
+				t.Errorf("reopened task should have no completion time, got %v", got.CompletedAt)
+			}
+		})
+	}
+}
+
+func TestAnnounceOnCallOnOpen(t *testing.T) {
+	tests := []struct {
+		name        string
+		enabled     bool
+		schedule    bool
+		members     bool
+		tierAfter   time.Duration
+		wantComment string
+	}{
+		{
+			name: "announces with the default threshold", enabled: true, schedule: true, members: true,
+			wantComment: "Thanks for the report! @oncaller is on call for `org/repo` and is expected to respond " +
+				"within 24 hours.",
+		},
+		{
+			name: "announces with the first configured tier", enabled: true, schedule: true, members: true,
+			tierAfter: 30 * time.Minute,
+			wantComment: "Thanks for the report! @oncaller is on call for `org/repo` and is expected to respond " +
+				"within 30 minutes.",
+		},
+		{name: "disabled", schedule: true, members: true},
+		{name: "no schedule", enabled: true},
+		{name: "no one on call", enabled: true, schedule: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{AnnounceOnCallOnOpen: tt.enabled})
+			if tt.schedule {
+				sch, _ := AddSchedule(db, "primary", "round-robin")
+				if tt.members {
+					user, _ := AddUser(db, "oncaller", "On Caller")
+					_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+				}
+				if tt.tierAfter > 0 {
+					tier := OnCallEscalationTier{ScheduleID: sch.ID, Level: 1, Target: "@org/b", After: tt.tierAfter}
+					if err := AddEscalationTier(db, tier); err != nil {
+						t.Fatalf("AddEscalationTier failed: %v", err)
+					}
+				}
+			}
+
+			event := &github.IssuesEvent{
+				Action: github.Ptr("opened"),
+				Repo:   &github.Repository{FullName: github.Ptr("org/repo")},
+				Issue:  &github.Issue{Number: github.Ptr(3)},
+				Sender: &github.User{Login: github.Ptr("reporter")},
+			}
+			handled, err := module.HandleEvent("issues", event, nil)
+			if err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			if want := tt.wantComment != ""; handled != want {
+				t.Errorf("handled: want %v, got %v", want, handled)
+			}
+			comments := recorder.Comments()
+			if tt.wantComment == "" && len(comments) != 0 {
+				t.Errorf("want no comments, got %q", comments)
+			}
+			if tt.wantComment != "" && (len(comments) != 1 || comments[0] != tt.wantComment) {
+				t.Errorf("want comment %q, got %q", tt.wantComment, comments)
+			}
+		})
+	}
+}
+
+func TestCurrentOnCallEndpoint(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{})
+	mux := http.NewServeMux()
+	module.RegisterRoutes(mux)
+
+	get := func() []CurrentOnCallView {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/oncall/current", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var views []CurrentOnCallView
+		if err := json.Unmarshal(rr.Body.Bytes(), &views); err != nil {
+			t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+		}
+		return views
+	}
+
+	if views := get(); len(views) != 0 {
+		t.Errorf("want no schedules, got %+v", views)
+	}
+
+	primary, _ := AddSchedule(db, "primary", "round-robin")
+	_, _ = AddSchedule(db, "org/repo on-call", "round-robin")
+	user, _ := AddUser(db, "oncaller", "On Caller")
+	_ = AssignUserToSchedule(db, primary.ID, user.ID, 0)
+	want := []CurrentOnCallView{
+		{ScheduleID: primary.ID + 1, Schedule: "org/repo on-call", Policy: RoundRobinPolicy},
+		{
+			ScheduleID: primary.ID, Schedule: "primary", Policy: RoundRobinPolicy,
+			GitHub: "oncaller", DisplayName: "On Caller",
+		},
+	}
+	if got := get(); !slices.Equal(got, want) {
+		t.Errorf("current on-call: want %+v, got %+v", want, got)
+	}
+}
+
+func TestReadOnlyModePostsNoComments(t *testing.T) {
+	tests := []struct {
+		name         string
+		withClient   bool
+		readOnly     bool
+		wantComments int
+	}{
+		{name: "read-only with client", withClient: true, readOnly: true},
+		{name: "read-only without client", readOnly: true},
+		{name: "normal mode", withClient: true, wantComments: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture, _, recorder := newTestModule(t, OnCallConfig{})
+			app := &internal.App{
+				Database: fixture.database,
+				Config: &config.AppConfig{Modules: map[string]any{
+					"oncall": map[string]any{"read_only": tt.readOnly},
+				}},
+			}
+			if tt.withClient {
+				app.GitHubClient = fixture.app.GitHubClient
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			module := &OnCallModule{}
+			if err := module.Initialize(ctx, app); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+			db := module.database.DB()
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "oncaller", "On Caller")
+			_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+			_, _ = AddTask(db, sch.ID, "org/repo", 1, "t", "desc", user.ID)
+
+			event := testutil.NewIssueCommentEvent("org/repo", 1, "/oncall reassign missing", "someone")
+			if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+
+			if got := len(recorder.Comments()); got != tt.wantComments {
+				t.Errorf("want %d comments, got %d", tt.wantComments, got)
+			}
+		})
+	}
+}
+
+func TestInitializeTelemetry(t *testing.T) {
+	configured := internal.NewNoopTelemetryManager()
+	tests := []struct {
+		name      string
+		telemetry *internal.TelemetryManager
+	}{
+		{name: "app telemetry", telemetry: configured},
+		{name: "no app telemetry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture, _, _ := newTestModule(t, OnCallConfig{})
+			module := &OnCallModule{}
+			app := &internal.App{Database: fixture.database, Telemetry: tt.telemetry}
+			if err := module.Initialize(context.Background(), app); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+			t.Cleanup(func() { _ = module.Shutdown(context.Background()) })
+
+			if module.telemetry == nil {
+				t.Fatal("module has no telemetry after Initialize")
+			}
+			if tt.telemetry != nil && module.telemetry != tt.telemetry {
+				t.Errorf("module did not keep the app's telemetry")
+			}
+		})
+	}
+}
+
+func TestInitializeTwiceStopsFirstCheckLoop(t *testing.T) {
+	fixture, _, _ := newTestModule(t, OnCallConfig{})
+	module := &OnCallModule{}
+	app := &internal.App{Database: fixture.database}
+	if err := module.Initialize(context.Background(), app); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	first := module.checksDone
+	if err := module.Initialize(context.Background(), app); err != nil {
+		t.Fatalf("second Initialize failed: %v", err)
+	}
+	t.Cleanup(func() { _ = module.Shutdown(context.Background()) })
+
+	select {
+	case <-first:
+	default:
+		t.Error("first check loop still running after the second Initialize")
+	}
+	select {
+	case <-module.checksDone:
+		t.Error("second check loop stopped")
+	default:
+	}
+}
+
+func TestSeedSchedulesOnInitialize(t *testing.T) {
+	fixture, db, _ := newTestModule(t, OnCallConfig{})
+	seed := func(members ...any) map[string]any {
+		return map[string]any{"oncall": map[string]any{"seed": []any{
+			map[string]any{
+				"name":       "primary",
+				"policy":     "sequential",
+				"recurrence": "FREQ=WEEKLY;BYDAY=MO;BYHOUR=9",
+				"members":    members,
+			},
+			map[string]any{"name": "triage", "members": []any{"alice"}},
+		}}}
+	}
+	initialize := func(modules map[string]any) {
+		t.Helper()
+		module := &OnCallModule{}
+		app := &internal.App{Database: fixture.database, Config: &config.AppConfig{Modules: modules}}
+		if err := module.Initialize(context.Background(), app); err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		_ = module.Shutdown(context.Background())
+	}
+	members := func(name string) []string {
+		t.Helper()
+		schedule, err := GetScheduleByName(db, name)
+		if err != nil || schedule == nil {
+			t.Fatalf("schedule %s: got %v, %v", name, schedule, err)
+		}
+		rels, _ := ListUsersForSchedule(db, schedule.ID)
+		var logins []string
+		for _, rel := range rels {
+			var login string
+			_ = db.QueryRow(`SELECT github FROM oncall_users WHERE id = ?`, rel.UserID).Scan(&login)
+			logins = append(logins, login)
+		}
+		return logins
+	}
+
+	initialize(seed("alice", "@bob"))
+	primary, _ := GetScheduleByName(db, "primary")
+	if primary.Policy != SequentialPolicy || primary.Recurrence != "FREQ=WEEKLY;BYDAY=MO;BYHOUR=9" {
+		t.Errorf("unexpected seeded schedule: %+v", primary)
+	}
+	if got := strings.Join(members("primary"), ","); got != "alice,bob" {
+		t.Errorf("primary members after first Initialize: want alice,bob, got %s", got)
+	}
+
+	// A member added by hand is kept, and seeding again only adds carol
+	dave, _ := AddUser(db, "dave", "Dave")
+	_ = AssignUserToSchedule(db, primary.ID, dave.ID, 2)
+	initialize(seed("alice", "bob", "carol"))
+	initialize(seed("alice", "bob", "carol"))
+
+	if got := strings.Join(members("primary"), ","); got != "alice,bob,dave,carol" {
+		t.Errorf("primary members after seeding again: want alice,bob,dave,carol, got %s", got)
+	}
+	if got := strings.Join(members("triage"), ","); got != "alice" {
+		t.Errorf("triage members: want alice, got %s", got)
+	}
+	var schedules, users int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM oncall_schedules`).Scan(&schedules)
+	_ = db.QueryRow(`SELECT COUNT(*) FROM oncall_users`).Scan(&users)
+	if schedules != 2 || users != 4 {
+		t.Errorf("want 2 schedules and 4 users, got %d and %d", schedules, users)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name       string
+		initialize bool
+	}{
+		{name: "without Initialize"},
+		{name: "after Initialize", initialize: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &OnCallModule{}
+			if tt.initialize {
+				fixture, _, _ := newTestModule(t, OnCallConfig{})
+				app := &internal.App{Database: fixture.database}
+				if err := module.Initialize(context.Background(), app); err != nil {
+					t.Fatalf("Initialize failed: %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			for i := range 2 {
+				if err := module.Shutdown(ctx); err != nil {
+					t.Errorf("Shutdown call %d failed: %v", i+1, err)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookAckFlow(t *testing.T) {
+	event := testutil.NewIssueCommentEvent("org/repo", 7, "/ack", "oncaller")
+
+	tests := []struct {
+		name       string
+		secret     string
+		payload    any
+		wantCode   int
+		wantStatus string
+	}{
+		{"signed ack is applied", testutil.DefaultSecret, event, http.StatusOK, TaskStatusAck},
+		{"bad signature is rejected", "wrong", event, http.StatusUnauthorized, TaskStatusOpen},
+		{"bad JSON is rejected", testutil.DefaultSecret, []byte(`{"action":`), http.StatusBadRequest, TaskStatusOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, h := newHarnessModule(t, OnCallConfig{})
+			db := module.database.DB()
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "oncaller", "On Caller")
+			_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+			task, _ := AddTask(db, sch.ID, "org/repo", 7, "t", "desc", user.ID)
+
+			h.Secret = tt.secret
+			if rr := h.Deliver(t, "issue_comment", tt.payload); rr.Code != tt.wantCode {
+				t.Fatalf("status code: want %d, got %d", tt.wantCode, rr.Code)
+			}
+
+			got, _ := GetTask(db, task.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("task status: want %q, got %q", tt.wantStatus, got.Status)
+			}
+		})
+	}
+}
+
+func TestEventsForDisabledRepositoriesAreIgnored(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{Repositories: []string{"org/*"}})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "oncaller", "On Caller")
+	_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+	task, _ := AddTask(db, sch.ID, "other/repo", 7, "t", "desc", user.ID)
+
+	event := testutil.NewIssueCommentEvent("other/repo", 7, "/ack", "oncaller")
+	handled, err := module.HandleEvent("issue_comment", event, nil)
+	if err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if handled {
+		t.Errorf("expected event for disabled repository to be ignored")
+	}
+	if got, _ := GetTask(db, task.ID); got.Status != TaskStatusOpen {
+		t.Errorf("task status: want %q, got %q", TaskStatusOpen, got.Status)
+	}
+}
+
+func TestRepositoryToggleCommand(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{
+		Repositories:    []string{"org/*"},
+		Maintainers:     []string{"boss"},
+		CommandCooldown: -1,
+	})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "oncaller", "On Caller")
+	_ = AssignUserToSchedule(db, sch.ID, user.ID, 0)
+	_, _ = AddTask(db, sch.ID, "other/repo", 7, "t", "desc", user.ID)
+
+	steps := []struct {
+		name        string
+		user        string
+		body        string
+		wantHandled bool
+		wantComment string
+		wantEnabled bool
+	}{
+		{"commands ignored while disabled", "boss", "/oncall disable", false, "", false},
+		{"only maintainers can enable", "someone", "/oncall enable", true, "only maintainers", false},
+		{"maintainer enables", "boss", "/oncall enable", true, "Oncall is now enabled for `other/repo`.", true},
+		{"commands run once enabled", "boss", "/oncall reassign missing", true, "does not exist", true},
+		{"maintainer disables", "boss", "/oncall disable", true, "Oncall is now disabled", false},
+	}
+
+	for _, step := range steps {
+		before := len(recorder.Comments())
+		event := testutil.NewIssueCommentEvent("other/repo", 7, step.body, step.user)
+		handled, err := module.HandleEvent("issue_comment", event, nil)
+		if err != nil {
+			t.Fatalf("%s: HandleEvent failed: %v", step.name, err)
+		}
+		if handled != step.wantHandled {
+			t.Errorf("%s: handled: want %v, got %v", step.name, step.wantHandled, handled)
+		}
+
+		comments := recorder.Comments()[before:]
+		if step.wantComment == "" && len(comments) != 0 {
+			t.Errorf("%s: want no comment, got %q", step.name, comments)
+		}
+		if step.wantComment != "" && (len(comments) != 1 || !strings.Contains(comments[0], step.wantComment)) {
+			t.Errorf("%s: want one comment containing %q, got %q", step.name, step.wantComment, comments)
+		}
+		if got := module.isRepositoryEnabled(db, "other/repo"); got != step.wantEnabled {
+			t.Errorf("%s: enabled: want %v, got %v", step.name, step.wantEnabled, got)
+		}
+	}
+
+	setting, err := GetRepoSetting(db, "Other/Repo")
+	if err != nil || setting == nil {
+		t.Fatalf("GetRepoSetting: want stored setting, got %v, %v", setting, err)
+	}
+	if setting.Enabled || setting.UpdatedBy != "boss" {
+		t.Errorf("stored setting: want disabled by boss, got %+v", setting)
+	}
+}
+
+func TestShowScheduleCommand(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{})
+	daily, _ := AddSchedule(db, "primary", "round-robin")
+	manual, _ := AddSchedule(db, "infra", "round-robin")
+	a, _ := AddUser(db, "a", "A")
+	b, _ := AddUser(db, "b", "B")
+	_, _ = AddUser(db, "idle", "Idle")
+	_ = AssignUserToSchedule(db, daily.ID, a.ID, 0)
+	_ = AssignUserToSchedule(db, daily.ID, b.ID, 1)
+	_ = AssignUserToSchedule(db, manual.ID, a.ID, 0)
+	if err := SetScheduleRecurrence(db, daily.ID, "FREQ=DAILY;BYHOUR=9"); err != nil {
+		t.Fatalf("SetScheduleRecurrence failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		user     string
+		body     string
+		want     []string
+		dontWant []string
+	}{
+		{
+			name: "defaults to the commenter",
+			user: "a",
+			body: "/oncall schedule",
+			want: []string{
+				"On-call shifts for @a in the next 14 days:",
+				"| `primary` | now |",
+				"- `infra`: on call now; handoffs are manual",
+			},
+		},
+		{
+			name:     "named user",
+			user:     "a",
+			body:     "/oncall schedule @B",
+			want:     []string{"On-call shifts for @b", "| `primary` |"},
+			dontWant: []string{"infra", "| now |"},
+		},
+		{
+			name: "unknown user",
+			user: "a",
+			body: "/oncall schedule @zed",
+			want: []string{"@zed is not an on-call user."},
+		},
+		{
+			name: "user without schedules",
+			user: "idle",
+			body: "/oncall schedule",
+			want: []string{"@idle is not on any schedule."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(recorder.Comments())
+			event := testutil.NewIssueCommentEvent("org/repo", 1, tt.body, tt.user)
+			handled, err := module.HandleEvent("issue_comment", event, nil)
+			if err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			if !handled {
+				t.Errorf("expected command to be handled")
+			}
+
+			comments := recorder.Comments()[before:]
+			if len(comments) != 1 {
+				t.Fatalf("want one comment, got %q", comments)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(comments[0], want) {
+					t.Errorf("comment %q does not contain %q", comments[0], want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(comments[0], dontWant) {
+					t.Errorf("comment %q should not contain %q", comments[0], dontWant)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownCommandFeedback(t *testing.T) {
+	tests := []struct {
+		name        string
+		suggest     bool
+		body        string
+		wantHandled bool
+		wantComment string
+	}{
+		{"typo of ack", true, "/ak", true, "Unknown command `/ak`, did you mean `/ack`?"},
+		{"typo of resolve", true, "thanks\n/reslove", true, "Unknown command `/reslove`, did you mean `/resolve`?"},
+		{"typo of dismiss", true, "/dimsiss", true, "Unknown command `/dimsiss`, did you mean `/dismiss`?"},
+		{"typo of oncall", true, "/oncal schedule", true, "Unknown command `/oncal`, did you mean `/oncall`?"},
+		{"help", false, "/oncall help", true, "Oncall commands:"},
+		{"suggestions disabled", false, "/ak", false, ""},
+		{"url", true, "see https://example.com/ak", false, ""},
+		{"path", true, "/usr/local/bin/ak", false, ""},
+		{"short unrelated command", true, "/cc @someone", false, ""},
+		{"unrelated command", true, "/lgtm", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, _, recorder := newTestModule(t, OnCallConfig{SuggestCommands: tt.suggest})
+
+			event := testutil.NewIssueCommentEvent("org/repo", 1, tt.body, "someone")
+			handled, err := module.HandleEvent("issue_comment", event, nil)
+			if err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			if handled != tt.wantHandled {
+				t.Errorf("handled: want %v, got %v", tt.wantHandled, handled)
+			}
+
+			comments := recorder.Comments()
+			if tt.wantComment == "" {
+				if len(comments) != 0 {
+					t.Errorf("want no comment, got %q", comments)
+				}
+				return
+			}
+			if len(comments) != 1 || !strings.Contains(comments[0], tt.wantComment) {
+				t.Errorf("want a comment containing %q, got %q", tt.wantComment, comments)
+			}
+		})
+	}
+}
+
+func TestImportUsersCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		user        string
+		body        string
+		wantComment string
+		wantUsers   []string
+		wantMissing []string
+	}{
+		{
+			name: "csv with header",
+			user: "maintainer",
+			body: "/oncall import\n```csv\ngithub_username,name,email\n" +
+				"alice,Alice,a@example.com\nExisting,E\n@bob,\n```",
+			wantComment: "Imported users: 2 created, 1 skipped",
+			wantUsers:   []string{"alice", "bob"},
+		},
+		{
+			name:        "markdown table",
+			user:        "maintainer",
+			body:        "/oncall import\n```\n| github_username | name |\n|---|---|\n| carol | Carol |\n```",
+			wantComment: "Imported users: 1 created, 0 skipped",
+			wantUsers:   []string{"carol"},
+		},
+		{
+			name:        "invalid login imports nothing",
+			user:        "maintainer",
+			body:        "/oncall import\n```\ndave,Dave\n-bad-,Bad\nx_y,XY\n```",
+			wantComment: "No users were imported:\n- line 2: `-bad-` is not a valid GitHub login\n- line 3:",
+			wantMissing: []string{"dave"},
+		},
+		{
+			name:        "without code block",
+			user:        "maintainer",
+			body:        "/oncall import",
+			wantComment: "Put the users to import in a code block",
+		},
+		{
+			name:        "not a maintainer",
+			user:        "someone",
+			body:        "/oncall import\n```\nerin,Erin\n```",
+			wantComment: "@someone only maintainers can import users.",
+			wantMissing: []string{"erin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{Maintainers: []string{"maintainer"}})
+			_, _ = AddUser(db, "existing", "Existing")
+
+			event := testutil.NewIssueCommentEvent("org/repo", 1, tt.body, tt.user)
+			if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+
+			comments := recorder.Comments()
+			if len(comments) != 1 || !strings.Contains(comments[0], tt.wantComment) {
+				t.Errorf("want a comment containing %q, got %q", tt.wantComment, comments)
+			}
+			for _, login := range tt.wantUsers {
+				if u, _ := GetUserByGitHub(db, login); u == nil {
+					t.Errorf("user %s was not imported", login)
+				}
+			}
+			for _, login := range tt.wantMissing {
+				if u, _ := GetUserByGitHub(db, login); u != nil {
+					t.Errorf("user %s was imported", login)
+				}
+			}
+		})
+	}
+}
+
+func TestBotCommentsAreIgnored(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	task, _ := AddTask(db, sch.ID, "org/repo", 1, "t", "desc", user.ID)
+
+	event := testutil.NewIssueCommentEvent("org/repo", 1, "did you mean `/resolve`?", "otto[bot]")
+	event.Comment.User.Type = github.Ptr("Bot")
+	handled, err := module.HandleEvent("issue_comment", event, nil)
+	if err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if handled {
+		t.Errorf("bot comment was handled")
+	}
+	if got, _ := GetTask(db, task.ID); got.Status != TaskStatusOpen {
+		t.Errorf("task status: want %q, got %q", TaskStatusOpen, got.Status)
+	}
+}
+
+func TestResolveCommandFromPullRequestReview(t *testing.T) {
+	newReviewEvent := func(action, body string) *github.PullRequestReviewEvent {
+		return &github.PullRequestReviewEvent{
+			Action:      github.Ptr(action),
+			Repo:        &github.Repository{FullName: github.Ptr("org/repo")},
+			PullRequest: &github.PullRequest{Number: github.Ptr(12)},
+			Review: &github.PullRequestReview{
+				Body: github.Ptr(body),
+				User: &github.User{Login: github.Ptr("reviewer")},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		event       *github.PullRequestReviewEvent
+		wantHandled bool
+		wantStatus  string
+	}{
+		{"submitted review resolves", newReviewEvent("submitted", "LGTM\n/resolve"), true, TaskStatusDone},
+		{"edited review is ignored", newReviewEvent("edited", "/resolve"), false, TaskStatusOpen},
+		{"review without command", newReviewEvent("submitted", "LGTM"), false, TaskStatusOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, _ := newTestModule(t, OnCallConfig{})
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "reviewer", "Reviewer")
+			task, _ := AddTask(db, sch.ID, "org/repo", 12, "t", "desc", user.ID)
+
+			handled, err := module.HandleEvent("pull_request_review", tt.event, nil)
+			if err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			if handled != tt.wantHandled {
+				t.Errorf("handled: want %v, got %v", tt.wantHandled, handled)
+			}
+			if got, _ := GetTask(db, task.ID); got.Status != tt.wantStatus {
+				t.Errorf("task status: want %q, got %q", tt.wantStatus, got.Status)
+			}
+		})
+	}
+}
+
+func TestResolveCommandPermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      OnCallConfig
+		user        string
+		wantStatus  string
+		wantComment string
+	}{
+		{"assignee resolves", OnCallConfig{}, "oncaller", TaskStatusDone, ""},
+		{"maintainer resolves", OnCallConfig{Maintainers: []string{"lead"}}, "lead", TaskStatusDone, ""},
+		{"author resolves own issue", OnCallConfig{AllowAuthorResolve: true}, "reporter", TaskStatusDone, ""},
+		{
+			"author rejected without allow_author_resolve", OnCallConfig{}, "reporter", TaskStatusOpen,
+			"@reporter only the assignee or maintainers can resolve this task.",
+		},
+		{
+			"third party rejected", OnCallConfig{AllowAuthorResolve: true}, "someone", TaskStatusOpen,
+			"@someone only the author, the assignee or maintainers can resolve this task.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, comments := newTestModule(t, tt.config)
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "oncaller", "On Caller")
+			_, _ = AddUser(db, "someone", "Someone")
+			task, _ := AddTask(db, sch.ID, "org/repo", 7, "t", "desc", user.ID)
+
+			event := testutil.NewIssueCommentEvent("org/repo", 7, "/resolve", tt.user)
+			event.Issue.User = &github.User{Login: github.Ptr("reporter")}
+			if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			if got, _ := GetTask(db, task.ID); got.Status != tt.wantStatus {
+				t.Errorf("task status: want %q, got %q", tt.wantStatus, got.Status)
+			}
+			got := comments.Comments()
+			if tt.wantComment == "" && len(got) != 0 {
+				t.Errorf("want no comments, got %q", got)
+			}
+			if tt.wantComment != "" && (len(got) != 1 || got[0] != tt.wantComment) {
+				t.Errorf("want comment %q, got %q", tt.wantComment, got)
+			}
+		})
+	}
+}
+
+func TestDismissCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      OnCallConfig
+		user        string
+		wantStatus  string
+		wantComment string
+	}{
+		{"assignee dismisses", OnCallConfig{}, "oncaller", TaskStatusDismissed, ""},
+		{"maintainer dismisses", OnCallConfig{Maintainers: []string{"lead"}}, "lead", TaskStatusDismissed, ""},
+		{
+			"author rejected even with allow_author_resolve", OnCallConfig{AllowAuthorResolve: true}, "reporter",
+			TaskStatusOpen, "@reporter only the assignee or maintainers can dismiss this task.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, comments := newTestModule(t, tt.config)
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "oncaller", "On Caller")
+			task, _ := AddTask(db, sch.ID, "org/repo", 7, "t", "desc", user.ID)
+
+			event := testutil.NewIssueCommentEvent("org/repo", 7, "/dismiss", tt.user)
+			event.Issue.User = &github.User{Login: github.Ptr("reporter")}
+			if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+			got, _ := GetTask(db, task.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("task status: want %q, got %q", tt.wantStatus, got.Status)
+			}
+			if tt.wantStatus == TaskStatusDismissed && got.CompletedAt == nil {
+				t.Errorf("want the dismissal time recorded")
+			}
+			c := comments.Comments()
+			if tt.wantComment == "" && len(c) != 0 {
+				t.Errorf("want no comments, got %q", c)
+			}
+			if tt.wantComment != "" && (len(c) != 1 || c[0] != tt.wantComment) {
+				t.Errorf("want comment %q, got %q", tt.wantComment, c)
+			}
+		})
+	}
+}
+
+func TestDismissedTasksStayDismissed(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{ReopenOnUnlabel: true, Maintainers: []string{"oncaller"}})
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "oncaller", "On Caller")
+	task, _ := AddTask(db, sch.ID, "org/repo", 7, "t", "desc", user.ID)
+	if err := UpdateTaskStatus(db, task.ID, TaskStatusDismissed); err != nil {
+		t.Fatalf("UpdateTaskStatus failed: %v", err)
+	}
+
+	unlabeled := testutil.NewIssuesEvent("org/repo", 7, "unlabeled", "oncaller")
+	unlabeled.Label = &github.Label{Name: github.Ptr("resolved")}
+	steps := []struct {
+		name       string
+		eventType  string
+		event      any
+		wantStatus string
+	}{
+		// Neither /resolve nor closing the issue turns a dismissal into a resolution
+		{
+			"resolve", "issue_comment", testutil.NewIssueCommentEvent("org/repo", 7, "/resolve", "oncaller"),
+			TaskStatusDismissed,
+		},
+		{"close", "issues", testutil.NewIssuesEvent("org/repo", 7, "closed", "oncaller"), TaskStatusDismissed},
+		{"reopen", "issues", unlabeled, TaskStatusOpen},
+	}
+	for _, step := range steps {
+		if _, err := module.HandleEvent(step.eventType, step.event, nil); err != nil {
+			t.Fatalf("%s: HandleEvent failed: %v", step.name, err)
+		}
+		if got, _ := GetTask(db, task.ID); got.Status != step.wantStatus {
+			t.Errorf("%s: task status: want %q, got %q", step.name, step.wantStatus, got.Status)
+		}
+		if step.wantStatus == TaskStatusDismissed {
+			if n, _ := CountUnfinishedTasksForSchedule(db, sch.ID); n != 0 {
+				t.Errorf("%s: unfinished tasks: want 0, got %d", step.name, n)
+			}
+		}
+	}
+}
+
+func TestDeliveryLog(t *testing.T) {
+	var log deliveryLog
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name      string
+		release   bool
+		id        string
+		at        time.Duration
+		wantClaim bool
+		wantKept  int
+	}{
+		{name: "first delivery", id: "d1", wantClaim: true, wantKept: 1},
+		{name: "second delivery", id: "d2", at: time.Hour, wantClaim: true, wantKept: 2},
+		{name: "redelivery", id: "d1", at: 2 * time.Hour, wantKept: 2},
+		{name: "redelivery after retention", id: "d1", at: deliveryRetention, wantClaim: true, wantKept: 2},
+		{name: "only expired deliveries are evicted", id: "d2", at: deliveryRetention, wantKept: 2},
+		{name: "released delivery", release: true, id: "d2", at: deliveryRetention, wantClaim: true, wantKept: 2},
+		{name: "eviction", id: "d3", at: 3 * deliveryRetention, wantClaim: true, wantKept: 1},
+	}
+	for _, step := range steps {
+		if step.release {
+			log.release(step.id)
+		}
+		if got := log.claim(step.id, start.Add(step.at)); got != step.wantClaim {
+			t.Errorf("%s: claim: want %v, got %v", step.name, step.wantClaim, got)
+		}
+		if got := log.order.Len(); got != step.wantKept || len(log.seen) != step.wantKept {
+			t.Errorf("%s: want %d deliveries kept, got %d in order and %d seen",
+				step.name, step.wantKept, got, len(log.seen))
+		}
+	}
+}
+
+func TestHandleEnvelopeIgnoresRedeliveries(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{CommandCooldown: -1})
+	clock := module.clock.(*internal.FakeClock)
+
+	tests := []struct {
+		name         string
+		deliveryID   string
+		advance      time.Duration
+		wantComments int
+	}{
+		{"first delivery", "d1", 0, 1},
+		{"redelivery is ignored", "d1", time.Hour, 1},
+		{"another delivery", "d2", 0, 2},
+		{"event without a delivery ID", "", 0, 3},
+		{"events without a delivery ID are not deduplicated", "", 0, 4},
+		{"redelivery after the retention window", "d1", deliveryRetention, 5},
+	}
+	for _, tt := range tests {
+		clock.Advance(tt.advance)
+		if _, err := PruneDeliveries(db, clock.Now().Add(-deliveryRetention)); err != nil {
+			t.Fatalf("PruneDeliveries failed: %v", err)
+		}
+		deliverHelp(t, module, tt.deliveryID)
+		if got := len(recorder.Comments()); got != tt.wantComments {
+			t.Errorf("%s: want %d comments, got %d", tt.name, tt.wantComments, got)
+		}
+	}
+}
+
+// deliverHelp hands the module an /oncall help comment with a delivery ID.
+func deliverHelp(t *testing.T, module *OnCallModule, deliveryID string) {
+	t.Helper()
+	env := &internal.EventEnvelope{
+		Type:       "issue_comment",
+		Event:      testutil.NewIssueCommentEvent("org/repo", 1, "/oncall help", "a"),
+		DeliveryID: deliveryID,
+	}
+	if handled, err := module.HandleEnvelope(env); err != nil || !handled {
+		t.Fatalf("HandleEnvelope(%q) = %v, %v", deliveryID, handled, err)
+	}
+}
+
+func TestDeliveriesAreRecordedDurably(t *testing.T) {
+	first, db, recorder := newTestModule(t, OnCallConfig{CommandCooldown: -1})
+	// A second instance, or the module after a restart, shares only the database
+	second := &OnCallModule{
+		app:      first.app,
+		database: first.database,
+		config:   first.config,
+		clock:    first.clock,
+	}
+
+	deliverHelp(t, first, "d1")
+	deliverHelp(t, second, "d1")
+	deliverHelp(t, second, "d1")
+	if got := len(recorder.Comments()); got != 1 {
+		t.Errorf("want 1 comment for a replayed delivery, got %d", got)
+	}
+
+	// A failed delivery is forgotten so that a redelivery retries it
+	if _, err := db.Exec(`DROP TABLE oncall_tasks`); err != nil {
+		t.Fatalf("failed to drop tasks: %v", err)
+	}
+	env := &internal.EventEnvelope{
+		Type:       "issue_comment",
+		Event:      testutil.NewIssueCommentEvent("org/repo", 1, "/resolve", "a"),
+		DeliveryID: "d2",
+	}
+	if _, err := second.HandleEnvelope(env); err == nil {
+		t.Fatalf("want an error without a tasks table")
+	}
+	if recorded, err := RecordDelivery(db, "d2", time.Now()); err != nil || !recorded {
+		t.Errorf("failed delivery still recorded: %v, %v", recorded, err)
+	}
+}
+
+func TestEventLogsCarryIssueContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		event     any
+		wantIssue int
+	}{
+		{
+			name:      "issue comment",
+			eventType: "issue_comment",
+			event:     testutil.NewIssueCommentEvent("org/repo", 7, "/resolve", "a"),
+			wantIssue: 7,
+		},
+		{
+			name:      "pull request review",
+			eventType: "pull_request_review",
+			event: &github.PullRequestReviewEvent{
+				Action:      github.Ptr("submitted"),
+				Repo:        &github.Repository{FullName: github.Ptr("org/repo")},
+				PullRequest: &github.PullRequest{Number: github.Ptr(12)},
+				Review: &github.PullRequestReview{
+					Body: github.Ptr("/resolve"),
+					User: &github.User{Login: github.Ptr("a")},
+				},
+			},
+			wantIssue: 12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, _ := newTestModule(t, OnCallConfig{})
+			var buf bytes.Buffer
+			module.logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})).
+				With("module", module.Name())
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			user, _ := AddUser(db, "a", "A")
+			_, _ = AddTask(db, sch.ID, "org/repo", tt.wantIssue, "t", "desc", user.ID)
+
+			if _, err := module.HandleEvent(tt.eventType, tt.event, nil); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) == 0 || lines[0] == "" {
+				t.Fatal("expected log output")
+			}
+			for _, line := range lines {
+				var record struct {
+					Module   string `json:"module"`
+					Repo     string `json:"repo"`
+					IssueNum int    `json:"issue_num"`
+				}
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("invalid log line %q: %v", line, err)
+				}
+				if record.Module != "oncall" || record.Repo != "org/repo" || record.IssueNum != tt.wantIssue {
+					t.Errorf("log line missing event context: %s", line)
+				}
+			}
+		})
+	}
+}
+
+func TestAckUsesDefaultScheduleTemplate(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{DefaultSchedule: "{{.Repo}} on-call", CommandCooldown: -1})
+	primary, _ := AddSchedule(db, "primary", "round-robin")
+	repoSchedule, _ := AddSchedule(db, "org/repo on-call", "round-robin")
+	a, _ := AddUser(db, "a", "A")
+	b, _ := AddUser(db, "b", "B")
+	_ = AssignUserToSchedule(db, primary.ID, a.ID, 0)
+	_ = AssignUserToSchedule(db, repoSchedule.ID, b.ID, 0)
+	task, _ := AddTask(db, repoSchedule.ID, "org/repo", 7, "t", "desc", b.ID)
+
+	for _, user := range []string{"a", "b"} {
+		event := testutil.NewIssueCommentEvent("org/repo", 7, "/ack", user)
+		if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+		got, _ := GetTask(db, task.ID)
+		want := TaskStatusOpen
+		if user == "b" {
+			want = TaskStatusAck
+		}
+		if got.Status != want {
+			t.Errorf("after /ack by %q: want status %q, got %q", user, want, got.Status)
+		}
+	}
+}
+
+func TestAckUsesFallbackSchedule(t *testing.T) {
+	tests := []struct {
+		name      string
+		repo      string
+		fallback  string
+		wantAcker string
+	}{
+		{"repository schedule present", "org/repo", "triage", "repo-oncall"},
+		{"fallback used", "org/other", "triage", "triager"},
+		{"no fallback configured", "org/other", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, _ := newTestModule(t, OnCallConfig{
+				DefaultSchedule:  "{{.Repo}} on-call",
+				FallbackSchedule: tt.fallback,
+				CommandCooldown:  -1,
+			})
+			repoSchedule, _ := AddSchedule(db, "org/repo on-call", "round-robin")
+			triage, _ := AddSchedule(db, "triage", "round-robin")
+			repoOnCall, _ := AddUser(db, "repo-oncall", "R")
+			triager, _ := AddUser(db, "triager", "T")
+			_ = AssignUserToSchedule(db, repoSchedule.ID, repoOnCall.ID, 0)
+			_ = AssignUserToSchedule(db, triage.ID, triager.ID, 0)
+			task, _ := AddTask(db, triage.ID, tt.repo, 7, "t", "desc", triager.ID)
+
+			for _, user := range []string{"repo-oncall", "triager"} {
+				event := testutil.NewIssueCommentEvent(tt.repo, 7, "/ack", user)
+				_, _ = module.HandleEvent("issue_comment", event, nil)
+			}
+
+			got, _ := GetTask(db, task.ID)
+			if got.AckedBy != tt.wantAcker {
+				t.Errorf("acknowledged by: want %q, got %q", tt.wantAcker, got.AckedBy)
+			}
+		})
+	}
+}
+
+func TestScheduleCache(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{
+		Maintainers:      []string{"lead"},
+		ScheduleCacheTTL: time.Minute,
+	})
+	clock := module.clock.(*internal.FakeClock)
+	lookup := func(name string) *OnCallSchedule {
+		t.Helper()
+		schedule, err := module.scheduleByName(db, name)
+		if err != nil {
+			t.Fatalf("scheduleByName failed: %v", err)
+		}
+		return schedule
+	}
+
+	primary, _ := AddSchedule(db, "primary", "round-robin")
+	if got := lookup("primary"); got == nil || got.ID != primary.ID {
+		t.Fatalf("want schedule %d, got %+v", primary.ID, got)
+	}
+	if got := lookup("missing"); got != nil {
+		t.Fatalf("want no schedule, got %+v", got)
+	}
+
+	// Writes made behind the module's back are hidden until the TTL passes
+	if _, err := db.Exec(`UPDATE oncall_schedules SET name = 'renamed' WHERE id = ?`, primary.ID); err != nil {
+		t.Fatalf("failed to rename schedule: %v", err)
+	}
+	_, _ = AddSchedule(db, "missing", "round-robin")
+	clock.Advance(59 * time.Second)
+	if lookup("primary") == nil {
+		t.Errorf("want cached schedule before the TTL passes")
+	}
+	if lookup("missing") != nil {
+		t.Errorf("want cached absence before the TTL passes")
+	}
+	clock.Advance(time.Second)
+	if got := lookup("primary"); got != nil {
+		t.Errorf("want no schedule after the TTL passes, got %+v", got)
+	}
+	if lookup("missing") == nil {
+		t.Errorf("want new schedule after the TTL passes")
+	}
+
+	// Removing a schedule through the module invalidates it at once
+	event := testutil.NewIssueCommentEvent("org/repo", 1, "/oncall remove schedule missing", "lead")
+	if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if got := lookup("missing"); got != nil {
+		t.Errorf("want no schedule after removal, got %+v", got)
+	}
+}
+
+func TestOnCallLookupsUseScheduleCache(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{ScheduleCacheTTL: time.Minute})
+	primary, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	_ = AssignUserToSchedule(db, primary.ID, user.ID, 0)
+	task, _ := AddTask(db, primary.ID, "org/repo", 1, "t", "desc", user.ID)
+	if _, err := module.scheduleByName(db, "primary"); err != nil {
+		t.Fatalf("scheduleByName failed: %v", err)
+	}
+
+	// Only the cache still knows the schedule by its old name
+	if _, err := db.Exec(`UPDATE oncall_schedules SET name = 'renamed' WHERE id = ?`, primary.ID); err != nil {
+		t.Fatalf("failed to rename schedule: %v", err)
+	}
+
+	onCall, err := module.currentOnCallUser(db, "primary")
+	if err != nil || onCall.GitHub != "a" {
+		t.Errorf("currentOnCallUser: want a, got %+v, %v", onCall, err)
+	}
+	event := testutil.NewIssueCommentEvent("org/repo", 1, "/ack", "a")
+	if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if got, _ := GetTask(db, task.ID); got.Status != TaskStatusAck {
+		t.Errorf("task status: want %q, got %q", TaskStatusAck, got.Status)
+	}
+	if announced, err := module.announceOnCall(db, module.log(), "org/repo", 2); err != nil || !announced {
+		t.Errorf("announceOnCall: want an announcement, got %v, %v", announced, err)
+	}
+	if comments := recorder.Comments(); len(comments) != 1 || !strings.Contains(comments[0], "@a is on call") {
+		t.Errorf("want one announcement for @a, got %q", comments)
+	}
+}
+
+func TestScheduleCacheForgetsSeededSchedules(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{ScheduleCacheTTL: time.Minute})
+	if schedule, err := module.scheduleByName(db, "primary"); err != nil || schedule != nil {
+		t.Fatalf("want no schedule before seeding, got %+v, %v", schedule, err)
+	}
+
+	// Seeding the schedule clears its cached absence
+	app := &internal.App{Database: module.database, Config: &config.AppConfig{Modules: map[string]any{
+		"oncall": map[string]any{
+			"schedule_cache_ttl": "1m",
+			"seed":               []any{map[string]any{"name": "primary"}},
+		},
+	}}}
+	if err := module.Initialize(context.Background(), app); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	t.Cleanup(func() { _ = module.Shutdown(context.Background()) })
+
+	schedule, err := module.scheduleByName(db, "primary")
+	if err != nil || schedule == nil {
+		t.Errorf("want seeded schedule, got %+v, %v", schedule, err)
+	}
+}
+
+func TestScheduleCacheIsSized(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{ScheduleCacheTTL: time.Minute, ScheduleCacheSize: 4})
+	for i := range 10 {
+		if _, err := module.scheduleByName(db, fmt.Sprintf("s%d", i)); err != nil {
+			t.Fatalf("scheduleByName failed: %v", err)
+		}
+		if n := module.scheduleCache().Len(); n > 4 {
+			t.Fatalf("cache holds %d entries, want at most 4", n)
+		}
+	}
+	for i := range 10 {
+		_, ok := module.scheduleCache().Get(fmt.Sprintf("s%d", i))
+		if want := i >= 6; ok != want {
+			t.Errorf("s%d cached: want %v, got %v", i, want, ok)
+		}
+	}
+}
+
+func TestScheduleCacheConcurrentAccess(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{ScheduleCacheTTL: time.Minute, ScheduleCacheSize: 2})
+	names := []string{"a", "b", "c"}
+	for _, name := range names {
+		_, _ = AddSchedule(db, name, "round-robin")
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				name := names[(i+j)%len(names)]
+				if j%10 == 0 {
+					module.scheduleCache().Invalidate(name)
+				}
+				schedule, err := module.scheduleByName(db, name)
+				if err != nil || schedule == nil || schedule.Name != name {
+					t.Errorf("lookup %q: got %+v, %v", name, schedule, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestEscalationCheckIsTraced(t *testing.T) {
+	module, db, _ := newTestModule(t, OnCallConfig{})
+	recorder := tracetest.NewSpanRecorder()
+	module.telemetry = &internal.TelemetryManager{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+	}
+
+	sch, _ := AddSchedule(db, "primary", "round-robin")
+	user, _ := AddUser(db, "a", "A")
+	_, _ = AddTask(db, sch.ID, "org/repo", 5, "t", "desc", user.ID)
+	_, _ = AddTask(db, sch.ID, "org/repo", 6, "t", "desc", user.ID)
+	module.clock.(*internal.FakeClock).Advance(48 * time.Hour)
+
+	if err := module.CheckUnacknowledgedTasks(); err != nil {
+		t.Fatalf("CheckUnacknowledgedTasks failed: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "oncall.escalation_check" {
+		t.Fatalf("want one oncall.escalation_check span, got %v", spans)
+	}
+	events := spans[0].Events()
+	if len(events) != 2 {
+		t.Fatalf("want 2 escalation events, got %d", len(events))
+	}
+	for i, want := range []int64{5, 6} {
+		var got attribute.Value
+		for _, kv := range events[i].Attributes {
+			if kv.Key == "issue_num" {
+				got = kv.Value
+			}
+		}
+		if events[i].Name != "escalation" || got.AsInt64() != want {
+			t.Errorf("event %d: want escalation of issue %d, got %q with %v",
+				i, want, events[i].Name, events[i].Attributes)
+		}
+	}
+}
+
+func TestMaintainerTeam(t *testing.T) {
+	module, db, recorder := newTestModule(t, OnCallConfig{
+		Maintainers:       []string{"static"},
+		MaintainerTeam:    "org/maintainers",
+		MaintainerTeamTTL: time.Minute,
+	})
+	_, _ = AddSchedule(db, "primary", "round-robin")
+
+	var mu sync.Mutex
+	members := []string{"alice"}
+	status := http.StatusOK
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/org/teams/maintainers/members" {
+			recorder.ServeHTTP(w, r)
+			return
+		}
+		requests.Add(1)
+		mu.Lock()
+		defer mu.Unlock()
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		users := make([]*github.User, len(members))
+		for i, login := range members {
+			users[i] = &github.User{Login: github.Ptr(login)}
+		}
+		_ = json.NewEncoder(w).Encode(users)
+	}))
+	t.Cleanup(srv.Close)
+	module.app.GitHubClient.BaseURL, _ = url.Parse(srv.URL + "/")
+	clock := module.clock.(*internal.FakeClock)
+
+	steps := []struct {
+		name         string
+		members      []string
+		status       int
+		advance      time.Duration
+		user         string
+		wantAllowed  bool
+		wantRequests int32
+	}{
+		{name: "team member", user: "Alice", wantAllowed: true, wantRequests: 1},
+		{name: "static maintainer not in team", user: "static", wantRequests: 1},
+		{name: "cached until ttl", members: []string{"bob"}, user: "alice", wantAllowed: true, wantRequests: 1},
+		{name: "refreshed after ttl", advance: time.Minute, user: "bob", wantAllowed: true, wantRequests: 2},
+		{name: "removed member", user: "alice", wantRequests: 2},
+		{
+			name:         "static fallback when team fails",
+			status:       http.StatusNotFound,
+			advance:      time.Minute,
+			user:         "static",
+			wantAllowed:  true,
+			wantRequests: 3,
+		},
+	}
+
+	for i, step := range steps {
+		mu.Lock()
+		if step.members != nil {
+			members = step.members
+		}
+		if step.status != 0 {
+			status = step.status
+		}
+		mu.Unlock()
+		clock.Advance(step.advance)
+
+		// Each step removes a different schedule so the command isn't suppressed
+		name := fmt.Sprintf("s%d", i)
+		_, _ = AddSchedule(db, name, "round-robin")
+		event := testutil.NewIssueCommentEvent("org/repo", 1, "/oncall remove schedule "+name, step.user)
+		if _, err := module.HandleEvent("issue_comment", event, nil); err != nil {
+			t.Fatalf("%s: HandleEvent failed: %v", step.name, err)
+		}
+
+		schedule, _ := GetScheduleByName(db, name)
+		if allowed := schedule == nil; allowed != step.wantAllowed {
+			t.Errorf("%s: allowed: want %v, got %v", step.name, step.wantAllowed, allowed)
+		}
+		if got := requests.Load(); got != step.wantRequests {
+			t.Errorf("%s: team requests: want %d, got %d", step.name, step.wantRequests, got)
+		}
+	}
+}
+
+func TestMaintainerTeamIsListedOnce(t *testing.T) {
+	module, _, _ := newTestModule(t, OnCallConfig{MaintainerTeam: "org/maintainers"})
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		_ = json.NewEncoder(w).Encode([]*github.User{{Login: github.Ptr("alice")}})
+	}))
+	t.Cleanup(srv.Close)
+	module.app.GitHubClient.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !module.isMaintainer("alice") {
+				t.Error("want alice to be a maintainer")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("team requests: want 1, got %d", got)
+	}
+}
+
+func TestAdvanceRotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		members     []string
+		inactive    []string
+		advance     time.Duration
+		wantOnCall  string
+		wantComment string
+	}{
+		{
+			name:        "hands off to the next member",
+			members:     []string{"a", "b", "c"},
+			advance:     25 * time.Hour,
+			wantOnCall:  "b",
+			wantComment: "Handoff for `primary`: @b is now on call, taking over from @a.",
+		},
+		{
+			name:        "hands off once per missed handoff",
+			members:     []string{"a", "b", "c"},
+			advance:     49 * time.Hour,
+			wantOnCall:  "c",
+			wantComment: "@c is now on call, taking over from @a",
+		},
+		{
+			name:        "skips inactive members",
+			members:     []string{"a", "b", "c"},
+			inactive:    []string{"b"},
+			advance:     25 * time.Hour,
+			wantOnCall:  "c",
+			wantComment: "@c is now on call",
+		},
+		{
+			name:       "no handoff due",
+			members:    []string{"a", "b"},
+			advance:    time.Hour,
+			wantOnCall: "a",
+		},
+		{
+			name:       "single member",
+			members:    []string{"a"},
+			advance:    25 * time.Hour,
+			wantOnCall: "a",
+		},
+		{
+			name:       "no active members",
+			members:    []string{"a", "b"},
+			inactive:   []string{"a", "b"},
+			advance:    25 * time.Hour,
+			wantOnCall: "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, db, recorder := newTestModule(t, OnCallConfig{HandoffIssue: "org/oncall#1"})
+			sch, _ := AddSchedule(db, "primary", "round-robin")
+			if err := SetScheduleRecurrence(db, sch.ID, "FREQ=DAILY;BYHOUR=9"); err != nil {
+				t.Fatalf("SetScheduleRecurrence failed: %v", err)
+			}
+			for i, login := range tt.members {
+				user, _ := AddUser(db, login, login)
+				_ = AssignUserToSchedule(db, sch.ID, user.ID, i)
+			}
+			for _, login := range tt.inactive {
+				if _, err := db.Exec(`UPDATE oncall_users SET active = 0 WHERE github = ?`, login); err != nil {
+					t.Fatalf("failed to deactivate %s: %v", login, err)
+				}
+			}
+
+			// Start at 10:00 tomorrow, an hour after a handoff, so the
+			// advances cross a whole number of daily 09:00 handoffs
+			clock := module.clock.(*internal.FakeClock)
+			clock.Set(time.Now().UTC().Truncate(24 * time.Hour).Add(34 * time.Hour))
+
+			// The first check only starts counting handoffs
+			if err := module.AdvanceRotations(); err != nil {
+				t.Fatalf("AdvanceRotations failed: %v", err)
+			}
+			clock.Advance(tt.advance)
+			// Checking again before the next handoff changes nothing
+			for range 2 {
+				if err := module.AdvanceRotations(); err != nil {
+					t.Fatalf("AdvanceRotations failed: %v", err)
+				}
+			}
+
+			current, err := GetCurrentOnCallUser(db, "primary")
+			if err != nil {
+				t.Fatalf("GetCurrentOnCallUser failed: %v", err)
+			}
+			if current.GitHub != tt.wantOnCall {
+				t.Errorf("on call: want %q, got %q", tt.wantOnCall, current.GitHub)
+			}
+			comments := recorder.Comments()
+			if tt.wantComment == "" {
+				if len(comments) != 0 {
+					t.Errorf("expected no comments, got %q", comments)
+				}
+				return
+			}
+			if len(comments) != 1 || !strings.Contains(comments[0], tt.wantComment) {
+				t.Errorf("comments: want one containing %q, got %q", tt.wantComment, comments)
+			}
+		})
+	}
+}